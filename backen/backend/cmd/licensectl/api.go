@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errAPIUnsupported   = errors.New("api 模式不支持该命令，请使用 -backend db")
+	errUsageKeyRequired = errors.New("api 模式导出使用记录须指定 -key")
+)
+
+// apiPageSize 分页获取列表时每页的数量，与服务端上限一致
+const apiPageSize = 100
+
+// apiBackend 通过 HTTP API 操作，需要管理员的 JWT 令牌
+type apiBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newAPIBackend(baseURL, token string) (*apiBackend, error) {
+	if token == "" {
+		return nil, errors.New("api 模式须通过 -token 或 LICENSECTL_TOKEN 指定管理员令牌")
+	}
+	return &apiBackend{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// do 发送请求并解析响应，非 2xx 响应返回服务端的错误信息
+func (a *apiBackend) do(method, path string, body, out interface{}) error {
+	data, err := a.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// send 发送请求并返回响应内容
+func (a *apiBackend) send(method, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("请求失败: HTTP %d", resp.StatusCode)
+	}
+	return data, nil
+}
+
+func licensePath(key string) string {
+	return "/licenses/" + url.PathEscape(key)
+}
+
+// GenerateLicenses 调用批量生成接口，与数据库模式一样在一个事务中保存
+func (a *apiBackend) GenerateLicenses(spec service.LicenseSpec, count int) ([]model.License, error) {
+	input := map[string]interface{}{
+		"count":            count,
+		"productid":        spec.ProductId,
+		"userid":           spec.UserId,
+		"version":          spec.Version,
+		"prefix":           spec.Prefix,
+		"max_seats":        spec.MaxSeats,
+		"max_concurrent":   spec.MaxConcurrent,
+		"entitlements":     spec.Entitlements,
+		"allowed_accounts": spec.AllowedAccounts,
+		"max_accounts":     spec.MaxAccounts,
+		"allowed_brokers":  spec.AllowedBrokers,
+		"account_type":     spec.AccountType,
+	}
+	if !spec.ValidUntil.IsZero() {
+		input["valid_until"] = spec.ValidUntil
+	}
+
+	var licenses []model.License
+	if err := a.do(http.MethodPost, "/licenses/bulk?format=json", input, &licenses); err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
+
+func (a *apiBackend) GetLicense(key string) (*model.License, error) {
+	var license model.License
+	if err := a.do(http.MethodGet, licensePath(key), nil, &license); err != nil {
+		return nil, err
+	}
+	return &license, nil
+}
+
+// ListLicenses 由服务端过滤，按游标逐页获取直到满足数量
+func (a *apiBackend) ListLicenses(filter service.LicenseFilter) ([]model.License, error) {
+	query := url.Values{}
+	query.Set("page_size", strconv.Itoa(apiPageSize))
+	setQuery := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	setQuery("status", string(filter.Status))
+	setQuery("productid", filter.ProductId)
+	setQuery("userid", filter.UserId)
+	setQuery("keyword", filter.Search)
+	if filter.IssuedTo != 0 {
+		query.Set("issued_to", strconv.FormatUint(uint64(filter.IssuedTo), 10))
+	}
+	if !filter.ExpiresAfter.IsZero() {
+		query.Set("expires_after", filter.ExpiresAfter.Format(time.RFC3339))
+	}
+	if !filter.ExpiresBefore.IsZero() {
+		query.Set("expires_before", filter.ExpiresBefore.Format(time.RFC3339))
+	}
+
+	var licenses []model.License
+	for {
+		var resp struct {
+			Licenses   []model.License `json:"licenses"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		if err := a.do(http.MethodGet, "/licenses/licenses?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, resp.Licenses...)
+		if filter.Limit > 0 && len(licenses) >= filter.Limit {
+			return licenses[:filter.Limit], nil
+		}
+		if resp.NextCursor == "" {
+			return licenses, nil
+		}
+		query.Set("cursor", resp.NextCursor)
+	}
+}
+
+func (a *apiBackend) ChangeStatus(key string, to model.LicenseStatus) (*model.License, error) {
+	return a.updateLicense(key, map[string]interface{}{"status": to})
+}
+
+func (a *apiBackend) ExtendLicense(key string, validUntil time.Time) (*model.License, error) {
+	license, err := a.GetLicense(key)
+	if err != nil {
+		return nil, err
+	}
+	if license.Status == model.LicenseRevoked {
+		return nil, fmt.Errorf("%w: 已吊销的许可证不能续期", service.ErrInvalidStatusTransition)
+	}
+
+	// 与数据库模式一致，已过期的许可证续期后恢复为已激活
+	input := map[string]interface{}{"validuntil": validUntil.Format(time.RFC3339)}
+	if license.Status == model.LicenseExpired {
+		input["status"] = model.LicenseActive
+	}
+	return a.updateLicense(key, input)
+}
+
+func (a *apiBackend) updateLicense(key string, input map[string]interface{}) (*model.License, error) {
+	var resp struct {
+		License model.License `json:"license"`
+	}
+	if err := a.do(http.MethodPut, licensePath(key), input, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.License, nil
+}
+
+func (a *apiBackend) IssueLicense(key string, userID uint) (*model.License, error) {
+	var license model.License
+	input := map[string]interface{}{"license_key": key, "user_id": userID}
+	if err := a.do(http.MethodPost, "/licenses/issue", input, &license); err != nil {
+		return nil, err
+	}
+	return &license, nil
+}
+
+func (a *apiBackend) CreateAdmin(username, email, password string) (*model.User, error) {
+	return nil, errAPIUnsupported
+}
+
+func (a *apiBackend) ResetPassword(username, password string) error {
+	return errAPIUnsupported
+}
+
+func (a *apiBackend) UnlockUser(username string) (*model.User, error) {
+	return nil, errAPIUnsupported
+}
+
+// ExportUsage 获取单个许可证的使用记录后在本地过滤
+func (a *apiBackend) ExportUsage(filter service.UsageFilter) ([]model.LicenseUsage, error) {
+	if filter.LicenseKey == "" {
+		return nil, errUsageKeyRequired
+	}
+
+	var resp struct {
+		Usages []model.LicenseUsage `json:"usages"`
+	}
+	if err := a.do(http.MethodGet, "/licenses/usage?key="+url.QueryEscape(filter.LicenseKey), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	usages := make([]model.LicenseUsage, 0, len(resp.Usages))
+	for _, usage := range resp.Usages {
+		if filter.Limit > 0 && len(usages) >= filter.Limit {
+			break
+		}
+		if filter.Action != "" && usage.Action != filter.Action ||
+			!filter.Since.IsZero() && usage.Timestamp.Before(filter.Since) ||
+			!filter.Until.IsZero() && !usage.Timestamp.Before(filter.Until) {
+			continue
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// GenerateClientInclude 下载服务端生成的 MQL 客户端文件
+func (a *apiBackend) GenerateClientInclude(productID string, opts service.ClientIncludeOptions) ([]byte, error) {
+	query := url.Values{}
+	query.Set("server", opts.ServerURL)
+	if opts.KeyID != "" {
+		query.Set("key_id", opts.KeyID)
+	}
+	if opts.Retries != 0 {
+		query.Set("retries", strconv.Itoa(opts.Retries))
+	}
+	return a.send(http.MethodGet, "/products/"+url.PathEscape(productID)+"/mql?"+query.Encode(), nil)
+}
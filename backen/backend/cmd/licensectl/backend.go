@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"license-management-system/internal/config"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/util"
+	"time"
+)
+
+// Backend 命令行工具的数据来源，直接访问数据库或调用 HTTP API
+type Backend interface {
+	GenerateLicenses(spec service.LicenseSpec, count int) ([]model.License, error)
+	GetLicense(key string) (*model.License, error)
+	ListLicenses(filter service.LicenseFilter) ([]model.License, error)
+	ChangeStatus(key string, to model.LicenseStatus) (*model.License, error)
+	ExtendLicense(key string, validUntil time.Time) (*model.License, error)
+	IssueLicense(key string, userID uint) (*model.License, error)
+	CreateAdmin(username, email, password string) (*model.User, error)
+	ResetPassword(username, password string) error
+	UnlockUser(username string) (*model.User, error)
+	ExportUsage(filter service.UsageFilter) ([]model.LicenseUsage, error)
+	GenerateClientInclude(productID string, opts service.ClientIncludeOptions) ([]byte, error)
+}
+
+// dbBackend 通过服务层直接操作数据库
+type dbBackend struct {
+	signingKey string // 签名私钥路径，生成 MQL 客户端文件时加载
+}
+
+// newDBBackend 按服务配置连接数据库，数据库结构版本须与程序一致
+func newDBBackend(configPath string) (*dbBackend, error) {
+	var args []string
+	if configPath != "" {
+		args = []string{"-config", configPath}
+	}
+	cfg, err := config.Load(nil, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Connect(cfg.Database); err != nil {
+		return nil, err
+	}
+	return &dbBackend{signingKey: cfg.Licensing.SigningKey}, nil
+}
+
+func (dbBackend) GenerateLicenses(spec service.LicenseSpec, count int) ([]model.License, error) {
+	return service.GenerateLicenses(spec, count)
+}
+
+func (dbBackend) GetLicense(key string) (*model.License, error) {
+	return service.GetLicense(key)
+}
+
+func (dbBackend) ListLicenses(filter service.LicenseFilter) ([]model.License, error) {
+	return service.ListLicenses(filter)
+}
+
+func (dbBackend) ChangeStatus(key string, to model.LicenseStatus) (*model.License, error) {
+	license, err := service.GetLicense(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.ChangeLicenseStatus(license, to); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+func (dbBackend) ExtendLicense(key string, validUntil time.Time) (*model.License, error) {
+	license, err := service.GetLicense(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.ExtendLicense(license, validUntil); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+func (dbBackend) IssueLicense(key string, userID uint) (*model.License, error) {
+	license, err := service.GetLicense(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.IssueLicense(license, userID); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+func (dbBackend) CreateAdmin(username, email, password string) (*model.User, error) {
+	return service.CreateUser(username, email, password, "admin")
+}
+
+func (dbBackend) ResetPassword(username, password string) error {
+	return service.ResetPassword(username, password)
+}
+
+func (dbBackend) UnlockUser(username string) (*model.User, error) {
+	return service.UnlockUser(username)
+}
+
+func (dbBackend) ExportUsage(filter service.UsageFilter) ([]model.LicenseUsage, error) {
+	return service.ListUsages(filter)
+}
+
+// GenerateClientInclude 使用服务配置的签名私钥，私钥文件不存在时不会自动生成
+func (b dbBackend) GenerateClientInclude(productID string, opts service.ClientIncludeOptions) ([]byte, error) {
+	if err := util.LoadSigningKey(b.signingKey); err != nil {
+		return nil, fmt.Errorf("加载签名私钥失败: %w", err)
+	}
+	_, source, err := service.GenerateClientInclude(productID, opts)
+	return source, err
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"os"
+	"strings"
+	"time"
+)
+
+// newFlagSet 创建子命令的参数集合
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("licensectl "+name, flag.ContinueOnError)
+}
+
+func runLicenseGenerate(e *env, args []string) error {
+	fs := newFlagSet("license generate")
+	product := fs.String("product", "", "产品代码 (必填)")
+	count := fs.Int("count", 1, "生成数量")
+	user := fs.String("user", "", "绑定的用户名或交易账号")
+	version := fs.String("version", "", "许可证版本，默认使用产品当前版本")
+	prefix := fs.String("prefix", "", "密钥前缀，默认使用产品配置")
+	days := fs.Int("days", 0, "有效天数，默认使用产品配置")
+	validUntil := fs.String("valid-until", "", "到期日期 (YYYY-MM-DD 或 RFC3339)，与 -days 二选一")
+	maxSeats := fs.Int("max-seats", 0, "可激活的设备数，默认使用产品配置")
+	maxConcurrent := fs.Int("max-concurrent", 0, "浮动许可证并发实例数，0 表示不限制")
+	features := fs.String("features", "", "功能开关，逗号分隔")
+	accounts := fs.String("accounts", "", "允许使用的交易账号，逗号分隔")
+	maxAccounts := fs.Int("max-accounts", 0, "账号数上限，未满时自动绑定新账号")
+	brokers := fs.String("brokers", "", "允许的经纪商或服务器名称（通配符），逗号分隔")
+	accountType := fs.String("account-type", "", "账户类型限制: demo、live 或 any")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *product == "" {
+		return errors.New("-product 不能为空")
+	}
+
+	spec := service.LicenseSpec{
+		ProductId:       *product,
+		UserId:          *user,
+		Version:         *version,
+		Prefix:          *prefix,
+		MaxSeats:        *maxSeats,
+		MaxConcurrent:   *maxConcurrent,
+		AllowedAccounts: splitList(*accounts),
+		MaxAccounts:     *maxAccounts,
+		AllowedBrokers:  splitList(*brokers),
+		AccountType:     model.AccountType(*accountType),
+	}
+	spec.Entitlements.Features = splitList(*features)
+	switch {
+	case *validUntil != "" && *days != 0:
+		return errors.New("-days 和 -valid-until 不能同时指定")
+	case *validUntil != "":
+		t, err := parseDate(*validUntil)
+		if err != nil {
+			return err
+		}
+		spec.ValidUntil = t
+	case *days != 0:
+		spec.ValidUntil = time.Now().AddDate(0, 0, *days)
+	}
+
+	licenses, err := e.backend.GenerateLicenses(spec, *count)
+	if err != nil {
+		return err
+	}
+	return e.out.licenses(licenses)
+}
+
+func runLicenseList(e *env, args []string) error {
+	fs := newFlagSet("license list")
+	status := fs.String("status", "", "许可证状态")
+	product := fs.String("product", "", "产品代码")
+	user := fs.String("user", "", "用户名或交易账号")
+	issuedTo := fs.Uint("issued-to", 0, "持有人用户 ID")
+	search := fs.String("search", "", "按密钥或用户名模糊匹配")
+	expiresAfter := fs.String("expires-after", "", "到期时间不早于该日期 (YYYY-MM-DD 或 RFC3339)")
+	expiresBefore := fs.String("expires-before", "", "到期时间早于该日期 (YYYY-MM-DD 或 RFC3339)")
+	limit := fs.Int("limit", 0, "最多返回的数量，0 表示不限制")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *status != "" && !model.LicenseStatus(*status).IsValid() {
+		return fmt.Errorf("%w: %s", service.ErrInvalidStatus, *status)
+	}
+
+	filter := service.LicenseFilter{
+		Status:    model.LicenseStatus(*status),
+		ProductId: *product,
+		UserId:    *user,
+		IssuedTo:  *issuedTo,
+		Search:    *search,
+		Limit:     *limit,
+	}
+	for _, d := range []struct {
+		value string
+		dst   *time.Time
+	}{{*expiresAfter, &filter.ExpiresAfter}, {*expiresBefore, &filter.ExpiresBefore}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseDate(d.value)
+		if err != nil {
+			return err
+		}
+		*d.dst = t
+	}
+
+	licenses, err := e.backend.ListLicenses(filter)
+	if err != nil {
+		return err
+	}
+	return e.out.licenses(licenses)
+}
+
+func runLicenseGet(e *env, args []string) error {
+	keys, err := parseKeys(newFlagSet("license get"), args)
+	if err != nil {
+		return err
+	}
+
+	licenses := make([]model.License, 0, len(keys))
+	for _, key := range keys {
+		license, err := e.backend.GetLicense(key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		licenses = append(licenses, *license)
+	}
+	return e.out.licenses(licenses)
+}
+
+// statusTargets 状态命令对应的目标状态
+var statusTargets = map[string]model.LicenseStatus{
+	"revoke":  model.LicenseRevoked,
+	"suspend": model.LicenseSuspended,
+	"resume":  model.LicenseActive,
+}
+
+// statusCommand 返回变更许可证状态的命令，可一次处理多个密钥
+func statusCommand(name string) func(e *env, args []string) error {
+	return func(e *env, args []string) error {
+		keys, err := parseKeys(newFlagSet("license "+name), args)
+		if err != nil {
+			return err
+		}
+
+		licenses := make([]model.License, 0, len(keys))
+		for _, key := range keys {
+			license, err := e.backend.ChangeStatus(key, statusTargets[name])
+			if err != nil {
+				e.out.licenses(licenses)
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			licenses = append(licenses, *license)
+		}
+		return e.out.licenses(licenses)
+	}
+}
+
+func runLicenseExtend(e *env, args []string) error {
+	fs := newFlagSet("license extend")
+	days := fs.Int("days", 0, "在当前到期时间（已过期时为当前时间）基础上延长的天数")
+	until := fs.String("until", "", "新的到期日期 (YYYY-MM-DD 或 RFC3339)")
+	keys, err := parseKeys(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*days == 0) == (*until == "") {
+		return errors.New("须指定 -days 或 -until 其中之一")
+	}
+
+	var validUntil time.Time
+	if *until != "" {
+		if validUntil, err = parseDate(*until); err != nil {
+			return err
+		}
+	}
+
+	licenses := make([]model.License, 0, len(keys))
+	for _, key := range keys {
+		target := validUntil
+		if *days != 0 {
+			license, err := e.backend.GetLicense(key)
+			if err != nil {
+				e.out.licenses(licenses)
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			base := license.ValidUntil
+			if now := time.Now(); base.Before(now) {
+				base = now
+			}
+			target = base.AddDate(0, 0, *days)
+		}
+
+		license, err := e.backend.ExtendLicense(key, target)
+		if err != nil {
+			e.out.licenses(licenses)
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		licenses = append(licenses, *license)
+	}
+	return e.out.licenses(licenses)
+}
+
+func runLicenseIssue(e *env, args []string) error {
+	fs := newFlagSet("license issue")
+	userID := fs.Uint("user-id", 0, "持有人用户 ID (必填)")
+	keys, err := parseKeys(fs, args)
+	if err != nil {
+		return err
+	}
+	if *userID == 0 {
+		return errors.New("-user-id 不能为空")
+	}
+
+	licenses := make([]model.License, 0, len(keys))
+	for _, key := range keys {
+		license, err := e.backend.IssueLicense(key, *userID)
+		if err != nil {
+			e.out.licenses(licenses)
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		licenses = append(licenses, *license)
+	}
+	return e.out.licenses(licenses)
+}
+
+func runUserCreateAdmin(e *env, args []string) error {
+	fs := newFlagSet("user create-admin")
+	username := fs.String("username", "", "用户名 (必填)")
+	email := fs.String("email", "", "邮箱 (必填)")
+	password := fs.String("password", "", "密码，为空时从标准输入读取")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" || *email == "" {
+		return errors.New("-username 和 -email 不能为空")
+	}
+	if err := readPassword(e, password); err != nil {
+		return err
+	}
+
+	user, err := e.backend.CreateAdmin(*username, *email, *password)
+	if err != nil {
+		return err
+	}
+	return e.out.users([]model.User{*user})
+}
+
+func runUserResetPassword(e *env, args []string) error {
+	fs := newFlagSet("user reset-password")
+	username := fs.String("username", "", "用户名 (必填)")
+	password := fs.String("password", "", "新密码，为空时从标准输入读取")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username 不能为空")
+	}
+	if err := readPassword(e, password); err != nil {
+		return err
+	}
+
+	if err := e.backend.ResetPassword(*username, *password); err != nil {
+		return err
+	}
+	return e.out.message("已重置用户 " + *username + " 的密码")
+}
+
+// runUserUnlock 管理员被锁定时无法通过 API 解除，需要直接访问数据库
+func runUserUnlock(e *env, args []string) error {
+	fs := newFlagSet("user unlock")
+	username := fs.String("username", "", "用户名 (必填)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username 不能为空")
+	}
+
+	user, err := e.backend.UnlockUser(*username)
+	if err != nil {
+		return err
+	}
+	return e.out.users([]model.User{*user})
+}
+
+func runUsageExport(e *env, args []string) error {
+	fs := newFlagSet("usage export")
+	key := fs.String("key", "", "许可证密钥")
+	action := fs.String("action", "", "操作类型，例如 verify、activate")
+	since := fs.String("since", "", "开始日期 (含)")
+	until := fs.String("until", "", "结束日期 (不含)")
+	limit := fs.Int("limit", 0, "最多返回的数量，0 表示不限制")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := service.UsageFilter{LicenseKey: *key, Action: *action, Limit: *limit}
+	var err error
+	if *since != "" {
+		if filter.Since, err = parseDate(*since); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = parseDate(*until); err != nil {
+			return err
+		}
+	}
+
+	usages, err := e.backend.ExportUsage(filter)
+	if err != nil {
+		return err
+	}
+	return e.out.usages(usages)
+}
+
+func runMQLGenerate(e *env, args []string) error {
+	fs := newFlagSet("mql generate")
+	product := fs.String("product", "", "产品代码 (必填)")
+	server := fs.String("server", "", "EA 访问的服务地址，例如 https://license.example.com (必填)")
+	keyID := fs.String("key-id", "", "嵌入的客户端 API 密钥，为空时由开发者在文件中填写")
+	retries := fs.Int("retries", 0, "网络错误时的重试次数，默认 3")
+	outPath := fs.String("out", "", "输出文件路径，为空时输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *product == "" || *server == "" {
+		return errors.New("-product 和 -server 不能为空")
+	}
+
+	source, err := e.backend.GenerateClientInclude(*product, service.ClientIncludeOptions{
+		ServerURL: *server,
+		KeyID:     *keyID,
+		Retries:   *retries,
+	})
+	if err != nil {
+		return err
+	}
+	if *outPath == "" {
+		_, err := e.out.w.Write(source)
+		return err
+	}
+	if err := os.WriteFile(*outPath, source, 0644); err != nil {
+		return err
+	}
+	return e.out.message("已生成 " + *outPath)
+}
+
+// parseKeys 解析参数并返回剩余的许可证密钥
+func parseKeys(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New("缺少许可证密钥")
+	}
+	return fs.Args(), nil
+}
+
+// parseDate 解析 YYYY-MM-DD（本地时间零点）或 RFC3339 格式的时间
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式应为 YYYY-MM-DD 或 RFC3339: %s", s)
+	}
+	return t, nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readPassword 未通过参数指定密码时从标准输入读取一行
+func readPassword(e *env, password *string) error {
+	if *password != "" {
+		return nil
+	}
+	line, err := bufio.NewReader(e.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return errors.New("未指定密码")
+	}
+	*password = strings.TrimRight(line, "\r\n")
+	if *password == "" {
+		return errors.New("未指定密码")
+	}
+	return nil
+}
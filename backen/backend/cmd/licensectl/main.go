@@ -0,0 +1,130 @@
+// licensectl 许可证管理命令行工具。
+//
+// 默认使用服务的配置文件直接连接数据库，也可以通过 -backend api 调用 HTTP API，
+// 此时需要提供管理员的 JWT 令牌。
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const usageHeader = `用法: licensectl [全局参数] <对象> <命令> [参数]
+
+全局参数:
+`
+
+// command 一个子命令
+type command struct {
+	name    string // 对象和命令，例如 "license generate"
+	summary string
+	run     func(e *env, args []string) error
+}
+
+// env 子命令的执行环境
+type env struct {
+	backend Backend
+	out     *printer
+	stdin   io.Reader
+}
+
+var commands = []command{
+	{"license generate", "生成许可证，-count 指定批量生成数量", runLicenseGenerate},
+	{"license list", "按条件查询许可证", runLicenseList},
+	{"license get", "查看许可证详情", runLicenseGet},
+	{"license revoke", "吊销许可证", statusCommand("revoke")},
+	{"license suspend", "暂停许可证", statusCommand("suspend")},
+	{"license resume", "恢复已暂停的许可证", statusCommand("resume")},
+	{"license extend", "延长许可证有效期", runLicenseExtend},
+	{"license issue", "将许可证分配给用户", runLicenseIssue},
+	{"user create-admin", "创建管理员账户", runUserCreateAdmin},
+	{"user reset-password", "重置用户密码", runUserResetPassword},
+	{"user unlock", "解除用户及其最近登录失败 IP 的登录限制", runUserUnlock},
+	{"usage export", "导出许可证使用记录", runUsageExport},
+	{"mql generate", "生成产品的 MQL4/MQL5 客户端 include 文件", runMQLGenerate},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+// run 解析全局参数，创建后端并执行子命令
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("licensectl", flag.ContinueOnError)
+	mode := fs.String("backend", envOr("LICENSECTL_BACKEND", "db"), "db 直接连接数据库，api 调用 HTTP API")
+	configPath := fs.String("config", "", "db 模式使用的服务配置文件 (默认 config.yaml)")
+	apiURL := fs.String("api-url", envOr("LICENSECTL_API_URL", "http://localhost"), "api 模式的服务地址")
+	token := fs.String("token", os.Getenv("LICENSECTL_TOKEN"), "api 模式使用的管理员 JWT 令牌")
+	format := fs.String("o", "table", "输出格式: table、json 或 csv")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageHeader)
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\n命令:")
+		for _, c := range commands {
+			fmt.Fprintf(fs.Output(), "  %-22s %s\n", c.name, c.summary)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	out, err := newPrinter(stdout, *format)
+	if err != nil {
+		return err
+	}
+
+	var backend Backend
+	switch *mode {
+	case "db":
+		backend, err = newDBBackend(*configPath)
+	case "api":
+		backend, err = newAPIBackend(*apiURL, *token)
+	default:
+		err = fmt.Errorf("未知的后端: %s", *mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	return execute(&env{backend: backend, out: out, stdin: stdin}, fs.Args())
+}
+
+// execute 查找并执行子命令
+func execute(e *env, args []string) error {
+	name := strings.Join(args[:2], " ")
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(e, args[2:])
+		}
+	}
+
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("未知的命令: %s\n可用命令: %s", name, strings.Join(names, ", "))
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
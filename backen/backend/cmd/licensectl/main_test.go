@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/handler"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/util"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testEnv 使用指定后端和输出格式执行命令，返回标准输出
+func testEnv(t *testing.T, backend Backend, format string, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	p, err := newPrinter(&out, format)
+	assert.NoError(t, err)
+	err = execute(&env{backend: backend, out: p, stdin: strings.NewReader(stdin)}, args)
+	return out.String(), err
+}
+
+func createTestProduct(t *testing.T) {
+	assert.NoError(t, database.DB.Create(&model.Product{
+		Code:              "gold",
+		Name:              "Gold Scalper",
+		EntitlementSchema: model.EntitlementSchema{Features: []string{"news_filter"}},
+	}).Error)
+}
+
+func TestLicensectlDB(t *testing.T) {
+	database.InitTestDB()
+	defer database.CleanTestDB()
+	createTestProduct(t)
+	keyPath := filepath.Join(t.TempDir(), "signing.key")
+	assert.NoError(t, util.InitSigningKey(keyPath))
+	backend := dbBackend{signingKey: keyPath}
+
+	out, err := testEnv(t, backend, "json", "", "license", "generate", "-product", "gold", "-count", "3", "-features", "news_filter", "-days", "10")
+	assert.NoError(t, err)
+	var generated []model.License
+	assert.NoError(t, json.Unmarshal([]byte(out), &generated))
+	assert.Len(t, generated, 3)
+	assert.Equal(t, []string{"news_filter"}, generated[0].Entitlements.Features)
+	key := generated[0].Key
+
+	_, err = testEnv(t, backend, "json", "", "license", "generate", "-product", "gold", "-features", "unknown")
+	assert.Error(t, err)
+	_, err = testEnv(t, backend, "json", "", "license", "generate", "-product", "gold", "-count", "0")
+	assert.Error(t, err)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    bool
+		wantStatus model.LicenseStatus
+	}{
+		{name: "suspend_inactive", args: []string{"license", "suspend", key}, wantErr: true},
+		{name: "revoke", args: []string{"license", "revoke", key}, wantStatus: model.LicenseRevoked},
+		{name: "extend_revoked", args: []string{"license", "extend", "-days", "30", key}, wantErr: true},
+		{name: "issue_unknown_user", args: []string{"license", "issue", "-user-id", "99", generated[1].Key}, wantErr: true},
+		{name: "unknown_key", args: []string{"license", "revoke", "NOPE"}, wantErr: true},
+		{name: "unknown_command", args: []string{"license", "destroy", key}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := testEnv(t, backend, "json", "", tt.args...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var licenses []model.License
+			assert.NoError(t, json.Unmarshal([]byte(out), &licenses))
+			assert.Equal(t, tt.wantStatus, licenses[0].Status)
+		})
+	}
+
+	// 已过期的许可证续期后恢复为已激活
+	expired := generated[2]
+	database.DB.Model(&model.License{}).Where("id = ?", expired.ID).Updates(map[string]interface{}{
+		"status":      model.LicenseExpired,
+		"valid_until": time.Now().Add(-time.Hour),
+	})
+	out, err = testEnv(t, backend, "json", "", "license", "extend", "-days", "30", expired.Key)
+	assert.NoError(t, err)
+	var extended []model.License
+	assert.NoError(t, json.Unmarshal([]byte(out), &extended))
+	assert.Equal(t, model.LicenseActive, extended[0].Status)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), extended[0].ValidUntil, time.Minute)
+
+	out, err = testEnv(t, backend, "csv", "", "license", "list", "-status", "revoked")
+	assert.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, key, records[1][0])
+
+	out, err = testEnv(t, backend, "table", "", "license", "list", "-search", generated[1].Key[:8])
+	assert.NoError(t, err)
+	assert.Contains(t, out, generated[1].Key)
+
+	// 密码从标准输入读取
+	_, err = testEnv(t, backend, "json", "short\n", "user", "create-admin", "-username", "ops", "-email", "ops@example.com")
+	assert.Error(t, err)
+	out, err = testEnv(t, backend, "json", "s3cret-pass\n", "user", "create-admin", "-username", "ops", "-email", "ops@example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"role": "admin"`)
+	assert.NotContains(t, out, "s3cret-pass")
+	_, err = testEnv(t, backend, "json", "s3cret-pass\n", "user", "create-admin", "-username", "ops", "-email", "other@example.com")
+	assert.Error(t, err)
+	_, err = testEnv(t, backend, "table", "", "user", "reset-password", "-username", "ops", "-password", "an0ther-pass")
+	assert.NoError(t, err)
+	_, err = testEnv(t, backend, "table", "", "user", "reset-password", "-username", "nobody", "-password", "an0ther-pass")
+	assert.Error(t, err)
+
+	// 解除锁定同时清除用户名的失败次数
+	database.DB.Model(&model.User{}).Where("username = ?", "ops").Update("status", model.UserLocked)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, service.RecordLoginFailure("ops", "203.0.113.9", time.Now()))
+	}
+	wait, err := service.CheckLoginThrottle("ops", "198.51.100.1", time.Now())
+	assert.NoError(t, err)
+	assert.NotZero(t, wait)
+	out, err = testEnv(t, backend, "json", "", "user", "unlock", "-username", "ops")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"status": "active"`)
+	wait, err = service.CheckLoginThrottle("ops", "198.51.100.1", time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	var admin model.User
+	database.DB.Where("username = ?", "ops").First(&admin)
+	out, err = testEnv(t, backend, "json", "", "license", "issue", "-user-id", strconv.Itoa(int(admin.ID)), generated[1].Key)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"issued_to": `+strconv.Itoa(int(admin.ID)))
+
+	now := time.Now()
+	database.DB.Create(&[]model.LicenseUsage{
+		{LicenseKey: key, Action: "verify", Timestamp: now.Add(-48 * time.Hour)},
+		{LicenseKey: key, Action: "verify", Timestamp: now},
+		{LicenseKey: key, Action: "activate", Timestamp: now},
+	})
+	out, err = testEnv(t, backend, "csv", "", "usage", "export", "-key", key, "-action", "verify", "-since", now.AddDate(0, 0, -1).Format("2006-01-02"))
+	assert.NoError(t, err)
+	records, err = csv.NewReader(strings.NewReader(out)).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	// 生成 MQL 客户端文件
+	out, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold", "-server", "https://license.example.com", "-retries", "5")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `#define LICENSE_SERVER         "https://license.example.com"`)
+	assert.Contains(t, out, `#define LICENSE_RETRIES        5`)
+	outPath := filepath.Join(t.TempDir(), "License_gold.mqh")
+	out, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold", "-server", "https://license.example.com", "-out", outPath)
+	assert.NoError(t, err)
+	assert.Contains(t, out, outPath)
+	source, err := os.ReadFile(outPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), `#define LICENSE_PRODUCT_ID     "gold"`)
+	_, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold")
+	assert.Error(t, err)
+	_, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold", "-server", "https://license.example.com", "-key-id", "ck_missing")
+	assert.ErrorIs(t, err, service.ErrClientKeyNotFound)
+	_, err = testEnv(t, dbBackend{signingKey: filepath.Join(t.TempDir(), "missing.key")}, "table", "", "mql", "generate", "-product", "gold", "-server", "https://license.example.com")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLicensectlAPI(t *testing.T) {
+	database.InitTestDB()
+	defer database.CleanTestDB()
+	createTestProduct(t)
+
+	// 认证中间件不在测试范围内，令牌仅用于满足参数校验
+	app := fiber.New()
+	licenses := app.Group("/api/v1/licenses")
+	licenses.Get("/licenses", handler.HandleGetAllLicenses)
+	licenses.Post("/bulk", handler.HandleLicenseBulkGenerate)
+	licenses.Get("/usage", handler.HandleLicenseUsage)
+	licenses.Get("/:key", handler.HandleGetLicense)
+	licenses.Put("/:key", handler.HandleLicenseUpdate)
+	app.Get("/api/v1/products/:code/mql", handler.HandleProductClientInclude)
+	assert.NoError(t, util.InitSigningKey(filepath.Join(t.TempDir(), "signing.key")))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	_, err = newAPIBackend("http://"+ln.Addr().String(), "")
+	assert.Error(t, err)
+	backend, err := newAPIBackend("http://"+ln.Addr().String(), "test-token")
+	assert.NoError(t, err)
+
+	out, err := testEnv(t, backend, "json", "", "license", "generate", "-product", "gold", "-count", "2")
+	assert.NoError(t, err)
+	var generated []model.License
+	assert.NoError(t, json.Unmarshal([]byte(out), &generated))
+	assert.Len(t, generated, 2)
+
+	_, err = testEnv(t, backend, "json", "", "license", "generate", "-product", "missing")
+	assert.ErrorContains(t, err, "产品不存在")
+
+	out, err = testEnv(t, backend, "json", "", "license", "revoke", generated[0].Key)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"status": "revoked"`)
+
+	out, err = testEnv(t, backend, "csv", "", "license", "list", "-status", "inactive")
+	assert.NoError(t, err)
+	assert.Contains(t, out, generated[1].Key)
+	assert.NotContains(t, out, generated[0].Key)
+
+	out, err = testEnv(t, backend, "json", "", "license", "list", "-limit", "1")
+	assert.NoError(t, err)
+	var listed []model.License
+	assert.NoError(t, json.Unmarshal([]byte(out), &listed))
+	assert.Len(t, listed, 1)
+
+	out, err = testEnv(t, backend, "json", "", "license", "list", "-expires-before", "2000-01-01")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, generated[1].Key)
+
+	_, err = testEnv(t, backend, "json", "", "usage", "export")
+	assert.ErrorIs(t, err, errUsageKeyRequired)
+	database.DB.Create(&model.LicenseUsage{LicenseKey: generated[1].Key, Action: "verify", Timestamp: time.Now()})
+	out, err = testEnv(t, backend, "json", "", "usage", "export", "-key", generated[1].Key)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"action": "verify"`)
+
+	key, err := service.CreateClientKey("gold", "ea")
+	assert.NoError(t, err)
+	out, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold", "-server", "https://license.example.com", "-key-id", key.KeyID, "-retries", "-1")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `#define LICENSE_CLIENT_KEY     "`+key.KeyID+`"`)
+	assert.Contains(t, out, `#define LICENSE_RETRIES        0`)
+	_, err = testEnv(t, backend, "table", "", "mql", "generate", "-product", "gold", "-server", "license.example.com")
+	assert.ErrorContains(t, err, "HTTP 400")
+
+	_, err = testEnv(t, backend, "json", "", "user", "reset-password", "-username", "admin", "-password", "an0ther-pass")
+	assert.ErrorIs(t, err, errAPIUnsupported)
+	_, err = testEnv(t, backend, "json", "", "user", "unlock", "-username", "admin")
+	assert.ErrorIs(t, err, errAPIUnsupported)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"license-management-system/internal/model"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// printer 按 table、json 或 csv 格式输出结果
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table", "json", "csv":
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("不支持的输出格式: %s", format)
+}
+
+// print json 格式输出 v，table 和 csv 格式输出 header 和 rows
+func (p *printer) print(v interface{}, header []string, rows [][]string) error {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "csv":
+		w := csv.NewWriter(p.w)
+		w.Write(header)
+		w.WriteAll(rows)
+		return w.Error()
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (p *printer) licenses(licenses []model.License) error {
+	header := []string{"key", "productid", "userid", "status", "valid_until", "max_seats", "issued_to", "token_renewed_at", "created_at"}
+	rows := make([][]string, 0, len(licenses))
+	for _, l := range licenses {
+		rows = append(rows, []string{
+			l.Key,
+			l.ProductId,
+			l.UserId,
+			string(l.Status),
+			formatTime(l.ValidUntil),
+			strconv.Itoa(l.MaxSeats),
+			strconv.FormatUint(uint64(l.IssuedTo), 10),
+			formatTime(l.TokenRenewedAt),
+			formatTime(l.CreatedAt),
+		})
+	}
+	return p.print(licenses, header, rows)
+}
+
+func (p *printer) users(users []model.User) error {
+	header := []string{"id", "username", "email", "role", "status", "createdat"}
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(u.ID), 10),
+			u.Username,
+			u.Email,
+			u.Role,
+			u.Status,
+			formatTime(u.CreatedAt),
+		})
+	}
+	return p.print(users, header, rows)
+}
+
+func (p *printer) usages(usages []model.LicenseUsage) error {
+	header := []string{"timestamp", "license_key", "action", "ip_address", "country", "device", "platform", "account", "broker", "server", "user_agent"}
+	rows := make([][]string, 0, len(usages))
+	for _, u := range usages {
+		rows = append(rows, []string{
+			formatTime(u.Timestamp),
+			u.LicenseKey,
+			u.Action,
+			u.IPAddress,
+			u.Country,
+			u.Device,
+			u.Platform,
+			u.Account,
+			u.Broker,
+			u.Server,
+			u.UserAgent,
+		})
+	}
+	return p.print(usages, header, rows)
+}
+
+// message 输出操作结果提示
+func (p *printer) message(msg string) error {
+	if p.format == "json" {
+		return p.print(map[string]string{"message": msg}, nil, nil)
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(timeLayout)
+}
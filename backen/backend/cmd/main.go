@@ -1,10 +1,20 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"license-management-system/internal/config"
 	"license-management-system/internal/database"
 	"license-management-system/internal/handler"
 	"license-management-system/internal/middleware"
+	"license-management-system/internal/scheduler"
+	"license-management-system/internal/service"
+	"license-management-system/internal/telemetry"
+	"license-management-system/internal/util"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -12,8 +22,57 @@ import (
 )
 
 func main() {
+	// 加载配置
+	fs := flag.NewFlagSet("license-manager", flag.ContinueOnError)
+	migrate := fs.Bool("migrate", false, "执行全部未执行的数据库迁移后退出，等同于 migrate up")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("加载配置失败:\n", err)
+	}
+
+	// 数据库迁移命令执行后退出，不启动服务
+	switch {
+	case *migrate:
+		if err := runMigrate(cfg.Database, []string{"up"}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case fs.Arg(0) == "migrate":
+		if err := runMigrate(cfg.Database, fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case fs.NArg() > 0:
+		log.Fatalf("未知的命令: %s", fs.Arg(0))
+	}
+
+	util.SetJWTSecret(cfg.Server.JWTSecret)
+
 	// 初始化数据库
-	database.InitDB()
+	database.InitDB(cfg.Database)
+
+	// 加载许可证文件签名密钥
+	if err := util.InitSigningKey(cfg.Licensing.SigningKey); err != nil {
+		log.Fatal("加载签名密钥失败:", err)
+	}
+
+	// 加载离线 GeoIP 数据库
+	if err := telemetry.InitGeoIP(cfg.Telemetry.GeoIPDB); err != nil {
+		log.Fatal("加载 GeoIP 数据库失败:", err)
+	}
+
+	// 启动后台定时任务
+	retention := service.Retention{
+		Usage:   time.Duration(cfg.Retention.UsageDays) * 24 * time.Hour,
+		JobRuns: time.Duration(cfg.Retention.JobRunDays) * 24 * time.Hour,
+	}
+	if err := service.RegisterJobs(scheduler.Default, retention); err != nil {
+		log.Fatal("注册定时任务失败:", err)
+	}
+	scheduler.Default.Start()
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -44,6 +103,9 @@ func main() {
 	users.Get("/info", middleware.Auth(), handler.HandleUserInfo)
 	users.Get("/search", middleware.Auth(), middleware.AdminOnly(), handler.HandleSearchUsers)
 	users.Get("/login-logs", middleware.Auth(), handler.HandleGetLoginLogs)
+	users.Get("/login-failures", middleware.Auth(), middleware.AdminOnly(), handler.HandleLoginFailures) // 失败的登录记录
+	users.Post("/:id/unlock", middleware.Auth(), middleware.AdminOnly(), handler.HandleUnlockUser)       // 解除登录限制
+	users.Put("/:id/status", middleware.Auth(), middleware.AdminOnly(), handler.HandleUpdateUserStatus)
 
 	// 许可证路由
 	licenses := api.Group("/licenses")
@@ -53,15 +115,71 @@ func main() {
 	licenses.Get("/licenses", middleware.AdminOnly(), handler.HandleGetAllLicenses)
 	licenses.Post("/generate", middleware.AdminOnly(), handler.HandleLicenseGenerate)
 	licenses.Post("/issue", middleware.AdminOnly(), handler.HandleLicenseIssue)
+	licenses.Post("/bulk", middleware.AdminOnly(), handler.HandleLicenseBulkGenerate) // 批量生成，返回 CSV
+	licenses.Post("/import", middleware.AdminOnly(), handler.HandleLicenseImport)
+	licenses.Get("/export", middleware.AdminOnly(), handler.HandleLicenseExport)
 	licenses.Put("/:key", middleware.AdminOnly(), handler.HandleLicenseUpdate) // 添加更新许可证的路由
 	licenses.Get("/statistics", middleware.AdminOnly(), handler.HandleLicenseStatistics)
+	licenses.Get("/failures", middleware.AdminOnly(), handler.HandleLicenseFailures) // 失败的校验和激活记录
 	licenses.Delete("/:key", middleware.AdminOnly(), handler.HandleLicenseDelete)
 
 	// 普通用户可访问的路由
 	licenses.Get("/verify", handler.HandleLicenseVerify)
-	licenses.Get("/:key", handler.HandleGetLicense) // 添加更新许可证的路由
+	licenses.Get("/statuses", handler.HandleLicenseStatuses)    // 状态列表及展示文本
+	licenses.Get("/public-key", handler.HandleLicensePublicKey) // 离线校验公钥
+	licenses.Get("/usage", handler.HandleLicenseUsage)          // 新增license使用记录查询路由，须在 /:key 之前注册
+	licenses.Get("/:key/file", handler.HandleLicenseFile)       // 签发离线许可证文件
+	licenses.Get("/:key", handler.HandleGetLicense)             // 添加更新许可证的路由
 	licenses.Post("/activate", handler.HandleLicenseActivate)
-	licenses.Get("/usage", handler.HandleLicenseUsage) // 新增license使用记录查询路由
+	licenses.Post("/deactivate", handler.HandleLicenseDeactivate)          // 停用设备释放席位
+	licenses.Get("/:key/activations", handler.HandleGetLicenseActivations) // 已激活设备列表
+	licenses.Get("/:key/leases", handler.HandleGetLicenseLeases)
+
+	// 浮动许可证租约
+	leases := licenses.Group("/leases")
+	leases.Post("/checkout", handler.HandleLeaseCheckout)
+	leases.Post("/:id/heartbeat", handler.HandleLeaseHeartbeat)
+	leases.Delete("/:id", handler.HandleLeaseRelease)
+
+	// 产品目录路由
+	products := api.Group("/products")
+	products.Use(middleware.Auth(), middleware.AdminOnly())
+	products.Get("/", handler.HandleGetProducts)
+	products.Post("/", handler.HandleCreateProduct)
+	products.Get("/:code", handler.HandleGetProduct)
+	products.Put("/:code", handler.HandleUpdateProduct)
+	products.Delete("/:code", handler.HandleDeleteProduct)
+	products.Post("/:code/versions", handler.HandleReleaseProductVersion)
+	products.Get("/:code/api-keys", handler.HandleGetClientKeys)
+	products.Post("/:code/api-keys", handler.HandleCreateClientKey)
+	products.Post("/:code/api-keys/:id/rotate", handler.HandleRotateClientKey)
+	products.Delete("/:code/api-keys/:id", handler.HandleRevokeClientKey)
+	products.Get("/:code/mql", handler.HandleProductClientInclude)
+
+	// 客户端程序（EA）路由，使用产品 API 密钥签名认证，不需要用户登录
+	client := api.Group("/client")
+	client.Use(middleware.ClientAuth())
+	client.Get("/verify", handler.HandleLicenseVerify)
+	client.Post("/activate", handler.HandleLicenseActivate)
+	client.Post("/deactivate", handler.HandleLicenseDeactivate)
+	client.Post("/leases/checkout", handler.HandleLeaseCheckout)
+	client.Post("/leases/:id/heartbeat", handler.HandleLeaseHeartbeat)
+	client.Delete("/leases/:id", handler.HandleLeaseRelease)
+
+	// 试用许可证路由
+	trials := api.Group("/trials")
+	trials.Post("/", handler.HandleTrialRequest)
+	trials.Get("/policies", middleware.Auth(), middleware.AdminOnly(), handler.HandleGetTrialPolicies)
+	trials.Put("/policies/:productid", middleware.Auth(), middleware.AdminOnly(), handler.HandleSaveTrialPolicy)
+	trials.Get("/grants", middleware.Auth(), middleware.AdminOnly(), handler.HandleGetTrialGrants)
+
+	// 定时任务管理路由
+	jobs := api.Group("/jobs")
+	jobs.Use(middleware.Auth(), middleware.AdminOnly())
+	jobs.Get("/", handler.HandleGetJobs)
+	jobs.Get("/runs", handler.HandleGetJobRuns)
+	jobs.Get("/:name/runs", handler.HandleGetJobRuns)
+	jobs.Post("/:name/run", handler.HandleRunJob)
 
-	log.Fatal(app.Listen(":80"))
+	log.Fatal(app.Listen(":" + strconv.Itoa(cfg.Server.Port)))
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"license-management-system/internal/config"
+	"license-management-system/internal/database"
+	"license-management-system/internal/migrations"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `用法: license-manager [参数] migrate <命令>
+
+命令:
+  up         执行全部未执行的迁移
+  down [N]   回滚最近执行的 N 个迁移，默认 1 个
+  status     查看迁移执行状态`
+
+// runMigrate 执行 migrate 子命令
+func runMigrate(cfg config.DatabaseConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.Open(cfg, &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up(db)
+		for _, m := range done {
+			fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("回滚数量无效: %s", args[1])
+			}
+		}
+		done, err := migrations.Down(db, steps)
+		for _, m := range done {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		statuses, err := migrations.List(db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t名称\t状态\t执行时间")
+		for _, s := range statuses {
+			state, appliedAt := "未执行", ""
+			if s.Applied {
+				state, appliedAt = "已执行", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("未知的 migrate 命令: %s\n%s", args[0], migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// Package config 加载服务配置。
+//
+// 配置按以下顺序叠加，后者覆盖前者：内置默认值、config.yaml、
+// 环境变量、命令行参数。加载完成后统一校验，校验失败时服务拒绝启动。
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath 默认配置文件路径
+const DefaultPath = "config.yaml"
+
+// 不能用于生产环境的示例 JWT 密钥
+var placeholderSecrets = []string{"your-secret-key", "your_jwt_secret"}
+
+// minJWTSecretLength JWT 密钥的最小长度
+const minJWTSecretLength = 16
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Licensing LicensingConfig `yaml:"licensing"`
+	Retention RetentionConfig `yaml:"retention"`
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+}
+
+type ServerConfig struct {
+	Port      int    `yaml:"port"`       // 服务端口
+	JWTSecret string `yaml:"jwt_secret"` // JWT 签名密钥
+}
+
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"` // 数据库驱动: sqlite3、mysql 或 postgres
+	DSN    string `yaml:"dsn"`    // SQLite 文件路径或数据库连接字符串
+}
+
+type LicensingConfig struct {
+	SigningKey string `yaml:"signing_key"` // 离线许可证签名私钥路径
+}
+
+type RetentionConfig struct {
+	UsageDays  int `yaml:"usage_days"`   // 许可证使用记录保留天数
+	JobRunDays int `yaml:"job_run_days"` // 任务执行记录保留天数
+}
+
+type TelemetryConfig struct {
+	GeoIPDB string `yaml:"geoip_db"` // 离线 GeoIP 国家数据库 (mmdb) 路径，为空时不解析国家
+}
+
+// Default 返回内置默认配置，JWT 密钥没有默认值，必须显式配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port: 80,
+		},
+		Database: DatabaseConfig{
+			Driver: "sqlite3",
+			DSN:    "data/license.db",
+		},
+		Licensing: LicensingConfig{
+			SigningKey: "data/license_signing.key",
+		},
+		Retention: RetentionConfig{
+			UsageDays:  180,
+			JobRunDays: 30,
+		},
+	}
+}
+
+// 环境变量与配置项的对应关系
+var envOverrides = []struct {
+	name  string
+	apply func(c *Config, v string) error
+}{
+	{"LICENSE_SERVER_PORT", func(c *Config, v string) error { return setInt(&c.Server.Port, v) }},
+	{"LICENSE_JWT_SECRET", func(c *Config, v string) error { c.Server.JWTSecret = v; return nil }},
+	{"LICENSE_DB_DRIVER", func(c *Config, v string) error { c.Database.Driver = v; return nil }},
+	{"LICENSE_DB_DSN", func(c *Config, v string) error { c.Database.DSN = v; return nil }},
+	{"LICENSE_SIGNING_KEY", func(c *Config, v string) error { c.Licensing.SigningKey = v; return nil }},
+	{"LICENSE_USAGE_RETENTION_DAYS", func(c *Config, v string) error { return setInt(&c.Retention.UsageDays, v) }},
+	{"LICENSE_JOB_RUN_RETENTION_DAYS", func(c *Config, v string) error { return setInt(&c.Retention.JobRunDays, v) }},
+	{"LICENSE_GEOIP_DB", func(c *Config, v string) error { c.Telemetry.GeoIPDB = v; return nil }},
+}
+
+// Load 解析命令行参数并加载配置。
+// fs 可预先注册与配置无关的参数，为 nil 时新建；解析后剩余的参数（子命令）通过 fs.Args() 获取。
+// 通过 -config 或 LICENSE_CONFIG 指定的配置文件必须存在，默认路径的文件可以不存在。
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	if fs == nil {
+		fs = flag.NewFlagSet("license-manager", flag.ContinueOnError)
+	}
+	path := fs.String("config", "", "配置文件路径 (默认 "+DefaultPath+")")
+	port := fs.Int("port", 0, "服务端口")
+	driver := fs.String("db-driver", "", "数据库驱动")
+	dsn := fs.String("db-dsn", "", "数据库连接字符串")
+	signingKey := fs.String("signing-key", "", "离线许可证签名私钥路径")
+	geoIPDB := fs.String("geoip-db", "", "离线 GeoIP 国家数据库路径")
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("解析命令行参数失败: %w", err)
+	}
+
+	cfg := Default()
+
+	configPath, required := *path, true
+	if configPath == "" {
+		configPath = os.Getenv("LICENSE_CONFIG")
+	}
+	if configPath == "" {
+		configPath, required = DefaultPath, false
+	}
+	if err := cfg.loadFile(configPath, required); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	// 命令行参数只覆盖显式指定的项
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-dsn":
+			cfg.Database.DSN = *dsn
+		case "signing-key":
+			cfg.Licensing.SigningKey = *signingKey
+		case "geoip-db":
+			cfg.Telemetry.GeoIPDB = *geoIPDB
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile 读取 YAML 配置文件，未出现的配置项保留默认值
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 应用环境变量覆盖
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, env := range envOverrides {
+		v, ok := lookup(env.name)
+		if !ok {
+			continue
+		}
+		if err := env.apply(c, v); err != nil {
+			return fmt.Errorf("环境变量 %s 无效: %w", env.name, err)
+		}
+	}
+	return nil
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port 须在 1 到 65535 之间: %d", c.Server.Port))
+	}
+
+	secret := strings.TrimSpace(c.Server.JWTSecret)
+	switch {
+	case secret == "":
+		errs = append(errs, errors.New("server.jwt_secret 不能为空"))
+	case isPlaceholderSecret(secret):
+		errs = append(errs, errors.New("server.jwt_secret 不能使用示例密钥"))
+	case len(secret) < minJWTSecretLength:
+		errs = append(errs, fmt.Errorf("server.jwt_secret 长度不能少于 %d 个字符", minJWTSecretLength))
+	}
+
+	if NormalizeDriver(c.Database.Driver) == "" {
+		errs = append(errs, fmt.Errorf("不支持的数据库驱动 database.driver: %q", c.Database.Driver))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn 不能为空"))
+	}
+
+	if c.Licensing.SigningKey == "" {
+		errs = append(errs, errors.New("licensing.signing_key 不能为空"))
+	}
+
+	if c.Retention.UsageDays < 1 || c.Retention.JobRunDays < 1 {
+		errs = append(errs, errors.New("retention 的保留天数必须大于 0"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NormalizeDriver 将驱动名称统一为 sqlite、mysql 或 postgres，不支持的驱动返回空字符串
+func NormalizeDriver(driver string) string {
+	switch strings.ToLower(driver) {
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	case "mysql":
+		return "mysql"
+	case "postgres", "postgresql", "pgx":
+		return "postgres"
+	}
+	return ""
+}
+
+func isPlaceholderSecret(secret string) bool {
+	for _, p := range placeholderSecrets {
+		if secret == p {
+			return true
+		}
+	}
+	return false
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123"
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: 8080
+  jwt_secret: "`+testSecret+`"
+database:
+  dsn: "file.db"
+telemetry:
+  geoip_db: "file.mmdb"
+`), 0600))
+
+	t.Setenv("LICENSE_DB_DSN", "env.db")
+	t.Setenv("LICENSE_USAGE_RETENTION_DAYS", "90")
+
+	cfg, err := Load(nil, []string{"-config", path, "-port", "9090", "-geoip-db", "flag.mmdb"})
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Server.Port)            // 命令行参数
+	assert.Equal(t, "env.db", cfg.Database.DSN)       // 环境变量
+	assert.Equal(t, testSecret, cfg.Server.JWTSecret) // 配置文件
+	assert.Equal(t, 90, cfg.Retention.UsageDays)
+	assert.Equal(t, "sqlite3", cfg.Database.Driver) // 默认值
+	assert.Equal(t, "flag.mmdb", cfg.Telemetry.GeoIPDB)
+}
+
+func TestLoadSubcommand(t *testing.T) {
+	t.Setenv("LICENSE_JWT_SECRET", testSecret)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	migrate := fs.Bool("migrate", false, "")
+	cfg, err := Load(fs, []string{"-port", "8081", "-migrate", "migrate", "down", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, 8081, cfg.Server.Port)
+	assert.True(t, *migrate)
+	assert.Equal(t, []string{"migrate", "down", "2"}, fs.Args())
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing_file", args: []string{"-config", filepath.Join(dir, "missing.yaml")}},
+		{name: "unknown_field", args: []string{"-config", write("unknown.yaml", "server:\n  jwt_secret: \""+testSecret+"\"\n  host: x\n")}},
+		{name: "missing_secret", args: []string{"-config", write("nosecret.yaml", "server:\n  port: 80\n")}},
+		{name: "placeholder_secret", args: []string{"-config", write("placeholder.yaml", "server:\n  jwt_secret: your-secret-key\n")}},
+		{name: "invalid_port", args: []string{"-config", write("port.yaml", "server:\n  jwt_secret: \""+testSecret+"\"\n"), "-port", "70000"}},
+		{name: "unsupported_driver", args: []string{"-config", write("driver.yaml", "server:\n  jwt_secret: \""+testSecret+"\"\n"), "-db-driver", "oracle"}},
+		{name: "unknown_flag", args: []string{"-listen", ":80"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(nil, tt.args)
+			assert.Error(t, err)
+		})
+	}
+}
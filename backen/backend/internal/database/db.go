@@ -1,38 +1,37 @@
 package database
 
 import (
+	"fmt"
+	"license-management-system/internal/config"
+	"license-management-system/internal/migrations"
 	"license-management-system/internal/model"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/glebarez/sqlite"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func InitDB() {
-	var err error
-	// 创建数据目录
-	dataDir := "data"
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.Fatal("创建数据目录失败:", err)
-	}
-
-	// 使用 data 目录下的数据库文件
-	dbPath := filepath.Join(dataDir, "license.db")
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+// Connect 按配置连接数据库并检查结构版本，成功后设置 DB
+func Connect(cfg config.DatabaseConfig) error {
+	db, err := Open(cfg, &gorm.Config{})
 	if err != nil {
-		log.Fatal("数据库连接失败:", err)
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	if err := migrations.Check(db); err != nil {
+		return fmt.Errorf("数据库结构版本不匹配: %w\n请先执行 migrate up 升级数据库，或使用与数据库版本一致的程序", err)
 	}
+	DB = db
+	return nil
+}
 
-	// 自动迁移模型
-	err = DB.AutoMigrate(&model.User{}, &model.License{}, &model.OperationLog{}, &model.LoginLog{})
-	if err != nil {
-		log.Fatal("数据库迁移失败:", err)
+// InitDB 连接数据库并初始化管理员账户。
+// 数据库结构版本与程序不一致时拒绝启动，需要先执行 migrate up
+func InitDB(cfg config.DatabaseConfig) {
+	if err := Connect(cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// 检查是否已存在管理员账户
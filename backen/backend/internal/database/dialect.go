@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+	"license-management-system/internal/config"
+	"os"
+	"path/filepath"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 支持的数据库方言，取值与 gorm Dialector.Name() 一致
+const (
+	DialectSQLite   = "sqlite"
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+// BucketUnit 时间分组粒度
+type BucketUnit string
+
+const (
+	BucketDay   BucketUnit = "day"
+	BucketWeek  BucketUnit = "week"
+	BucketMonth BucketUnit = "month"
+)
+
+// Open 按配置的驱动打开数据库连接
+func Open(cfg config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	// 各驱动的唯一索引冲突统一转换为 gorm.ErrDuplicatedKey
+	gormConfig.TranslateError = true
+	switch config.NormalizeDriver(cfg.Driver) {
+	case DialectSQLite:
+		// SQLite 数据库文件所在目录不存在时自动创建
+		if dir := filepath.Dir(cfg.DSN); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, fmt.Errorf("创建数据目录失败: %w", err)
+			}
+		}
+		return gorm.Open(sqlite.Open(cfg.DSN), gormConfig)
+	case DialectMySQL:
+		return gorm.Open(mysql.Open(cfg.DSN), gormConfig)
+	case DialectPostgres:
+		return gorm.Open(postgres.Open(cfg.DSN), gormConfig)
+	}
+	return nil, fmt.Errorf("不支持的数据库驱动: %q", cfg.Driver)
+}
+
+// DateBucket 返回将时间列按天、周或月分组的 SQL 表达式。
+// 各方言的结果格式一致：按天和按周为 2006-01-02（按周取周一），按月为 2006-01。
+func DateBucket(db *gorm.DB, column string, unit BucketUnit) string {
+	switch db.Dialector.Name() {
+	case DialectMySQL:
+		switch unit {
+		case BucketWeek:
+			return fmt.Sprintf("DATE_FORMAT(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d')", column, column)
+		case BucketMonth:
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", column)
+		}
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+	case DialectPostgres:
+		switch unit {
+		case BucketWeek:
+			return fmt.Sprintf("to_char(date_trunc('week', %s), 'YYYY-MM-DD')", column)
+		case BucketMonth:
+			return fmt.Sprintf("to_char(%s, 'YYYY-MM')", column)
+		}
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", column)
+	}
+
+	// SQLite 以带时区的文本保存时间，直接截取本地日期，避免日期函数换算到 UTC
+	switch unit {
+	case BucketWeek:
+		return fmt.Sprintf("date(substr(%s, 1, 10), 'weekday 0', '-6 days')", column)
+	case BucketMonth:
+		return fmt.Sprintf("substr(%s, 1, 7)", column)
+	}
+	return fmt.Sprintf("substr(%s, 1, 10)", column)
+}
+
+// TextDateBucket 与 DateBucket 相同，用于以 YYYY-MM-DD 文本保存的日期列
+func TextDateBucket(db *gorm.DB, column string, unit BucketUnit) string {
+	if unit == BucketDay {
+		return column
+	}
+	if db.Dialector.Name() == DialectPostgres {
+		column = fmt.Sprintf("CAST(%s AS DATE)", column)
+	}
+	return DateBucket(db, column, unit)
+}
+
+// SecondsBetween 返回两个时间列之间相差秒数的 SQL 表达式
+func SecondsBetween(db *gorm.DB, start, end string) string {
+	switch db.Dialector.Name() {
+	case DialectMySQL:
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", start, end)
+	case DialectPostgres:
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s))", end, start)
+	}
+	return fmt.Sprintf("((julianday(%s) - julianday(%s)) * 86400)", end, start)
+}
+
+// ByLicenseKey 按许可证密钥查询。key 是 MySQL 的保留字，必须由方言负责加引号
+func ByLicenseKey(key string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key})
+	}
+}
+
+// ByLicenseKeys 按多个许可证密钥查询
+func ByLicenseKeys(keys []string) func(*gorm.DB) *gorm.DB {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.IN{Column: clause.Column{Name: "key"}, Values: values})
+	}
+}
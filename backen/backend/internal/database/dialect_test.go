@@ -0,0 +1,60 @@
+package database
+
+import (
+	"license-management-system/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectHelpers(t *testing.T) {
+	InitTestDB()
+	defer CleanTestDB()
+
+	// 2024-03-06 是星期三
+	started := time.Date(2024, 3, 6, 23, 30, 0, 0, time.Local)
+	run := &model.JobRun{
+		JobName:    "dialect",
+		StartedAt:  started,
+		FinishedAt: started.Add(90 * time.Minute),
+	}
+	assert.NoError(t, DB.Create(run).Error)
+	rollup := &model.DailyProductUsage{Day: "2024-03-06", ProductId: "gold"}
+	assert.NoError(t, DB.Create(rollup).Error)
+
+	tests := []struct {
+		name  string
+		table interface{}
+		id    uint
+		expr  string
+		want  string
+	}{
+		{name: "day", table: &model.JobRun{}, id: run.ID, expr: DateBucket(DB, "started_at", BucketDay), want: "2024-03-06"},
+		{name: "week", table: &model.JobRun{}, id: run.ID, expr: DateBucket(DB, "started_at", BucketWeek), want: "2024-03-04"},
+		{name: "month", table: &model.JobRun{}, id: run.ID, expr: DateBucket(DB, "started_at", BucketMonth), want: "2024-03"},
+		{name: "text_day", table: &model.DailyProductUsage{}, id: rollup.ID, expr: TextDateBucket(DB, "day", BucketDay), want: "2024-03-06"},
+		{name: "text_week", table: &model.DailyProductUsage{}, id: rollup.ID, expr: TextDateBucket(DB, "day", BucketWeek), want: "2024-03-04"},
+		{name: "text_month", table: &model.DailyProductUsage{}, id: rollup.ID, expr: TextDateBucket(DB, "day", BucketMonth), want: "2024-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			assert.NoError(t, DB.Model(tt.table).Select(tt.expr).Where("id = ?", tt.id).Scan(&got).Error)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	var seconds float64
+	assert.NoError(t, DB.Model(&model.JobRun{}).
+		Select(SecondsBetween(DB, "started_at", "finished_at")).
+		Where("id = ?", run.ID).
+		Scan(&seconds).Error)
+	assert.InDelta(t, 5400, seconds, 1)
+
+	var count int64
+	assert.NoError(t, DB.Create(&model.License{Key: "KEY-1", Status: model.LicenseActive}).Error)
+	assert.NoError(t, DB.Model(&model.License{}).Scopes(ByLicenseKey("KEY-1")).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
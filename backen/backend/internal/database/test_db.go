@@ -1,24 +1,57 @@
 package database
 
 import (
-	"license-management-system/internal/model"
+	"license-management-system/internal/config"
+	"license-management-system/internal/migrations"
+	"os"
+	"strings"
 
-	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 )
 
+// InitTestDB 初始化测试数据库。默认使用内存 SQLite；
+// 设置 LICENSE_TEST_DB_DRIVER 和 LICENSE_TEST_DB_DSN 后改用对应的 MySQL 或 PostgreSQL，
+// 每次初始化都会删除全部表并重新执行迁移。
 func InitTestDB() {
+	cfg := config.DatabaseConfig{
+		Driver: os.Getenv("LICENSE_TEST_DB_DRIVER"),
+		DSN:    os.Getenv("LICENSE_TEST_DB_DSN"),
+	}
+	if cfg.Driver == "" {
+		cfg = config.DatabaseConfig{Driver: "sqlite", DSN: "file::memory:?cache=shared"}
+	}
+
 	var err error
-	DB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	DB, err = Open(cfg, &gorm.Config{})
 	if err != nil {
-		panic("failed to connect test database")
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := dropAllTables(DB); err != nil {
+		panic("failed to reset test database: " + err.Error())
 	}
 
-	// 自动迁移测试数据库
-	err = DB.AutoMigrate(&model.User{}, &model.License{}, &model.OperationLog{})
+	// 与生产环境一样通过迁移建表
+	if _, err := migrations.Up(DB); err != nil {
+		panic("failed to migrate test database: " + err.Error())
+	}
+}
+
+// dropAllTables 删除数据库中的全部表，包括 schema_version
+func dropAllTables(db *gorm.DB) error {
+	tables, err := db.Migrator().GetTables()
 	if err != nil {
-		panic("failed to migrate test database")
+		return err
+	}
+	for _, table := range tables {
+		if strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+		if err := db.Migrator().DropTable(table); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func CleanTestDB() {
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientKeyInput 创建或轮换 API 密钥的输入
+type ClientKeyInput struct {
+	Name       string `json:"name"`
+	GraceHours *int   `json:"grace_hours"` // 轮换时旧密钥的宽限期（小时），默认 24
+}
+
+// clientKeyResponse 新密钥的 secret 只在创建和轮换时返回一次
+func clientKeyResponse(key *model.ClientAPIKey) fiber.Map {
+	return fiber.Map{
+		"api_key": key,
+		"secret":  key.Secret,
+	}
+}
+
+// clientKeyError 返回 API 密钥操作的错误响应
+func clientKeyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	case errors.Is(err, service.ErrClientKeyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrClientKeyInvalid), errors.Is(err, service.ErrInvalidGracePeriod):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "API 密钥操作失败",
+	})
+}
+
+// HandleGetClientKeys 获取产品的 API 密钥列表，不包含 secret
+func HandleGetClientKeys(c *fiber.Ctx) error {
+	keys, err := service.ListClientKeys(c.Params("code"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取 API 密钥失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+// HandleCreateClientKey 为产品创建客户端 API 密钥
+func HandleCreateClientKey(c *fiber.Ctx) error {
+	input := new(ClientKeyInput)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "无效的输入数据",
+			})
+		}
+	}
+
+	key, err := service.CreateClientKey(c.Params("code"), input.Name)
+	if err != nil {
+		return clientKeyError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(clientKeyResponse(key))
+}
+
+// HandleRotateClientKey 轮换 API 密钥：生成新密钥，旧密钥在宽限期后失效
+func HandleRotateClientKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的密钥ID",
+		})
+	}
+
+	input := new(ClientKeyInput)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "无效的输入数据",
+			})
+		}
+	}
+	grace := service.DefaultKeyRotationGrace
+	if input.GraceHours != nil {
+		grace = time.Duration(*input.GraceHours) * time.Hour
+	}
+
+	key, err := service.RotateClientKey(c.Params("code"), uint(id), grace)
+	if err != nil {
+		return clientKeyError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(clientKeyResponse(key))
+}
+
+// HandleRevokeClientKey 立即停用 API 密钥
+func HandleRevokeClientKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的密钥ID",
+		})
+	}
+
+	key, err := service.RevokeClientKey(c.Params("code"), uint(id))
+	if err != nil {
+		return clientKeyError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API 密钥已停用",
+		"api_key": key,
+	})
+}
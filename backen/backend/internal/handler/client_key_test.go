@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/middleware"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type clientKeyBody struct {
+	APIKey model.ClientAPIKey `json:"api_key"`
+	Secret string             `json:"secret"`
+}
+
+func TestHandleClientRequests(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/products/:code/api-keys", HandleGetClientKeys)
+	app.Post("/api/v1/products/:code/api-keys", HandleCreateClientKey)
+	app.Post("/api/v1/products/:code/api-keys/:id/rotate", HandleRotateClientKey)
+	app.Delete("/api/v1/products/:code/api-keys/:id", HandleRevokeClientKey)
+	client := app.Group("/api/v1/client", middleware.ClientAuth())
+	client.Get("/verify", HandleLicenseVerify)
+	client.Post("/activate", HandleLicenseActivate)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&[]model.Product{{Code: "gold", Name: "Gold Scalper"}, {Code: "silver", Name: "Silver Scalper"}})
+	gold, _ := keygen.Generate("")
+	silver, _ := keygen.Generate("")
+	database.DB.Create(&[]model.License{
+		{Key: gold, Status: model.LicenseActive, ProductId: "gold", UserId: "10086", ValidUntil: time.Now().AddDate(0, 0, 30), MaxSeats: 1},
+		{Key: silver, Status: model.LicenseActive, ProductId: "silver", UserId: "10086", ValidUntil: time.Now().AddDate(0, 0, 30)},
+	})
+
+	admin := func(method, path, body string) (int, clientKeyBody) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out clientKeyBody
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, _ := admin("POST", "/api/v1/products/platinum/api-keys", `{"name":"ea"}`)
+	assert.Equal(t, fiber.StatusNotFound, status)
+	status, created := admin("POST", "/api/v1/products/gold/api-keys", `{"name":"ea"}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, "gold", created.APIKey.ProductId)
+
+	nonce := 0
+	signed := func(key clientKeyBody, method, uri string, ts time.Time, mutate func(*http.Request)) int {
+		nonce++
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		n := "n-" + strconv.Itoa(nonce)
+		req, _ := http.NewRequest(method, uri, nil)
+		req.Header.Set(licensing.HeaderClientKey, key.APIKey.KeyID)
+		req.Header.Set(licensing.HeaderClientTimestamp, timestamp)
+		req.Header.Set(licensing.HeaderClientNonce, n)
+		req.Header.Set(licensing.HeaderClientSignature, licensing.SignRequest(key.Secret, method, uri, timestamp, n, nil))
+		if mutate != nil {
+			mutate(req)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	verifyURI := "/api/v1/client/verify?key=" + gold + "&userid=10086&productid=gold&terminal_id=T-A&account=10086&nonce=v-1"
+
+	tests := []struct {
+		name       string
+		method     string
+		uri        string
+		ts         time.Time
+		mutate     func(*http.Request)
+		wantStatus int
+	}{
+		{name: "activate", method: "POST", uri: "/api/v1/client/activate?key=" + gold + "&terminal_id=T-A&account=10086&nonce=a-1", ts: time.Now(), wantStatus: fiber.StatusOK},
+		{name: "verify", method: "GET", uri: verifyURI, ts: time.Now(), wantStatus: fiber.StatusOK},
+		{name: "verify_without_nonce", method: "GET", uri: "/api/v1/client/verify?key=" + gold + "&userid=10086&productid=gold&terminal_id=T-A&account=10086", ts: time.Now(), wantStatus: fiber.StatusBadRequest},
+		{name: "activate_without_nonce", method: "POST", uri: "/api/v1/client/activate?key=" + gold + "&terminal_id=T-A&account=10086", ts: time.Now(), wantStatus: fiber.StatusBadRequest},
+		{name: "missing_key", method: "GET", uri: verifyURI, ts: time.Now(), mutate: func(r *http.Request) { r.Header.Del(licensing.HeaderClientKey) }, wantStatus: fiber.StatusUnauthorized},
+		{name: "bad_signature", method: "GET", uri: verifyURI, ts: time.Now(), mutate: func(r *http.Request) { r.Header.Set(licensing.HeaderClientSignature, "00") }, wantStatus: fiber.StatusUnauthorized},
+		{name: "tampered_query", method: "GET", uri: verifyURI, ts: time.Now(), mutate: func(r *http.Request) { r.URL.RawQuery += "&account=1" }, wantStatus: fiber.StatusUnauthorized},
+		{name: "expired", method: "GET", uri: verifyURI, ts: time.Now().Add(-10 * time.Minute), wantStatus: fiber.StatusUnauthorized},
+		{name: "other_product_query", method: "GET", uri: "/api/v1/client/verify?key=" + silver + "&userid=10086&productid=silver&nonce=v-2", ts: time.Now(), wantStatus: fiber.StatusForbidden},
+		{name: "other_product_license", method: "POST", uri: "/api/v1/client/activate?key=" + silver + "&terminal_id=T-A&account=10086&nonce=a-2", ts: time.Now(), wantStatus: fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, signed(created, tt.method, tt.uri, tt.ts, tt.mutate))
+		})
+	}
+
+	// 同一随机数不能重复使用
+	replay := func(r *http.Request) {
+		r.Header.Set(licensing.HeaderClientNonce, "n-2")
+		r.Header.Set(licensing.HeaderClientSignature, licensing.SignRequest(created.Secret, r.Method, verifyURI, r.Header.Get(licensing.HeaderClientTimestamp), "n-2", nil))
+	}
+	assert.Equal(t, fiber.StatusUnauthorized, signed(created, "GET", verifyURI, time.Now(), replay))
+
+	// 轮换后宽限期内新旧密钥都可使用
+	id := strconv.Itoa(int(created.APIKey.ID))
+	status, _ = admin("POST", "/api/v1/products/gold/api-keys/"+id+"/rotate", `{"grace_hours":-1}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	status, rotated := admin("POST", "/api/v1/products/gold/api-keys/"+id+"/rotate", "")
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.NotEqual(t, created.APIKey.KeyID, rotated.APIKey.KeyID)
+	assert.Equal(t, fiber.StatusOK, signed(created, "GET", verifyURI, time.Now(), nil))
+	assert.Equal(t, fiber.StatusOK, signed(rotated, "GET", verifyURI, time.Now(), nil))
+
+	var old model.ClientAPIKey
+	database.DB.First(&old, created.APIKey.ID)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), old.ExpiresAt, time.Minute)
+
+	// 宽限期结束后旧密钥失效
+	database.DB.Model(&old).Update("expires_at", time.Now().Add(-time.Second))
+	assert.Equal(t, fiber.StatusUnauthorized, signed(created, "GET", verifyURI, time.Now(), nil))
+
+	// 停用立即生效
+	status, _ = admin("DELETE", "/api/v1/products/gold/api-keys/"+strconv.Itoa(int(rotated.APIKey.ID)), "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, fiber.StatusUnauthorized, signed(rotated, "GET", verifyURI, time.Now(), nil))
+	status, _ = admin("DELETE", "/api/v1/products/silver/api-keys/"+id, "")
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	// 列表不返回 secret
+	req, _ := http.NewRequest("GET", "/api/v1/products/gold/api-keys", nil)
+	resp, _ := app.Test(req)
+	var list map[string][]map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&list)
+	if assert.Len(t, list["api_keys"], 2) {
+		assert.NotContains(t, list["api_keys"][0], "secret")
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/scheduler"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleGetJobs 获取已注册的定时任务及其调度信息
+func HandleGetJobs(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"jobs": scheduler.Default.Jobs(),
+	})
+}
+
+// HandleGetJobRuns 获取定时任务的执行历史
+func HandleGetJobRuns(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+
+	// 限制页面大小
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	runs, total, err := scheduler.GetJobRuns(c.Params("name"), page, pageSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取任务执行记录失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"runs":  runs,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// HandleRunJob 立即执行指定任务
+func HandleRunJob(c *fiber.Ctx) error {
+	run, err := scheduler.Default.RunNow(c.Params("name"))
+	if errors.Is(err, scheduler.ErrJobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "任务不存在",
+		})
+	}
+	if errors.Is(err, scheduler.ErrJobRunning) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "任务正在执行，请稍后再试",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "执行任务失败",
+		})
+	}
+
+	return c.JSON(run)
+}
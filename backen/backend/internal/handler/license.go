@@ -1,57 +1,213 @@
 package handler
 
 import (
+	"errors"
 	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
 	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/telemetry"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type LicenseInput struct {
-	Version         string    `json:"version"`
-	ValidUntil      time.Time `json:"valid_until"`
-	Permissions     string    `json:"permissions"`
-	UserId          string    `json:"userid"`
-	ProductId       string    `json:"productid"`
-	LastActivatedAt time.Time `json:"last_activated_at"`
+	Version           string                 `json:"version"`
+	VersionConstraint string                 `json:"version_constraint"` // 允许的客户端版本范围，默认使用产品配置
+	MaxMajorVersion   int                    `json:"max_major_version"`  // 允许的最大主版本号，默认使用产品配置
+	ValidUntil        time.Time              `json:"valid_until"`
+	Entitlements      licensing.Entitlements `json:"entitlements"` // 单独设置的权益，未设置的字段使用产品默认值
+	UserId            string                 `json:"userid"`
+	ProductId         string                 `json:"productid"`
+	LastActivatedAt   time.Time              `json:"last_activated_at"`
+	Prefix            string                 `json:"prefix"`           // 密钥前缀，默认使用产品配置
+	MaxSeats          int                    `json:"max_seats"`        // 可激活的设备数，默认使用产品配置
+	MaxConcurrent     int                    `json:"max_concurrent"`   // 浮动许可证并发实例数，0 表示不限制
+	AllowedAccounts   []string               `json:"allowed_accounts"` // 允许使用的交易账号
+	MaxAccounts       int                    `json:"max_accounts"`     // 账号数上限，未满时自动绑定新账号
+	AllowedBrokers    []string               `json:"allowed_brokers"`  // 允许的经纪商或服务器名称（通配符）
+	AccountType       model.AccountType      `json:"account_type"`     // demo、live 或 any
 }
 
-// HandleGetAllLicenses 管理员获取所有许可证数据
+// spec 转换为生成许可证的参数
+func (in *LicenseInput) spec() service.LicenseSpec {
+	return service.LicenseSpec{
+		ProductId:         in.ProductId,
+		UserId:            in.UserId,
+		Version:           in.Version,
+		VersionConstraint: in.VersionConstraint,
+		MaxMajorVersion:   in.MaxMajorVersion,
+		Entitlements:      in.Entitlements,
+		Prefix:            in.Prefix,
+		ValidUntil:        in.ValidUntil,
+		MaxSeats:          in.MaxSeats,
+		MaxConcurrent:     in.MaxConcurrent,
+		AllowedAccounts:   in.AllowedAccounts,
+		MaxAccounts:       in.MaxAccounts,
+		AllowedBrokers:    in.AllowedBrokers,
+		AccountType:       in.AccountType,
+	}
+}
+
+// LicenseListQuery 许可证列表的查询参数，时间参数为 YYYY-MM-DD 或 RFC3339 格式，
+// 范围包含起点不包含终点
+type LicenseListQuery struct {
+	Page          int    `query:"page"`
+	PageSize      int    `query:"page_size"`
+	Cursor        string `query:"cursor"`
+	Sort          string `query:"sort"`
+	Order         string `query:"order"`
+	Keyword       string `query:"keyword"`
+	Status        string `query:"status"`
+	ProductId     string `query:"productid"`
+	UserId        string `query:"userid"`
+	IssuedTo      uint   `query:"issued_to"`
+	ExpiresAfter  string `query:"expires_after"`
+	ExpiresBefore string `query:"expires_before"`
+	CreatedAfter  string `query:"created_after"`
+	CreatedBefore string `query:"created_before"`
+}
+
+// filter 转换为查询条件，参数无效时返回的错误可直接返回给客户端
+func (q *LicenseListQuery) filter() (service.LicenseFilter, error) {
+	f := service.LicenseFilter{
+		Status:    model.LicenseStatus(q.Status),
+		ProductId: q.ProductId,
+		UserId:    q.UserId,
+		IssuedTo:  q.IssuedTo,
+		Search:    q.Keyword,
+	}
+	if f.Status != "" && !f.Status.IsValid() {
+		return f, errors.New("无效的许可证状态")
+	}
+
+	dates := []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"expires_after", q.ExpiresAfter, &f.ExpiresAfter},
+		{"expires_before", q.ExpiresBefore, &f.ExpiresBefore},
+		{"created_after", q.CreatedAfter, &f.CreatedAfter},
+		{"created_before", q.CreatedBefore, &f.CreatedBefore},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseQueryDate(d.value)
+		if err != nil {
+			return f, errors.New(d.name + " 日期格式应为 YYYY-MM-DD 或 RFC3339")
+		}
+		*d.dst = t
+	}
+	return f, nil
+}
+
+// parseQueryDate 解析 YYYY-MM-DD（本地时间零点）或 RFC3339 格式的时间
+func parseQueryDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// HandleGetAllLicenses 管理员分页查询许可证。
+// 默认按页码分页并返回总数；传入上一页返回的 next_cursor 时按游标继续查询，
+// 适合遍历大量数据，此时不返回总数
 func HandleGetAllLicenses(c *fiber.Ctx) error {
-	// TODO: 添加管理员权限验证
-	// 这里需要实现管理员权限验证逻辑
+	query := new(LicenseListQuery)
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的查询参数",
+		})
+	}
+	filter, err := query.filter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	var licenses []model.License
-	result := database.DB.Find(&licenses)
-	if result.Error != nil {
+	// 设置默认值
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 10
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+	if query.Order != "" && query.Order != "asc" && query.Order != "desc" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "order 只能为 asc 或 desc",
+		})
+	}
+
+	page, err := service.PageLicenses(filter, service.LicensePage{
+		Sort:   query.Sort,
+		Desc:   query.Order != "asc",
+		Offset: (query.Page - 1) * query.PageSize,
+		Limit:  query.PageSize,
+		Cursor: query.Cursor,
+	})
+	if errors.Is(err, service.ErrInvalidSortColumn) || errors.Is(err, service.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "获取许可证数据失败",
 		})
 	}
 
+	if query.Cursor != "" {
+		return c.JSON(fiber.Map{
+			"licenses":    page.Licenses,
+			"size":        query.PageSize,
+			"next_cursor": page.NextCursor,
+		})
+	}
+
+	total, err := service.CountLicenses(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取许可证总数失败",
+		})
+	}
 	return c.JSON(fiber.Map{
-		"licenses": licenses,
+		"licenses":    page.Licenses,
+		"total":       total,
+		"page":        query.Page,
+		"size":        query.PageSize,
+		"next_cursor": page.NextCursor,
 	})
 }
+
 func HandleLicenseGenerate(c *fiber.Ctx) error {
 	input := new(LicenseInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
 
-	// 生成唯一的许可证密钥
-	key := generateLicenseKey()
-
-	license := &model.License{
-		Key:         key,
-		Status:      "active",
-		ValidUntil:  time.Now().AddDate(0, 0, 30),
-		Version:     input.Version,
-		Permissions: input.Permissions,
-		UserId:      input.UserId,
-		ProductId:   input.ProductId,
-
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-		LastActivatedAt: time.Now(),
+	license, err := service.BuildLicense(input.spec())
+	if isLicenseSpecError(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "许可证密钥生成失败",
+		})
 	}
 
 	result := database.DB.Create(license)
@@ -78,7 +234,7 @@ func HandleLicenseIssue(c *fiber.Ctx) error {
 	}
 
 	var license model.License
-	result := database.DB.Where("key = ?", input.LicenseKey).First(&license)
+	result := database.DB.Scopes(database.ByLicenseKey(input.LicenseKey)).First(&license)
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "许可证不存在",
@@ -101,6 +257,25 @@ func HandleLicenseVerify(c *fiber.Ctx) error {
 		})
 	}
 
+	if !validVerdictNonce(c) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的随机数",
+		})
+	}
+	if hasLineBreak(c, "key", "userid") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "密钥和用户名不能包含换行",
+		})
+	}
+
+	if errors.Is(keygen.Validate(key), keygen.ErrChecksum) {
+		recordAttempt(c, "verify", key, c.Query("productid"), service.ReasonNotFound)
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "许可证密钥无效",
+			"reason": service.ReasonNotFound,
+		}, licensing.Verdict{Action: "verify", Key: key, Reason: string(service.ReasonNotFound)})
+	}
+
 	userid := c.Query("userid")
 	if userid == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -115,36 +290,160 @@ func HandleLicenseVerify(c *fiber.Ctx) error {
 		})
 	}
 
-	var license model.License
-	result := database.DB.Where("key = ?", key).First(&license)
-	if result.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "许可证不存在",
+	fp := new(model.Fingerprint)
+	if err := c.QueryParser(fp); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的设备指纹",
 		})
 	}
 
-	if license.UserId != userid || license.ProductId != productid {
+	accountType, ok := model.ParseAccountType(c.Query("account_type"))
+	if !ok {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "用户名或策略名称不匹配",
+			"error": "无效的账户类型",
+		})
+	}
+
+	result, err := service.VerifyLicense(service.VerifyRequest{
+		Key:         key,
+		UserId:      userid,
+		ProductId:   productid,
+		Version:     c.Query("version"),
+		Fingerprint: *fp,
+		Trading: service.TradingContext{
+			Account:     userid,
+			Broker:      c.Query("broker"),
+			Server:      c.Query("server"),
+			AccountType: accountType,
+		},
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+		Platform:  c.Query("platform"),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "许可证校验失败",
 		})
 	}
 
-	isValid := license.Status != "已吊销" && time.Now().Before(license.ValidUntil)
+	verdict := licensing.Verdict{
+		Action:       "verify",
+		Key:          key,
+		Valid:        result.Valid,
+		Reason:       string(result.Reason),
+		Entitlements: result.Entitlements,
+	}
+	if result.License != nil {
+		verdict.Status = string(result.License.Status)
+	}
+
+	switch result.Reason {
+	case service.ReasonNotFound:
+		return respondVerdict(c, fiber.StatusNotFound, fiber.Map{
+			"error":  "许可证不存在",
+			"reason": result.Reason,
+		}, verdict)
+	case service.ReasonMismatch:
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "用户名或策略名称不匹配",
+			"reason": result.Reason,
+		}, verdict)
+	case service.ReasonFingerprintRequired:
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "设备指纹不能为空",
+			"reason": result.Reason,
+		}, verdict)
+	case service.ReasonDeviceNotActivated:
+		return respondVerdict(c, fiber.StatusForbidden, fiber.Map{
+			"valid":  false,
+			"status": result.License.Status,
+			"reason": result.Reason,
+			"error":  "设备未激活",
+		}, verdict)
+	}
+
+	body := fiber.Map{
+		"valid":          result.Valid,
+		"status":         result.License.Status,
+		"reason":         result.Reason,
+		"entitlements":   result.Entitlements,
+		"latest_version": result.LatestVersion,
+	}
+	// 校验通过时附带离线凭证，客户端重启且无法连接服务器时在到期前凭它继续运行
+	if result.Token != nil {
+		token, err := licensing.SignToken(util.SigningKey(), *result.Token)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "离线凭证签名失败",
+			})
+		}
+		body["verdict_token"] = token
+		body["token_expires_at"] = result.Token.ExpiresAt
+	}
+	return respondVerdict(c, fiber.StatusOK, body, verdict)
+}
+
+// clientInfo 解析请求的客户端信息，客户端可通过 platform 参数上报操作系统
+func clientInfo(c *fiber.Ctx) telemetry.Client {
+	return telemetry.Resolve(c.IP(), c.Get(fiber.HeaderUserAgent), c.Query("platform"))
+}
 
-	// 记录license验证使用情况
-	usage := model.LicenseUsage{
+// recordAttempt 记录一次校验或激活尝试及其结果，许可证不存在时 productID 为客户端请求的产品
+func recordAttempt(c *fiber.Ctx, action, key, productID string, reason service.VerifyReason) {
+	service.RecordUsage(&model.LicenseUsage{
 		LicenseKey: key,
-		Action:     "verify",
-		IPAddress:  c.IP(),
-		UserAgent:  c.Get("User-Agent"),
-		Timestamp:  time.Now(),
+		ProductId:  productID,
+		Action:     action,
+		Reason:     string(reason),
+	}, clientInfo(c), c.Get(fiber.HeaderUserAgent))
+}
+
+// validVerdictNonce 客户端携带的随机数会写入签名内容，只能包含可见 ASCII 字符且不超过 64 个字符。
+// 客户端 API 的请求必须携带随机数，否则签名的结论可以被重放
+func validVerdictNonce(c *fiber.Ctx) bool {
+	nonce := c.Query("nonce")
+	if _, client := c.Locals("clientProduct").(string); client && nonce == "" {
+		return false
 	}
-	database.DB.Create(&usage)
+	if len(nonce) > 64 {
+		return false
+	}
+	for i := 0; i < len(nonce); i++ {
+		if nonce[i] <= ' ' || nonce[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
 
-	return c.JSON(fiber.Map{
-		"valid":  isValid,
-		"status": license.Status,
-	})
+// hasLineBreak 查询参数是否包含换行，签名的结论每行一个字段，字段值不能包含换行
+func hasLineBreak(c *fiber.Ctx, names ...string) bool {
+	for _, name := range names {
+		if strings.ContainsAny(c.Query(name), "\r\n") {
+			return true
+		}
+	}
+	return false
+}
+
+// signVerdict 使用服务器私钥对本次校验或激活的结论签名，签名内容包括客户端的随机数和服务器时间
+func signVerdict(c *fiber.Ctx, verdict licensing.Verdict) (*licensing.File, error) {
+	verdict.Nonce = c.Query("nonce")
+	verdict.ServerTime = time.Now()
+	return licensing.SignVerdict(util.SigningKey(), verdict)
+}
+
+// respondVerdict 返回校验或激活结果，并在 signed_verdict 中附带签名的结论，
+// 客户端应只信任用公钥校验通过的结论，防止伪造的服务器返回任意结果
+func respondVerdict(c *fiber.Ctx, status int, body fiber.Map, verdict licensing.Verdict) error {
+	signed, err := signVerdict(c, verdict)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "校验结果签名失败",
+		})
+	}
+	body["signed_verdict"] = signed
+	return c.Status(status).JSON(body)
 }
 
 // HandleLicenseUsage 查询license使用记录
@@ -177,26 +476,164 @@ func HandleLicenseActivate(c *fiber.Ctx) error {
 		})
 	}
 
+	if !validVerdictNonce(c) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的随机数",
+		})
+	}
+	if hasLineBreak(c, "key", "userid") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "密钥和用户名不能包含换行",
+		})
+	}
+
+	if errors.Is(keygen.Validate(key), keygen.ErrChecksum) {
+		recordAttempt(c, "activate", key, "", service.ReasonNotFound)
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "许可证密钥无效",
+			"reason": service.ReasonNotFound,
+		}, licensing.Verdict{Action: "activate", Key: key, Reason: string(service.ReasonNotFound)})
+	}
+
 	var license model.License
-	result := database.DB.Where("key = ?", key).First(&license)
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
 	if result.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "许可证不存在",
+		recordAttempt(c, "activate", key, "", service.ReasonNotFound)
+		return respondVerdict(c, fiber.StatusNotFound, fiber.Map{
+			"error":  "许可证不存在",
+			"reason": service.ReasonNotFound,
+		}, licensing.Verdict{Action: "activate", Key: key, Reason: string(service.ReasonNotFound)})
+	}
+	if !clientProductMatches(c, &license) {
+		recordAttempt(c, "activate", key, license.ProductId, service.ReasonMismatch)
+		return respondVerdict(c, fiber.StatusForbidden, fiber.Map{
+			"error":  "API 密钥与产品不匹配",
+			"reason": service.ReasonMismatch,
+		}, activateVerdict(key, &license, service.ReasonMismatch))
+	}
+
+	if err := service.ExpireIfOverdue(&license); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "更新许可证状态失败",
 		})
 	}
 
-	if license.Status == "已激活" {
+	// 只有未激活或已激活的许可证可以激活新设备
+	if license.Status != model.LicenseInactive && license.Status != model.LicenseActive {
+		reason := service.StatusReason(license.Status)
+		recordAttempt(c, "activate", key, license.ProductId, reason)
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "许可证" + license.Status.Label("zh") + "，无法激活",
+			"status": license.Status,
+			"reason": reason,
+		}, activateVerdict(key, &license, reason))
+	}
+
+	fp := new(model.Fingerprint)
+	if err := c.QueryParser(fp); err != nil || !fp.IsComplete() {
+		recordAttempt(c, "activate", key, license.ProductId, service.ReasonFingerprintRequired)
+		return respondVerdict(c, fiber.StatusBadRequest, fiber.Map{
+			"error":  "设备指纹不能为空",
+			"reason": service.ReasonFingerprintRequired,
+		}, activateVerdict(key, &license, service.ReasonFingerprintRequired))
+	}
+
+	accountType, ok := model.ParseAccountType(c.Query("account_type"))
+	if !ok {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "许可证已经激活",
+			"error": "无效的账户类型",
 		})
 	}
+	trading := service.TradingContext{
+		Account:     c.Query("userid"),
+		Broker:      c.Query("broker"),
+		Server:      c.Query("server"),
+		AccountType: accountType,
+	}
+	rejectBinding := func(reason service.VerifyReason) error {
+		recordAttempt(c, "activate", key, license.ProductId, reason)
+		return respondVerdict(c, fiber.StatusForbidden, fiber.Map{
+			"error":  "交易账户不符合许可证的绑定规则",
+			"reason": reason,
+		}, activateVerdict(key, &license, reason))
+	}
 
-	license.Status = "已激活"
-	license.UpdatedAt = time.Now()
+	// 先检查交易账户规则，未绑定的账号与席位在同一事务中绑定
+	bindReason, err := service.CheckBinding(&license, trading)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "许可证激活失败",
+		})
+	}
+	if bindReason != service.ReasonOK && bindReason != service.ReasonAccountNotBound {
+		return rejectBinding(bindReason)
+	}
 
-	database.DB.Save(&license)
+	// 占用席位，同一设备重复激活不会重复占用，绑定被拒绝时席位一并回滚
+	_, bindReason, err = service.ActivateSeatAndBind(&license, *fp, c.IP(), trading)
+	if err != nil {
+		if errors.Is(err, service.ErrSeatLimitReached) {
+			recordAttempt(c, "activate", key, license.ProductId, service.ReasonSeatLimit)
+			return respondVerdict(c, fiber.StatusConflict, fiber.Map{
+				"error":     "许可证席位已满，请先在其他设备上停用",
+				"max_seats": license.MaxSeats,
+				"reason":    service.ReasonSeatLimit,
+			}, activateVerdict(key, &license, service.ReasonSeatLimit))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "许可证激活失败",
+		})
+	}
+	if bindReason != service.ReasonOK {
+		return rejectBinding(bindReason)
+	}
 
-	return c.JSON(license)
+	// 记录license激活使用情况
+	recordAttempt(c, "activate", key, license.ProductId, service.ReasonOK)
+
+	if license.Status == model.LicenseInactive {
+		err := service.ChangeLicenseStatus(&license, model.LicenseActive)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "许可证激活失败",
+			})
+		}
+	} else {
+		license.LastActivatedAt = time.Now()
+		database.DB.Model(&license).Update("last_activated_at", license.LastActivatedAt)
+	}
+
+	// 激活成功时返回许可证信息和签名的结论
+	verdict := activateVerdict(key, &license, service.ReasonOK)
+	verdict.Valid = true
+	entitlements, err := service.ResolveEntitlements(&license)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取许可证权益失败",
+		})
+	}
+	verdict.Entitlements = entitlements
+	signed, err := signVerdict(c, verdict)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "校验结果签名失败",
+		})
+	}
+
+	return c.JSON(struct {
+		model.License
+		SignedVerdict *licensing.File `json:"signed_verdict"`
+	}{license, signed})
+}
+
+// activateVerdict 激活结果的结论，key 为客户端请求的密钥，失败时不包含权益
+func activateVerdict(key string, license *model.License, reason service.VerifyReason) licensing.Verdict {
+	return licensing.Verdict{
+		Action: "activate",
+		Key:    key,
+		Status: string(license.Status),
+		Reason: string(reason),
+	}
 }
 
 // HandleLicenseUpdate 更新许可证信息
@@ -211,12 +648,20 @@ func HandleLicenseUpdate(c *fiber.Ctx) error {
 
 	// 定义更新许可证的输入结构
 	type UpdateInput struct {
-		Status      string `json:"status"`
-		ValidUntil  string `json:"validuntil"`
-		Version     string `json:"version"`
-		Permissions string `json:"permissions"`
-		UserId      string `json:"userid"`
-		ProductId   string `json:"productid"`
+		Status            string                  `json:"status"`
+		ValidUntil        string                  `json:"validuntil"`
+		Version           string                  `json:"version"`
+		VersionConstraint *string                 `json:"version_constraint"`
+		MaxMajorVersion   *int                    `json:"max_major_version"`
+		Entitlements      *licensing.Entitlements `json:"entitlements"`
+		UserId            string                  `json:"userid"`
+		ProductId         string                  `json:"productid"`
+		MaxSeats          *int                    `json:"max_seats"`
+		MaxConcurrent     *int                    `json:"max_concurrent"`
+		AllowedAccounts   *[]string               `json:"allowed_accounts"`
+		MaxAccounts       *int                    `json:"max_accounts"`
+		AllowedBrokers    *[]string               `json:"allowed_brokers"`
+		AccountType       *model.AccountType      `json:"account_type"`
 	}
 
 	// 解析请求体
@@ -229,42 +674,121 @@ func HandleLicenseUpdate(c *fiber.Ctx) error {
 
 	// 查找许可证
 	var license model.License
-	result := database.DB.Where("key = ?", key).First(&license)
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "许可证不存在",
 		})
 	}
 
-	// 更新许可证信息
+	// 先校验全部字段，状态变更和其余字段最后在一个事务中保存
+	// 状态变更需符合状态机规则
+	status := model.LicenseStatus(input.Status)
 	if input.Status != "" {
-		license.Status = input.Status
+		if err := service.CheckStatusChange(&license, status); err != nil {
+			return statusChangeError(c, &license, status)
+		}
 	}
+
+	// 更新许可证信息
 	if input.ValidUntil != "" {
 		parsedTime, err := time.Parse(time.RFC3339, input.ValidUntil)
-		if err == nil {
-			license.ValidUntil = parsedTime
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "有效期格式应为 RFC3339，例如 2025-12-31T23:59:59Z",
+			})
 		}
+		if !parsedTime.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": service.ErrInvalidValidUntil.Error(),
+			})
+		}
+		license.ValidUntil = parsedTime
 	}
 	if input.Version != "" {
 		license.Version = input.Version
 	}
-	if input.Permissions != "" {
-		license.Permissions = input.Permissions
+	if input.VersionConstraint != nil {
+		license.VersionConstraint = *input.VersionConstraint
+	}
+	if input.MaxMajorVersion != nil {
+		license.MaxMajorVersion = *input.MaxMajorVersion
+	}
+	if err := service.ValidateVersionRule(license.VersionConstraint, license.MaxMajorVersion); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 	if input.UserId != "" {
 		license.UserId = input.UserId
 	}
 	if input.ProductId != "" {
+		if _, err := service.GetProduct(input.ProductId); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "产品不存在",
+			})
+		}
 		license.ProductId = input.ProductId
 	}
-
-	// 更新时间戳
-	license.UpdatedAt = time.Now()
+	if input.Entitlements != nil {
+		license.Entitlements = *input.Entitlements
+	}
+	// 权益须在（可能已变更的）产品权益范围内
+	if input.Entitlements != nil || input.ProductId != "" {
+		err := service.ValidateEntitlements(license.ProductId, license.Entitlements)
+		if isEntitlementError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil && !errors.Is(err, service.ErrProductNotFound) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "校验许可证权益失败",
+			})
+		}
+	}
+	if input.MaxSeats != nil {
+		if *input.MaxSeats < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "席位数不能为负数",
+			})
+		}
+		license.MaxSeats = *input.MaxSeats
+	}
+	if input.MaxConcurrent != nil {
+		if *input.MaxConcurrent < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "并发数不能为负数",
+			})
+		}
+		license.MaxConcurrent = *input.MaxConcurrent
+	}
+	if input.AllowedAccounts != nil {
+		license.AllowedAccounts = *input.AllowedAccounts
+	}
+	if input.MaxAccounts != nil {
+		license.MaxAccounts = *input.MaxAccounts
+	}
+	if input.AllowedBrokers != nil {
+		license.AllowedBrokers = *input.AllowedBrokers
+	}
+	if input.AccountType != nil {
+		license.AccountType = *input.AccountType
+	}
+	if err := service.ValidateBindingRules(&license); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	// 保存更新
-	result = database.DB.Save(&license)
-	if result.Error != nil {
+	err := service.SaveLicense(&license, status)
+	if errors.Is(err, service.ErrStatusConflict) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "许可证状态已被修改，请刷新后重试",
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "更新许可证失败",
 		})
@@ -276,6 +800,15 @@ func HandleLicenseUpdate(c *fiber.Ctx) error {
 	})
 }
 
+// statusChangeError 不符合状态机规则的状态变更，返回当前状态和允许的变更
+func statusChangeError(c *fiber.Ctx, license *model.License, to model.LicenseStatus) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":       "不允许将许可证从" + license.Status.Label("zh") + "变更为" + to.Label("zh"),
+		"status":      license.Status,
+		"transitions": license.Status.Transitions(),
+	})
+}
+
 // HandleLicenseDelete 删除许可证
 func HandleLicenseDelete(c *fiber.Ctx) error {
 	key := c.Params("key")
@@ -286,7 +819,7 @@ func HandleLicenseDelete(c *fiber.Ctx) error {
 	}
 
 	var license model.License
-	result := database.DB.Where("key = ?", key).First(&license)
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "许可证不存在",
@@ -315,7 +848,7 @@ func HandleGetLicense(c *fiber.Ctx) error {
 	}
 
 	var license model.License
-	result := database.DB.Where("key = ?", key).First(&license)
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "许可证不存在",
@@ -325,8 +858,62 @@ func HandleGetLicense(c *fiber.Ctx) error {
 	return c.JSON(license)
 }
 
-// 生成唯一的许可证密钥
-func generateLicenseKey() string {
-	// 这里应该实现一个更复杂的密钥生成算法
-	return time.Now().Format("20060102150405")
+// HandleLicenseStatuses 获取许可证状态及其展示文本和允许的变更
+func HandleLicenseStatuses(c *fiber.Ctx) error {
+	lang := c.Query("lang", "zh")
+
+	statuses := make([]fiber.Map, 0, len(model.LicenseStatuses))
+	for _, status := range model.LicenseStatuses {
+		statuses = append(statuses, fiber.Map{
+			"value":       status,
+			"label":       status.Label(lang),
+			"transitions": status.Transitions(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"statuses": statuses,
+	})
+}
+
+// isLicenseSpecError 判断是否为生成许可证时的输入错误
+func isLicenseSpecError(err error) bool {
+	return errors.Is(err, service.ErrProductNotFound) ||
+		errors.Is(err, service.ErrInvalidValidUntil) ||
+		errors.Is(err, service.ErrInvalidLicenseLimits) ||
+		errors.Is(err, service.ErrInvalidKeyPrefix) ||
+		errors.Is(err, service.ErrInvalidVersionRule) ||
+		errors.Is(err, service.ErrInvalidBindingRule) ||
+		errors.Is(err, service.ErrInvalidBatchSize) ||
+		isEntitlementError(err)
+}
+
+// isEntitlementError 判断是否为权益超出产品范围的错误
+func isEntitlementError(err error) bool {
+	var entErr *model.EntitlementError
+	return errors.As(err, &entErr)
+}
+
+// clientProductMatches 通过客户端 API 密钥访问时，许可证必须属于密钥所属的产品
+func clientProductMatches(c *fiber.Ctx, license *model.License) bool {
+	product, ok := c.Locals("clientProduct").(string)
+	return !ok || license.ProductId == product
+}
+
+// canAccessLicense 判断当前用户是否为管理员或许可证持有人
+func canAccessLicense(c *fiber.Ctx, license *model.License) bool {
+	// 客户端 API 密钥认证的请求没有用户
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return false
+	}
+	if license.IssuedTo == userID {
+		return true
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return false
+	}
+	return user.Role == "admin"
 }
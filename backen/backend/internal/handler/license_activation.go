@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleLicenseDeactivate 停用设备并释放席位。
+// 客户端可携带设备指纹自行停用；更换服务器时，持有人或管理员可按激活记录ID停用旧设备。
+func HandleLicenseDeactivate(c *fiber.Ctx) error {
+	key := c.Query("key")
+	if key == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "许可证密钥不能为空",
+		})
+	}
+
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	if result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "许可证不存在",
+		})
+	}
+	if !clientProductMatches(c, &license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API 密钥与产品不匹配",
+		})
+	}
+
+	var err error
+	if activationID := c.QueryInt("activation_id"); activationID > 0 {
+		if !canAccessLicense(c, &license) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "无权停用该设备",
+			})
+		}
+		err = service.DeactivateSeatByID(key, uint(activationID))
+	} else {
+		fp := new(model.Fingerprint)
+		if err := c.QueryParser(fp); err != nil || !fp.IsComplete() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "设备指纹不能为空",
+			})
+		}
+		err = service.DeactivateSeat(key, *fp)
+	}
+
+	if errors.Is(err, service.ErrActivationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "设备未激活",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "停用设备失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "设备已停用",
+	})
+}
+
+// HandleGetLicenseActivations 获取许可证已激活的设备列表
+func HandleGetLicenseActivations(c *fiber.Ctx) error {
+	key := c.Params("key")
+
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	if result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "许可证不存在",
+		})
+	}
+
+	if !canAccessLicense(c, &license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "无权查看该许可证",
+		})
+	}
+
+	activations, err := service.GetActivations(key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取激活设备失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"activations": activations,
+		"max_seats":   license.MaxSeats,
+	})
+}
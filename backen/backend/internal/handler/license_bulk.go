@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BulkGenerateInput 批量生成许可证的参数，其余字段与单个生成相同
+type BulkGenerateInput struct {
+	LicenseInput
+	Count int `json:"count"`
+}
+
+// HandleLicenseBulkGenerate 按模板批量生成许可证，在一个事务中保存。
+// 默认返回 CSV 文件，format=json 时返回 JSON 数组
+func HandleLicenseBulkGenerate(c *fiber.Ctx) error {
+	input := new(BulkGenerateInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+
+	licenses, err := service.GenerateLicenses(input.spec(), input.Count)
+	if isLicenseSpecError(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "批量生成许可证失败",
+		})
+	}
+
+	if c.Query("format") == "json" {
+		return c.Status(fiber.StatusCreated).JSON(licenses)
+	}
+	c.Status(fiber.StatusCreated)
+	return sendLicenseCSV(c, "licenses-"+input.ProductId, licenses)
+}
+
+// HandleLicenseImport 导入旧系统的许可证，保留原有密钥。
+// 支持 JSON 数组、CSV 请求体或 multipart 上传的 file 文件（.csv 或 .json），
+// 任一记录校验失败时不导入任何数据并返回全部错误
+func HandleLicenseImport(c *fiber.Ctx) error {
+	data, isCSV, err := readImportBody(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var records []service.LicenseImport
+	var rowErrs []service.ImportError
+	if isCSV {
+		records, rowErrs, err = service.ParseLicenseCSV(bytes.NewReader(data))
+	} else {
+		err = json.Unmarshal(data, &records)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无法解析导入数据: " + err.Error(),
+		})
+	}
+	if len(rowErrs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "导入数据格式错误",
+			"errors": rowErrs,
+		})
+	}
+
+	imported, rowErrs, err := service.ImportLicenses(records)
+	if errors.Is(err, service.ErrInvalidImportSize) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "导入许可证失败",
+		})
+	}
+	if len(rowErrs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "导入数据校验失败，未导入任何许可证",
+			"errors": rowErrs,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "许可证导入成功",
+		"imported": imported,
+	})
+}
+
+// HandleLicenseExport 按条件导出许可证为 CSV，过滤参数与许可证列表相同，导出的文件可以直接用于导入。
+// 单次最多导出 MaxImportSize 条，超过时需要缩小过滤范围分批导出
+func HandleLicenseExport(c *fiber.Ctx) error {
+	query := new(LicenseListQuery)
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的查询参数",
+		})
+	}
+	filter, err := query.filter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	total, err := service.CountLicenses(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "导出许可证失败",
+		})
+	}
+	if total > service.MaxImportSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("单次最多导出 %d 个许可证，请缩小过滤范围", service.MaxImportSize),
+			"total": total,
+		})
+	}
+
+	filter.Limit = service.MaxImportSize
+	licenses, err := service.ListLicenses(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "导出许可证失败",
+		})
+	}
+
+	return sendLicenseCSV(c, "licenses", licenses)
+}
+
+// sendLicenseCSV 以附件形式返回许可证 CSV
+func sendLicenseCSV(c *fiber.Ctx, name string, licenses []model.License) error {
+	var buf bytes.Buffer
+	if err := service.WriteLicenseCSV(&buf, licenses); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "生成 CSV 失败",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Attachment(name + "-" + time.Now().Format("20060102150405") + ".csv")
+	return c.Send(buf.Bytes())
+}
+
+// readImportBody 读取导入数据，返回内容以及是否为 CSV
+func readImportBody(c *fiber.Ctx) ([]byte, bool, error) {
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+
+	if strings.HasPrefix(contentType, fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, false, errors.New("请上传 file 文件")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, false, err
+		}
+		return data, !strings.EqualFold(filepath.Ext(header.Filename), ".json"), nil
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		return c.Body(), false, nil
+	case strings.HasPrefix(contentType, "text/csv"):
+		return c.Body(), true, nil
+	}
+	return nil, false, errors.New("仅支持 application/json、text/csv 或 multipart/form-data")
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBulkTest(t *testing.T) *fiber.App {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/bulk", HandleLicenseBulkGenerate)
+	app.Post("/api/v1/licenses/import", HandleLicenseImport)
+	app.Get("/api/v1/licenses/export", HandleLicenseExport)
+	database.InitTestDB()
+	t.Cleanup(database.CleanTestDB)
+
+	database.DB.Create(&model.Product{
+		Code:              "gold",
+		Name:              "Gold Scalper",
+		EntitlementSchema: model.EntitlementSchema{Features: []string{"full", "news_filter"}},
+	})
+	return app
+}
+
+func readCSV(t *testing.T, resp *http.Response) [][]string {
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	assert.NoError(t, err)
+	return records
+}
+
+func TestHandleLicenseBulkGenerate(t *testing.T) {
+	app := setupBulkTest(t)
+
+	tests := []struct {
+		name       string
+		input      fiber.Map
+		wantStatus int
+		wantRows   int
+	}{
+		{name: "valid_batch", input: fiber.Map{"productid": "gold", "count": 25, "entitlements": fiber.Map{"features": []string{"full"}}}, wantStatus: fiber.StatusCreated, wantRows: 25},
+		{name: "zero_count", input: fiber.Map{"productid": "gold", "count": 0}, wantStatus: fiber.StatusBadRequest},
+		{name: "too_many", input: fiber.Map{"productid": "gold", "count": 1001}, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown_feature", input: fiber.Map{"productid": "gold", "count": 2, "entitlements": fiber.Map{"features": []string{"vip"}}}, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown_product", input: fiber.Map{"productid": "silver", "count": 2}, wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.input)
+			req, _ := http.NewRequest("POST", "/api/v1/licenses/bulk", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != fiber.StatusCreated {
+				return
+			}
+			assert.Contains(t, resp.Header.Get("Content-Type"), "text/csv")
+			assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+			records := readCSV(t, resp)
+			assert.Len(t, records, tt.wantRows+1)
+			assert.Equal(t, "full", records[1][8])
+		})
+	}
+
+	var count int64
+	database.DB.Model(&model.License{}).Count(&count)
+	assert.Equal(t, int64(25), count)
+}
+
+func TestHandleLicenseImport(t *testing.T) {
+	app := setupBulkTest(t)
+	database.DB.Create(&model.License{Key: "OLD-EXISTING", ProductId: "gold", Status: model.LicenseActive})
+
+	send := func(contentType string, body io.Reader) (*http.Response, fiber.Map) {
+		req, _ := http.NewRequest("POST", "/api/v1/licenses/import", body)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var result fiber.Map
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	// 任一记录无效时全部不导入
+	invalid := `[
+		{"key": "OLD-1", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z"},
+		{"key": "OLD-1", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z"},
+		{"key": "OLD-2", "productid": "silver", "valid_until": "2030-01-01T00:00:00Z"},
+		{"key": "OLD-3", "productid": "gold"},
+		{"key": "OLD-EXISTING", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z"},
+		{"key": "OLD-4", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z", "permissions": "vip"}
+	]`
+	resp, result := send("application/json", strings.NewReader(invalid))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	assert.Len(t, result["errors"], 5)
+	var count int64
+	database.DB.Model(&model.License{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	valid := `[
+		{"key": "OLD-1", "productid": "gold", "userid": "8001", "status": "active", "valid_until": "2030-01-01T00:00:00Z", "permissions": "full, news_filter"},
+		{"key": "OLD-2", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z"}
+	]`
+	resp, result = send("application/json", strings.NewReader(valid))
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, float64(2), result["imported"])
+
+	var imported model.License
+	database.DB.Where("user_id = ?", "8001").First(&imported)
+	assert.Equal(t, "OLD-1", imported.Key)
+	assert.Equal(t, model.LicenseActive, imported.Status)
+	assert.Equal(t, []string{"full", "news_filter"}, imported.Entitlements.Features)
+
+	// CSV 请求体，列名兼容 product_id 写法，未知的列被忽略
+	csvBody := "key,product_id,valid_until,max_seats,legacy_note\nCSV-1,gold,2030-06-30,3,x\nCSV-2,gold,2030-06-30 12:00:00,,y\n"
+	resp, result = send("text/csv", strings.NewReader(csvBody))
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode, result)
+
+	resp, result = send("text/csv", strings.NewReader("key,valid_until\nCSV-3,not-a-date\n"))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	assert.Len(t, result["errors"], 1)
+
+	resp, _ = send("text/csv", strings.NewReader("productid\ngold\n"))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// multipart 上传
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", "legacy.json")
+	part.Write([]byte(`[{"key": "FILE-1", "productid": "gold", "valid_until": "2030-01-01T00:00:00Z"}]`))
+	writer.Close()
+	resp, _ = send(writer.FormDataContentType(), &buf)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	resp, _ = send("text/plain", strings.NewReader("OLD-9"))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHandleLicenseExport(t *testing.T) {
+	app := setupBulkTest(t)
+	database.DB.Create(&[]model.License{
+		{Key: "EXP-1", ProductId: "gold", Status: model.LicenseActive, UserId: "8001"},
+		{Key: "EXP-2", ProductId: "gold", Status: model.LicenseRevoked, UserId: "8002"},
+		{Key: "EXP-3", ProductId: "silver", Status: model.LicenseActive, UserId: "8003"},
+	})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []string
+	}{
+		{name: "all", query: "", wantStatus: fiber.StatusOK, wantKeys: []string{"EXP-1", "EXP-2", "EXP-3"}},
+		{name: "by_status", query: "?status=active", wantStatus: fiber.StatusOK, wantKeys: []string{"EXP-1", "EXP-3"}},
+		{name: "by_product_status", query: "?productid=gold&status=active", wantStatus: fiber.StatusOK, wantKeys: []string{"EXP-1"}},
+		{name: "search", query: "?keyword=8002", wantStatus: fiber.StatusOK, wantKeys: []string{"EXP-2"}},
+		{name: "invalid_status", query: "?status=deleted", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/v1/licenses/export"+tt.query, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+			records := readCSV(t, resp)
+			var keys []string
+			for _, record := range records[1:] {
+				keys = append(keys, record[0])
+			}
+			assert.ElementsMatch(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func TestHandleLicenseExportRoundTrip(t *testing.T) {
+	app := setupBulkTest(t)
+	key, _ := keygen.Generate("")
+	original := model.License{
+		Key:               key,
+		ProductId:         "gold",
+		Status:            model.LicenseActive,
+		UserId:            "8001",
+		ValidUntil:        time.Now().AddDate(1, 0, 0).Truncate(time.Second),
+		Version:           "1.2.0",
+		VersionConstraint: ">=1.0.0 <2.0.0",
+		MaxSeats:          2,
+		Entitlements:      licensing.Entitlements{Features: []string{"full"}, MaxLotSize: 0.5, Symbols: []string{"EURUSD"}},
+		AllowedAccounts:   []string{"10086", "20001"},
+		MaxAccounts:       3,
+		AllowedBrokers:    []string{"ICMarkets*"},
+		AccountType:       model.AccountTypeDemo,
+	}
+	database.DB.Create(&original)
+
+	req, _ := http.NewRequest("GET", "/api/v1/licenses/export?productid=gold", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	exported, _ := io.ReadAll(resp.Body)
+
+	// 删除后重新导入，设置与导出前一致
+	database.DB.Unscoped().Where("id = ?", original.ID).Delete(&model.License{})
+	req, _ = http.NewRequest("POST", "/api/v1/licenses/import", bytes.NewReader(exported))
+	req.Header.Set(fiber.HeaderContentType, "text/csv")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var imported model.License
+	assert.NoError(t, database.DB.Scopes(database.ByLicenseKey(key)).First(&imported).Error)
+	assert.Equal(t, original.VersionConstraint, imported.VersionConstraint)
+	assert.Equal(t, original.Entitlements, imported.Entitlements)
+	assert.Equal(t, original.AllowedAccounts, imported.AllowedAccounts)
+	assert.Equal(t, original.MaxAccounts, imported.MaxAccounts)
+	assert.Equal(t, original.AllowedBrokers, imported.AllowedBrokers)
+	assert.Equal(t, original.AccountType, imported.AccountType)
+	assert.True(t, original.ValidUntil.Equal(imported.ValidUntil))
+}
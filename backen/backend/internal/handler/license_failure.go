@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LicenseFailureQuery 失败记录的查询参数，时间参数为 YYYY-MM-DD 或 RFC3339 格式
+type LicenseFailureQuery struct {
+	Page      int    `query:"page"`
+	PageSize  int    `query:"page_size"`
+	Key       string `query:"key"`
+	IP        string `query:"ip"`
+	ProductId string `query:"productid"`
+	Action    string `query:"action"`
+	Reason    string `query:"reason"`
+	Since     string `query:"since"`
+	Until     string `query:"until"`
+	GroupBy   string `query:"group_by"`
+}
+
+// HandleLicenseFailures 管理员查询失败的校验和激活记录，可按许可证、IP 和产品过滤。
+// 指定 group_by 时返回按 key、ip、product 或 reason 分组的失败次数，用于发现异常来源
+func HandleLicenseFailures(c *fiber.Ctx) error {
+	query := new(LicenseFailureQuery)
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的查询参数",
+		})
+	}
+	if query.Action != "" && query.Action != "verify" && query.Action != "activate" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action 只能为 verify 或 activate",
+		})
+	}
+
+	filter := service.UsageFilter{
+		LicenseKey: query.Key,
+		IPAddress:  query.IP,
+		ProductId:  query.ProductId,
+		Action:     query.Action,
+		Reason:     query.Reason,
+		FailedOnly: true,
+	}
+	dates := []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"since", query.Since, &filter.Since},
+		{"until", query.Until, &filter.Until},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseQueryDate(d.value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": d.name + " 日期格式应为 YYYY-MM-DD 或 RFC3339",
+			})
+		}
+		*d.dst = t
+	}
+
+	// 设置默认值
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 10
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+	filter.Offset = (query.Page - 1) * query.PageSize
+	filter.Limit = query.PageSize
+
+	if query.GroupBy != "" {
+		groups, err := service.GroupUsages(filter, query.GroupBy)
+		if errors.Is(err, service.ErrInvalidGroupBy) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "group_by 只能为 key、ip、product 或 reason",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "统计失败记录失败",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"group_by": query.GroupBy,
+			"groups":   groups,
+		})
+	}
+
+	failures, err := service.ListUsages(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "查询失败记录失败",
+		})
+	}
+	total, err := service.CountUsages(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "查询失败记录失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"failures": failures,
+		"total":    total,
+		"page":     query.Page,
+		"size":     query.PageSize,
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLicenseFailures(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	app.Get("/api/v1/licenses/failures", HandleLicenseFailures)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	active, _ := keygen.Generate("")
+	revoked, _ := keygen.Generate("")
+	unknown, _ := keygen.Generate("")
+	database.DB.Create(&[]model.License{
+		{Key: active, Status: model.LicenseActive, ProductId: "gold", UserId: "10086", ValidUntil: time.Now().AddDate(0, 0, 30), MaxSeats: 1},
+		{Key: revoked, Status: model.LicenseRevoked, ProductId: "silver", UserId: "10086", ValidUntil: time.Now().AddDate(0, 0, 30)},
+	})
+
+	// 每次校验和激活都记录结果
+	attempts := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantReason service.VerifyReason
+	}{
+		{name: "verify_unknown", method: "GET", path: "/api/v1/licenses/verify?key=" + unknown + "&userid=10086&productid=gold", wantStatus: fiber.StatusNotFound, wantReason: service.ReasonNotFound},
+		{name: "verify_mismatch", method: "GET", path: "/api/v1/licenses/verify?key=" + active + "&userid=10010&productid=gold&terminal_id=T-A&account=10086", wantStatus: fiber.StatusBadRequest, wantReason: service.ReasonMismatch},
+		{name: "verify_revoked", method: "GET", path: "/api/v1/licenses/verify?key=" + revoked + "&userid=10086&productid=silver", wantStatus: fiber.StatusOK, wantReason: service.ReasonRevoked},
+		{name: "activate_ok", method: "POST", path: "/api/v1/licenses/activate?key=" + active + "&terminal_id=T-A&account=10086", wantStatus: fiber.StatusOK, wantReason: service.ReasonOK},
+		{name: "activate_seat_limit", method: "POST", path: "/api/v1/licenses/activate?key=" + active + "&terminal_id=T-B&account=10086", wantStatus: fiber.StatusConflict, wantReason: service.ReasonSeatLimit},
+		{name: "activate_revoked", method: "POST", path: "/api/v1/licenses/activate?key=" + revoked + "&terminal_id=T-A&account=10086", wantStatus: fiber.StatusBadRequest, wantReason: service.ReasonRevoked},
+		{name: "activate_unknown", method: "POST", path: "/api/v1/licenses/activate?key=" + unknown + "&terminal_id=T-A&account=10086", wantStatus: fiber.StatusNotFound, wantReason: service.ReasonNotFound},
+	}
+	for _, tt := range attempts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var usage model.LicenseUsage
+			assert.NoError(t, database.DB.Order("id DESC").First(&usage).Error)
+			assert.Equal(t, string(tt.wantReason), usage.Reason)
+		})
+	}
+
+	var unknownUsage model.LicenseUsage
+	database.DB.Where("license_key = ? AND action = ?", unknown, "verify").First(&unknownUsage)
+	assert.Equal(t, "gold", unknownUsage.ProductId)
+
+	// 其他来源的失败记录
+	database.DB.Create(&model.LicenseUsage{LicenseKey: unknown, Action: "verify", Reason: "not_found", IPAddress: "203.0.113.9", Timestamp: time.Now()})
+
+	get := func(query string) (int, fiber.Map) {
+		req, _ := http.NewRequest("GET", "/api/v1/licenses/failures"+query, nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var body fiber.Map
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTotal  float64
+	}{
+		{name: "all", query: "", wantStatus: fiber.StatusOK, wantTotal: 7},
+		{name: "by_key", query: "?key=" + unknown, wantStatus: fiber.StatusOK, wantTotal: 3},
+		{name: "by_ip", query: "?ip=203.0.113.9", wantStatus: fiber.StatusOK, wantTotal: 1},
+		{name: "by_product", query: "?productid=silver", wantStatus: fiber.StatusOK, wantTotal: 2},
+		{name: "by_action_reason", query: "?action=activate&reason=seat_limit", wantStatus: fiber.StatusOK, wantTotal: 1},
+		{name: "since_future", query: "?since=" + time.Now().AddDate(0, 0, 1).Format("2006-01-02"), wantStatus: fiber.StatusOK, wantTotal: 0},
+		{name: "invalid_action", query: "?action=login", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_date", query: "?until=yesterday", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_group", query: "?group_by=country", wantStatus: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(tt.query)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantStatus == fiber.StatusOK {
+				assert.Equal(t, tt.wantTotal, body["total"])
+			}
+		})
+	}
+
+	status, body := get("?group_by=key")
+	assert.Equal(t, fiber.StatusOK, status)
+	groups := body["groups"].([]interface{})
+	if assert.Len(t, groups, 3) {
+		assert.Equal(t, fiber.Map{"value": unknown, "count": float64(3)}, fiber.Map(groups[0].(map[string]interface{})))
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleLicenseFile 签发离线许可证文件
+func HandleLicenseFile(c *fiber.Ctx) error {
+	key := c.Params("key")
+	if key == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "许可证密钥不能为空",
+		})
+	}
+
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	if result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "许可证不存在",
+		})
+	}
+
+	// 只有管理员或许可证持有人可以下载许可证文件
+	if !canAccessLicense(c, &license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "无权下载该许可证文件",
+		})
+	}
+
+	if (license.Status != model.LicenseActive && license.Status != model.LicenseInactive) ||
+		!time.Now().Before(license.ValidUntil) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "许可证已失效，无法签发许可证文件",
+		})
+	}
+
+	entitlements, err := service.ResolveEntitlements(&license)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取许可证权益失败",
+		})
+	}
+
+	file, err := licensing.Sign(util.SigningKey(), licensing.Document{
+		Key:          license.Key,
+		UserId:       license.UserId,
+		ProductId:    license.ProductId,
+		Entitlements: entitlements,
+		ValidUntil:   license.ValidUntil,
+		IssuedAt:     time.Now(),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "许可证文件签发失败",
+		})
+	}
+
+	c.Attachment(license.Key + ".lic")
+	return c.JSON(file)
+}
+
+// HandleLicensePublicKey 获取用于离线校验的公钥
+func HandleLicensePublicKey(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"algorithm":  licensing.Algorithm,
+		"public_key": licensing.EncodePublicKey(util.PublicKey()),
+	})
+}
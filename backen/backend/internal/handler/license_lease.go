@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleLeaseCheckout 签出浮动许可证租约
+func HandleLeaseCheckout(c *fiber.Ctx) error {
+	key := c.Query("key")
+	if key == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "许可证密钥不能为空",
+		})
+	}
+
+	clientID := c.Query("client_id")
+	if clientID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "客户端标识不能为空",
+		})
+	}
+
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	if result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "许可证不存在",
+		})
+	}
+	if !clientProductMatches(c, &license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API 密钥与产品不匹配",
+		})
+	}
+
+	if !service.IsLicenseUsable(&license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "许可证已失效",
+		})
+	}
+
+	ttl := time.Duration(c.QueryInt("ttl")) * time.Second
+	lease, err := service.CheckoutLease(&license, clientID, clientInfo(c), ttl)
+	if errors.Is(err, service.ErrLeaseLimitReached) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":          "并发实例数已达上限",
+			"max_concurrent": license.MaxConcurrent,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "签出租约失败",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(leaseResponse(lease))
+}
+
+// HandleLeaseHeartbeat 租约心跳续期
+func HandleLeaseHeartbeat(c *fiber.Ctx) error {
+	lease, err := service.GetLease(c.Params("id"))
+	if errors.Is(err, service.ErrLeaseNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "租约不存在或已过期，请重新签出",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取租约失败",
+		})
+	}
+
+	// 许可证在租约期间被吊销或过期时不再续期
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(lease.LicenseKey)).First(&license)
+	if result.Error != nil || !service.IsLicenseUsable(&license) {
+		service.ReleaseLease(lease.LeaseID)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "许可证已失效",
+		})
+	}
+
+	ttl := time.Duration(c.QueryInt("ttl")) * time.Second
+	if err := service.RenewLease(lease, ttl); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "租约续期失败",
+		})
+	}
+
+	return c.JSON(leaseResponse(lease))
+}
+
+// HandleLeaseRelease 客户端退出时释放租约
+func HandleLeaseRelease(c *fiber.Ctx) error {
+	err := service.ReleaseLease(c.Params("id"))
+	if errors.Is(err, service.ErrLeaseNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "租约不存在或已过期",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "释放租约失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "租约已释放",
+	})
+}
+
+// HandleGetLicenseLeases 获取许可证当前有效的租约
+func HandleGetLicenseLeases(c *fiber.Ctx) error {
+	key := c.Params("key")
+
+	var license model.License
+	result := database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	if result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "许可证不存在",
+		})
+	}
+
+	if !canAccessLicense(c, &license) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "无权查看该许可证",
+		})
+	}
+
+	leases, err := service.GetActiveLeases(key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取租约失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"leases":         leases,
+		"max_concurrent": license.MaxConcurrent,
+	})
+}
+
+func leaseResponse(lease *model.LicenseLease) fiber.Map {
+	return fiber.Map{
+		"lease_id":   lease.LeaseID,
+		"expires_at": lease.ExpiresAt,
+		"ttl":        int(time.Until(lease.ExpiresAt).Seconds()),
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLeaseLifecycle(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/leases/checkout", HandleLeaseCheckout)
+	app.Post("/api/v1/licenses/leases/:id/heartbeat", HandleLeaseHeartbeat)
+	app.Delete("/api/v1/licenses/leases/:id", HandleLeaseRelease)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:           key,
+		Status:        model.LicenseActive,
+		ValidUntil:    time.Now().AddDate(0, 0, 30),
+		MaxConcurrent: 1,
+	})
+
+	checkout := func(clientID string) (*http.Response, string) {
+		req, _ := http.NewRequest("POST", "/api/v1/licenses/leases/checkout?key="+key+"&client_id="+clientID+"&platform=win64", nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body.LeaseID
+	}
+
+	resp, leaseA := checkout("terminal-a")
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, leaseA)
+
+	// 同一客户端再次签出复用原租约
+	resp, again := checkout("terminal-a")
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, leaseA, again)
+
+	resp, _ = checkout("terminal-b")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	// 会话时长由心跳计算
+	startedAt := time.Now().Add(-10 * time.Minute)
+	database.DB.Model(&model.UsageSession{}).Where("lease_id = ?", leaseA).Update("started_at", startedAt)
+	req, _ := http.NewRequest("POST", "/api/v1/licenses/leases/"+leaseA+"/heartbeat?ttl=60", nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var session model.UsageSession
+	database.DB.Where("lease_id = ?", leaseA).First(&session)
+	assert.Equal(t, "terminal-a", session.ClientID)
+	assert.Equal(t, "windows", session.Platform)
+	assert.Equal(t, 2, session.Heartbeats)
+	assert.InDelta(t, 600, session.DurationSeconds, 2)
+	assert.True(t, session.EndedAt.IsZero())
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/licenses/leases/"+leaseA, nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	database.DB.Where("lease_id = ?", leaseA).First(&session)
+	assert.False(t, session.EndedAt.IsZero())
+
+	resp, leaseB := checkout("terminal-b")
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// 过期租约被回收后释放并发数，会话在最后一次心跳时结束
+	database.DB.Model(&model.LicenseLease{}).Where("license_key = ?", key).Update("expires_at", time.Now().Add(-time.Second))
+	resp, leaseC := checkout("terminal-c")
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var expired model.UsageSession
+	assert.NoError(t, database.DB.Where("lease_id = ?", leaseB).First(&expired).Error)
+	assert.False(t, expired.EndedAt.IsZero())
+	assert.True(t, expired.EndedAt.Equal(expired.LastSeenAt))
+
+	// 定时任务回收过期租约时同样结束会话
+	database.DB.Model(&model.LicenseLease{}).Where("lease_id = ?", leaseC).Update("expires_at", time.Now().Add(-time.Second))
+	reclaimed, err := service.ReclaimExpiredLeases()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), reclaimed)
+	var reclaimedSession model.UsageSession
+	assert.NoError(t, database.DB.Where("lease_id = ?", leaseC).First(&reclaimedSession).Error)
+	assert.False(t, reclaimedSession.EndedAt.IsZero())
+}
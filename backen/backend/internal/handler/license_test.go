@@ -3,18 +3,25 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
 	"license-management-system/internal/model"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestHandleLicenseGenerate(t *testing.T) {
 	app := fiber.New()
+	app.Post("/api/v1/licenses/generate", HandleLicenseGenerate)
 	database.InitTestDB()
 	defer database.CleanTestDB()
 
@@ -24,6 +31,16 @@ func TestHandleLicenseGenerate(t *testing.T) {
 		Role:     "admin",
 	}
 	database.DB.Create(adminUser)
+	database.DB.Create(&model.Product{
+		Code:            "gold",
+		Name:            "Gold Scalper",
+		DefaultMaxSeats: 2,
+		EntitlementSchema: model.EntitlementSchema{
+			Features:   []string{"full", "news_filter"},
+			MaxLotSize: 5,
+			Timeframes: []string{"M5", "H1"},
+		},
+	})
 
 	tests := []struct {
 		name       string
@@ -34,9 +51,10 @@ func TestHandleLicenseGenerate(t *testing.T) {
 		{
 			name: "valid_license",
 			input: LicenseInput{
-				Version:     "1.0",
-				ValidUntil:  time.Now().AddDate(0, 0, 30),
-				Permissions: "full",
+				Version:      "1.0",
+				ValidUntil:   time.Now().AddDate(0, 0, 30),
+				Entitlements: licensing.Entitlements{Features: []string{"full"}, MaxLotSize: 2},
+				ProductId:    "gold",
 			},
 			wantStatus: fiber.StatusCreated,
 			wantError:  false,
@@ -44,9 +62,45 @@ func TestHandleLicenseGenerate(t *testing.T) {
 		{
 			name: "invalid_days",
 			input: LicenseInput{
-				Version:     "1.0",
-				ValidUntil:  time.Now().AddDate(0, 0, -1), // 无效的日期用于测试错误情况
-				Permissions: "full",
+				Version:    "1.0",
+				ValidUntil: time.Now().AddDate(0, 0, -1), // 无效的日期用于测试错误情况
+				ProductId:  "gold",
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantError:  true,
+		},
+		{
+			name: "unknown_product",
+			input: LicenseInput{
+				Version:   "1.0",
+				ProductId: "silver",
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantError:  true,
+		},
+		{
+			name: "unknown_feature",
+			input: LicenseInput{
+				Entitlements: licensing.Entitlements{Features: []string{"grid"}},
+				ProductId:    "gold",
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantError:  true,
+		},
+		{
+			name: "lot_size_exceeds_schema",
+			input: LicenseInput{
+				Entitlements: licensing.Entitlements{MaxLotSize: 10},
+				ProductId:    "gold",
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantError:  true,
+		},
+		{
+			name: "timeframe_not_allowed",
+			input: LicenseInput{
+				Entitlements: licensing.Entitlements{Timeframes: []string{"D1"}},
+				ProductId:    "gold",
 			},
 			wantStatus: fiber.StatusBadRequest,
 			wantError:  true,
@@ -65,3 +119,642 @@ func TestHandleLicenseGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleLicenseVerifyChecksum(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, err := keygen.Generate("")
+	assert.NoError(t, err)
+
+	// 篡改最后一位校验位
+	runes := []rune(key)
+	if runes[len(runes)-1] == 'Z' {
+		runes[len(runes)-1] = 'Y'
+	} else {
+		runes[len(runes)-1] = 'Z'
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{name: "bad_checksum", key: string(runes), wantStatus: fiber.StatusBadRequest},
+		{name: "unknown_key", key: key, wantStatus: fiber.StatusNotFound},
+		{name: "legacy_key", key: "20250401000024", wantStatus: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/v1/licenses/verify?key="+tt.key+"&userid=1&productid=p", nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestHandleLicenseActivateSeats(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	app.Post("/api/v1/licenses/deactivate", HandleLicenseDeactivate)
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:        key,
+		Status:     model.LicenseActive,
+		ValidUntil: time.Now().AddDate(0, 0, 30),
+		UserId:     "10086",
+		ProductId:  "gold",
+		MaxSeats:   1,
+	})
+
+	vpsA := "&terminal_id=T-A&account=10086"
+	vpsB := "&terminal_id=T-B&account=10086"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "activate_first_device", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsA, wantStatus: fiber.StatusOK},
+		{name: "activate_same_device_again", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsA, wantStatus: fiber.StatusOK},
+		{name: "activate_over_seat_limit", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsB, wantStatus: fiber.StatusConflict},
+		{name: "activate_without_fingerprint", method: "POST", path: "/api/v1/licenses/activate?key=" + key, wantStatus: fiber.StatusBadRequest},
+		{name: "verify_registered_device", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + vpsA, wantStatus: fiber.StatusOK},
+		{name: "verify_unregistered_device", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + vpsB, wantStatus: fiber.StatusForbidden},
+		{name: "deactivate_first_device", method: "POST", path: "/api/v1/licenses/deactivate?key=" + key + vpsA, wantStatus: fiber.StatusOK},
+		{name: "activate_new_device", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsB, wantStatus: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestHandleLicenseUnlimitedSeats(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:        key,
+		Status:     model.LicenseActive,
+		ValidUntil: time.Now().AddDate(0, 0, 30),
+		UserId:     "10086",
+		ProductId:  "gold",
+	})
+
+	verify := "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold"
+	vpsA := "&terminal_id=T-A&account=10086"
+	vpsB := "&terminal_id=T-B&account=10086"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "verify_before_activation", method: "GET", path: verify, wantStatus: fiber.StatusOK},
+		{name: "activate_first_device", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsA, wantStatus: fiber.StatusOK},
+		{name: "verify_registered_device", method: "GET", path: verify + vpsA, wantStatus: fiber.StatusOK},
+		{name: "verify_unregistered_device", method: "GET", path: verify + vpsB, wantStatus: fiber.StatusForbidden},
+		{name: "verify_without_fingerprint", method: "GET", path: verify, wantStatus: fiber.StatusBadRequest},
+		{name: "activate_second_device", method: "POST", path: "/api/v1/licenses/activate?key=" + key + vpsB, wantStatus: fiber.StatusOK},
+		{name: "verify_second_device", method: "GET", path: verify + vpsB, wantStatus: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestHandleLicenseSignedVerdict(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	unknown, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:          key,
+		Status:       model.LicenseInactive,
+		ValidUntil:   time.Now().AddDate(0, 0, 30),
+		UserId:       "10086",
+		ProductId:    "gold",
+		MaxSeats:     1,
+		Entitlements: licensing.Entitlements{Features: []string{"full"}},
+	})
+	device := "&terminal_id=T-A&account=10086"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		nonce      string
+		wantStatus int
+		wantKey    string
+		wantValid  bool
+		wantReason string
+	}{
+		{name: "activate", method: "POST", path: "/api/v1/licenses/activate?key=" + key + device, nonce: "a1", wantStatus: fiber.StatusOK, wantKey: key, wantValid: true, wantReason: "ok"},
+		{name: "activate_without_fingerprint", method: "POST", path: "/api/v1/licenses/activate?key=" + key, nonce: "a2", wantStatus: fiber.StatusBadRequest, wantKey: key, wantReason: "fingerprint_required"},
+		{name: "verify", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + device, nonce: "v1", wantStatus: fiber.StatusOK, wantKey: key, wantValid: true, wantReason: "ok"},
+		{name: "verify_mismatch", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=silver" + device, nonce: "v2", wantStatus: fiber.StatusBadRequest, wantKey: key, wantReason: "mismatch"},
+		{name: "verify_unknown", method: "GET", path: "/api/v1/licenses/verify?key=" + unknown + "&userid=10086&productid=gold", nonce: "v3", wantStatus: fiber.StatusNotFound, wantKey: unknown, wantReason: "not_found"},
+		{name: "verify_without_nonce", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + device, wantStatus: fiber.StatusOK, wantKey: key, wantValid: true, wantReason: "ok"},
+		{name: "invalid_nonce", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + device, nonce: "a b", wantStatus: fiber.StatusBadRequest},
+		{name: "nonce_line_break", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=10086&productid=gold" + device, nonce: "v4\nvalid=1", wantStatus: fiber.StatusBadRequest},
+		{name: "key_line_break", method: "GET", path: "/api/v1/licenses/verify?key=" + url.QueryEscape(key+"\nvalid=1") + "&userid=10086&productid=gold" + device, nonce: "v5", wantStatus: fiber.StatusBadRequest},
+		{name: "userid_line_break", method: "GET", path: "/api/v1/licenses/verify?key=" + key + "&userid=" + url.QueryEscape("10086\r") + "&productid=gold" + device, nonce: "v6", wantStatus: fiber.StatusBadRequest},
+		{name: "activate_key_line_break", method: "POST", path: "/api/v1/licenses/activate?key=" + url.QueryEscape(key+"\r\n") + device, nonce: "a3", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if tt.nonce != "" {
+				path += "&nonce=" + url.QueryEscape(tt.nonce)
+			}
+			req, _ := http.NewRequest(tt.method, path, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var body struct {
+				Key           string          `json:"key"`
+				SignedVerdict *licensing.File `json:"signed_verdict"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if tt.wantKey == "" {
+				assert.Nil(t, body.SignedVerdict)
+				return
+			}
+
+			// 客户端用公钥校验签名，并确认结论属于本次请求
+			if assert.NotNil(t, body.SignedVerdict) {
+				if tt.nonce == "" {
+					// 不带随机数的结论可以被重放，客户端不能信任
+					_, err := licensing.VerifyVerdict(util.PublicKey(), *body.SignedVerdict, tt.nonce, tt.wantKey)
+					assert.Equal(t, licensing.ErrNonceRequired, err)
+					return
+				}
+				verdict, err := licensing.VerifyVerdict(util.PublicKey(), *body.SignedVerdict, tt.nonce, tt.wantKey)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantValid, verdict.Valid)
+				assert.Equal(t, tt.wantReason, verdict.Reason)
+				assert.WithinDuration(t, time.Now(), verdict.ServerTime, time.Minute)
+				if tt.wantValid {
+					assert.True(t, verdict.Entitlements.HasFeature("full"))
+				}
+
+				_, err = licensing.VerifyVerdict(util.PublicKey(), *body.SignedVerdict, "replayed", tt.wantKey)
+				assert.Equal(t, licensing.ErrVerdictMismatch, err)
+			}
+		})
+	}
+
+	// 激活成功的响应仍然包含许可证信息
+	req, _ := http.NewRequest("POST", "/api/v1/licenses/activate?key="+key+device, nil)
+	resp, _ := app.Test(req)
+	var activated map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&activated)
+	assert.Equal(t, key, activated["key"])
+	assert.Equal(t, string(model.LicenseActive), activated["status"])
+	assert.Contains(t, activated, "signed_verdict")
+}
+
+func TestHandleLicenseVerdictToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&[]model.Product{{Code: "gold", Name: "Gold Scalper", OfflineGraceHours: 72}, {Code: "silver", Name: "Silver Scalper"}})
+	gold, _ := keygen.Generate("")
+	silver, _ := keygen.Generate("")
+	ending, _ := keygen.Generate("")
+	revoked, _ := keygen.Generate("")
+	unbound, _ := keygen.Generate("")
+	database.DB.Create(&[]model.License{
+		{Key: gold, Status: model.LicenseActive, ValidUntil: time.Now().AddDate(0, 0, 30), UserId: "10086", ProductId: "gold"},
+		{Key: unbound, Status: model.LicenseActive, ValidUntil: time.Now().AddDate(0, 0, 30), UserId: "10086", ProductId: "gold"},
+		{Key: silver, Status: model.LicenseActive, ValidUntil: time.Now().AddDate(0, 0, 30), UserId: "10086", ProductId: "silver"},
+		{Key: ending, Status: model.LicenseActive, ValidUntil: time.Now().Add(10 * time.Hour), UserId: "10086", ProductId: "gold"},
+		{Key: revoked, Status: model.LicenseRevoked, ValidUntil: time.Now().AddDate(0, 0, 30), UserId: "10086", ProductId: "gold"},
+	})
+
+	tests := []struct {
+		name        string
+		key         string
+		productID   string
+		terminalID  string
+		wantToken   bool
+		wantExpires time.Duration
+	}{
+		{name: "product_grace", key: gold, productID: "gold", terminalID: "T-A", wantToken: true, wantExpires: 72 * time.Hour},
+		{name: "default_grace", key: silver, productID: "silver", terminalID: "T-A", wantToken: true, wantExpires: 24 * time.Hour},
+		{name: "license_expires_first", key: ending, productID: "gold", terminalID: "T-A", wantToken: true, wantExpires: 10 * time.Hour},
+		{name: "revoked", key: revoked, productID: "gold", terminalID: "T-A"},
+		{name: "without_terminal", key: unbound, productID: "gold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/v1/licenses/verify?key="+tt.key+"&userid=10086&productid="+tt.productID+"&terminal_id="+tt.terminalID, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+
+			var body struct {
+				VerdictToken *licensing.File `json:"verdict_token"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			var license model.License
+			database.DB.Scopes(database.ByLicenseKey(tt.key)).First(&license)
+			if !tt.wantToken {
+				assert.Nil(t, body.VerdictToken)
+				assert.True(t, license.TokenRenewedAt.IsZero())
+				return
+			}
+
+			// 客户端重启时无需联网，用公钥校验凭证并确认属于当前终端和账号
+			if assert.NotNil(t, body.VerdictToken) {
+				token, err := licensing.VerifyToken(util.PublicKey(), *body.VerdictToken, tt.key, "T-A", "10086", time.Now())
+				assert.NoError(t, err)
+				assert.WithinDuration(t, time.Now().Add(tt.wantExpires), token.ExpiresAt, time.Minute)
+				_, err = licensing.VerifyToken(util.PublicKey(), *body.VerdictToken, tt.key, "T-B", "10086", time.Now())
+				assert.Equal(t, licensing.ErrVerdictMismatch, err)
+			}
+			assert.WithinDuration(t, time.Now(), license.TokenRenewedAt, time.Minute)
+		})
+	}
+}
+
+func TestHandleLicenseVerifyVersion(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&model.Product{Code: "gold", Name: "Gold Scalper", CurrentVersion: "3.0.0"})
+
+	v2Key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:        v2Key,
+		Status:     model.LicenseActive,
+		ValidUntil: time.Now().AddDate(0, 0, 30),
+		UserId:     "10086",
+		ProductId:  "gold",
+		Version:    "2.1.0",
+	})
+	rangeKey, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:               rangeKey,
+		Status:            model.LicenseActive,
+		ValidUntil:        time.Now().AddDate(0, 0, 30),
+		UserId:            "10086",
+		ProductId:         "gold",
+		VersionConstraint: ">=2.1, <4",
+		MaxMajorVersion:   3,
+	})
+	database.DB.Create(&model.Product{Code: "silver", Name: "Silver Scalper", CurrentVersion: "3.0.0", MaxMajorVersion: 3})
+	productRuleKey, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:        productRuleKey,
+		Status:     model.LicenseActive,
+		ValidUntil: time.Now().AddDate(0, 0, 30),
+		UserId:     "10086",
+		ProductId:  "silver",
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		product    string
+		version    string
+		wantValid  bool
+		wantReason string
+	}{
+		{name: "no_version", key: v2Key, version: "", wantValid: true, wantReason: "ok"},
+		{name: "same_major", key: v2Key, version: "2.5.1", wantValid: true, wantReason: "ok"},
+		{name: "newer_major", key: v2Key, version: "3.0.0", wantValid: false, wantReason: "upgrade_required"},
+		{name: "invalid_version", key: v2Key, version: "build-abc", wantValid: false, wantReason: "invalid_version"},
+		{name: "within_range", key: rangeKey, version: "3.0.0", wantValid: true, wantReason: "ok"},
+		{name: "below_range", key: rangeKey, version: "2.0.9", wantValid: false, wantReason: "version_not_allowed"},
+		{name: "no_version_with_rule", key: rangeKey, version: "", wantValid: false, wantReason: "upgrade_required"},
+		{name: "no_version_with_product_rule", key: productRuleKey, product: "silver", version: "", wantValid: false, wantReason: "upgrade_required"},
+		{name: "product_rule", key: productRuleKey, product: "silver", version: "3.2.0", wantValid: true, wantReason: "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := tt.product
+			if product == "" {
+				product = "gold"
+			}
+			req, _ := http.NewRequest("GET", "/api/v1/licenses/verify?key="+tt.key+"&userid=10086&productid="+product+"&version="+tt.version, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			var body struct {
+				Valid         bool   `json:"valid"`
+				Reason        string `json:"reason"`
+				LatestVersion string `json:"latest_version"`
+			}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.wantValid, body.Valid)
+			assert.Equal(t, tt.wantReason, body.Reason)
+			assert.Equal(t, "3.0.0", body.LatestVersion)
+		})
+	}
+}
+
+func TestHandleLicenseVerifyBinding(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/verify", HandleLicenseVerify)
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:             key,
+		Status:          model.LicenseActive,
+		ValidUntil:      time.Now().AddDate(0, 0, 30),
+		UserId:          "10086",
+		ProductId:       "gold",
+		AllowedAccounts: []string{"10086"},
+		MaxAccounts:     2,
+		AllowedBrokers:  []string{"ICMarkets*", "*-Demo"},
+		AccountType:     model.AccountTypeDemo,
+	})
+
+	verify := "/api/v1/licenses/verify?key=" + key + "&productid=gold&terminal_id=T-A&account=10086"
+	activate := "/api/v1/licenses/activate?key=" + key + "&terminal_id=T-A&account=10086"
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantReason string
+	}{
+		{name: "listed_account", method: "GET", path: verify + "&userid=10086&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "ok"},
+		{name: "server_pattern", method: "GET", path: verify + "&userid=10086&broker=Other&server=Exness-Demo&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "ok"},
+		{name: "live_account", method: "GET", path: verify + "&userid=10086&broker=ICMarketsSC&account_type=real", wantStatus: fiber.StatusOK, wantReason: "account_type_not_allowed"},
+		{name: "broker_not_allowed", method: "GET", path: verify + "&userid=10086&broker=Pepperstone&server=Pepperstone-Live&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "broker_not_allowed"},
+		{name: "verify_does_not_bind", method: "GET", path: verify + "&userid=20001&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "account_not_bound"},
+		{name: "activate_live_account", method: "POST", path: activate + "&userid=20001&broker=ICMarketsSC&account_type=real", wantStatus: fiber.StatusForbidden, wantReason: "account_type_not_allowed"},
+		{name: "activate_binds_account", method: "POST", path: activate + "&userid=20001&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusOK},
+		{name: "bound_account", method: "GET", path: verify + "&userid=20001&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "ok"},
+		{name: "account_limit_reached", method: "GET", path: verify + "&userid=30001&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusOK, wantReason: "account_limit_reached"},
+		{name: "activate_over_limit", method: "POST", path: activate + "&userid=30001&broker=ICMarketsSC&account_type=demo", wantStatus: fiber.StatusForbidden, wantReason: "account_limit_reached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantReason == "" {
+				return
+			}
+
+			var body struct {
+				Valid  bool   `json:"valid"`
+				Reason string `json:"reason"`
+			}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.wantReason, body.Reason)
+			assert.Equal(t, tt.wantReason == "ok", body.Valid)
+		})
+	}
+
+	var license model.License
+	database.DB.Scopes(database.ByLicenseKey(key)).First(&license)
+	assert.Equal(t, []string{"10086", "20001"}, license.AllowedAccounts)
+}
+
+func TestHandleLicenseActivateBindRejected(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/licenses/activate", HandleLicenseActivate)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	database.DB.Create(&model.License{
+		Key:             key,
+		Status:          model.LicenseActive,
+		ValidUntil:      time.Now().AddDate(0, 0, 30),
+		ProductId:       "gold",
+		MaxSeats:        2,
+		AllowedAccounts: []string{"10086"},
+		MaxAccounts:     2,
+	})
+
+	// 模拟并发激活在占用席位期间绑定了最后一个账号名额
+	hook := "test:bind_last_account"
+	database.DB.Callback().Create().After("gorm:create").Register(hook, func(db *gorm.DB) {
+		if db.Statement.Table != "license_activations" {
+			return
+		}
+		db.Session(&gorm.Session{NewDB: true}).Model(&model.License{}).
+			Scopes(database.ByLicenseKey(key)).
+			Select("allowed_accounts").
+			Updates(&model.License{AllowedAccounts: []string{"10086", "20002"}})
+	})
+	defer database.DB.Callback().Create().Remove(hook)
+
+	req, _ := http.NewRequest("POST", "/api/v1/licenses/activate?key="+key+"&terminal_id=T-A&account=20001&userid=20001", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "account_limit_reached", body.Reason)
+
+	// 绑定被拒绝时席位一并回滚
+	var seats int64
+	database.DB.Model(&model.LicenseActivation{}).Where("license_key = ?", key).Count(&seats)
+	assert.Equal(t, int64(0), seats)
+}
+
+func TestHandleGetAllLicenses(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/licenses", HandleGetAllLicenses)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	// 创建时间相同的许可证用于验证游标在排序值相同时按 ID 继续
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	expires := time.Now().Truncate(24 * time.Hour)
+	for i, status := range []model.LicenseStatus{
+		model.LicenseActive, model.LicenseActive, model.LicenseActive, model.LicenseInactive,
+		model.LicenseExpired, model.LicenseRevoked, model.LicenseActive,
+	} {
+		productId := "gold"
+		if i >= 5 {
+			productId = "silver"
+		}
+		database.DB.Create(&model.License{
+			Key:        fmt.Sprintf("LIST-%02d", i),
+			Status:     status,
+			ProductId:  productId,
+			UserId:     fmt.Sprintf("%d", 8000+i),
+			ValidUntil: expires.AddDate(0, 0, i*10),
+			CreatedAt:  created.Add(time.Duration(i/3) * time.Minute),
+		})
+	}
+
+	type listResponse struct {
+		Licenses   []model.License `json:"licenses"`
+		Total      *int64          `json:"total"`
+		Page       int             `json:"page"`
+		Size       int             `json:"size"`
+		NextCursor string          `json:"next_cursor"`
+	}
+	get := func(query string) (int, listResponse) {
+		req, _ := http.NewRequest("GET", "/api/v1/licenses/licenses?"+query, nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var body listResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+	keysOf := func(licenses []model.License) []string {
+		keys := make([]string, 0, len(licenses))
+		for _, l := range licenses {
+			keys = append(keys, l.Key)
+		}
+		return keys
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTotal  int64
+		wantKeys   []string
+	}{
+		{name: "default_newest_first", query: "page_size=3", wantStatus: fiber.StatusOK, wantTotal: 7, wantKeys: []string{"LIST-06", "LIST-05", "LIST-04"}},
+		{name: "second_page", query: "page=2&page_size=3", wantStatus: fiber.StatusOK, wantTotal: 7, wantKeys: []string{"LIST-03", "LIST-02", "LIST-01"}},
+		{name: "by_status_product", query: "status=active&productid=gold", wantStatus: fiber.StatusOK, wantTotal: 3, wantKeys: []string{"LIST-02", "LIST-01", "LIST-00"}},
+		{name: "keyword", query: "keyword=8004", wantStatus: fiber.StatusOK, wantTotal: 1, wantKeys: []string{"LIST-04"}},
+		{name: "keyword_wildcards", query: "keyword=" + url.QueryEscape("LIST_0%"), wantStatus: fiber.StatusOK, wantTotal: 0, wantKeys: []string{}},
+		{name: "keyword_backslash", query: "keyword=" + url.QueryEscape(`LIST\-04`), wantStatus: fiber.StatusOK, wantTotal: 0, wantKeys: []string{}},
+		{name: "sort_key_asc", query: "sort=key&order=asc&page_size=2", wantStatus: fiber.StatusOK, wantTotal: 7, wantKeys: []string{"LIST-00", "LIST-01"}},
+		{name: "expiry_range", query: "sort=valid_until&order=asc&expires_after=" + url.QueryEscape(expires.AddDate(0, 0, 10).Format(time.RFC3339)) + "&expires_before=" + url.QueryEscape(expires.AddDate(0, 0, 30).Format(time.RFC3339)), wantStatus: fiber.StatusOK, wantTotal: 2, wantKeys: []string{"LIST-01", "LIST-02"}},
+		{name: "created_after", query: "created_after=" + url.QueryEscape(created.Add(2*time.Minute).Format(time.RFC3339)), wantStatus: fiber.StatusOK, wantTotal: 1, wantKeys: []string{"LIST-06"}},
+		{name: "invalid_sort", query: "sort=password", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_order", query: "order=random", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_status", query: "status=deleted", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_date", query: "expires_before=tomorrow", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_cursor", query: "cursor=bm90LWpzb24", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(tt.query)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantTotal, *body.Total)
+			assert.Equal(t, tt.wantKeys, keysOf(body.Licenses))
+		})
+	}
+
+	// 按游标遍历全部数据，不重复不遗漏
+	for _, order := range []string{"asc", "desc"} {
+		var keys []string
+		query := "sort=created_at&order=" + order + "&page_size=2"
+		status, body := get(query)
+		for {
+			assert.Equal(t, fiber.StatusOK, status)
+			keys = append(keys, keysOf(body.Licenses)...)
+			if body.NextCursor == "" {
+				break
+			}
+			status, body = get(query + "&cursor=" + body.NextCursor)
+			assert.Nil(t, body.Total)
+		}
+		assert.Len(t, keys, 7)
+		assert.ElementsMatch(t, []string{"LIST-00", "LIST-01", "LIST-02", "LIST-03", "LIST-04", "LIST-05", "LIST-06"}, keys)
+	}
+
+	// 游标与排序参数不一致
+	_, body := get("sort=key&page_size=2")
+	status, _ := get("sort=created_at&page_size=2&cursor=" + body.NextCursor)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestHandleLicenseUpdate(t *testing.T) {
+	app := fiber.New()
+	app.Put("/api/v1/licenses/:key", HandleLicenseUpdate)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	key, _ := keygen.Generate("")
+	validUntil := time.Now().AddDate(0, 0, 30).Truncate(time.Second)
+	database.DB.Create(&model.License{Key: key, Status: model.LicenseActive, ValidUntil: validUntil, UserId: "10086"})
+
+	// 按顺序执行，任一字段无效时整个更新都不应写入
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantDB     model.LicenseStatus
+	}{
+		{name: "invalid_version_rule", body: `{"status":"suspended","version_constraint":"not a range"}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "invalid_valid_until", body: `{"status":"suspended","validuntil":"2030-01-01"}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "past_valid_until", body: `{"status":"suspended","validuntil":"2020-01-01T00:00:00Z"}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "negative_seats", body: `{"status":"revoked","max_seats":-1}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "accounts_over_limit", body: `{"status":"suspended","allowed_accounts":["1001","1002","1003"],"max_accounts":2}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "invalid_transition", body: `{"status":"inactive"}`, wantStatus: fiber.StatusBadRequest, wantDB: model.LicenseActive},
+		{name: "suspend", body: `{"status":"suspended","max_seats":2}`, wantStatus: fiber.StatusOK, wantDB: model.LicenseSuspended},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", "/api/v1/licenses/"+key, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var license model.License
+			assert.NoError(t, database.DB.Where("id = ?", 1).First(&license).Error)
+			assert.Equal(t, tt.wantDB, license.Status)
+			assert.True(t, license.ValidUntil.Equal(validUntil))
+		})
+	}
+
+	var license model.License
+	database.DB.Where("id = ?", 1).First(&license)
+	assert.Equal(t, 2, license.MaxSeats)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"license-management-system/internal/util"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// 校验和激活的响应需要服务器私钥签名
+	dir, err := os.MkdirTemp("", "handler-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := util.InitSigningKey(filepath.Join(dir, "signing.pem")); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
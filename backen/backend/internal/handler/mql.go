@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/mqlgen"
+	"license-management-system/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleProductClientInclude 下载产品的 MQL4/MQL5 客户端 include 文件。
+// 查询参数 server 为客户端访问的服务地址，默认使用当前请求的地址；
+// key_id 为嵌入的 API 密钥，retries 为网络错误时的重试次数
+func HandleProductClientInclude(c *fiber.Ctx) error {
+	opts := service.ClientIncludeOptions{
+		ServerURL: c.Query("server", c.BaseURL()),
+		KeyID:     c.Query("key_id"),
+		Retries:   c.QueryInt("retries"),
+	}
+
+	name, source, err := service.GenerateClientInclude(c.Params("code"), opts)
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	case errors.Is(err, service.ErrClientKeyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrClientKeyInvalid),
+		errors.Is(err, mqlgen.ErrInvalidServerURL),
+		errors.Is(err, mqlgen.ErrInvalidRetries):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "生成客户端文件失败",
+		})
+	}
+
+	c.Attachment(name)
+	c.Set(fiber.HeaderContentType, "text/plain; charset=utf-8")
+	return c.Send(source)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"io"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleProductClientInclude(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/products/:code/mql", HandleProductClientInclude)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&[]model.Product{{Code: "gold", Name: "Gold Scalper"}, {Code: "silver", Name: "Silver Scalper"}})
+	key, err := service.CreateClientKey("gold", "ea")
+	assert.NoError(t, err)
+	other, err := service.CreateClientKey("silver", "ea")
+	assert.NoError(t, err)
+	expired, err := service.CreateClientKey("gold", "old")
+	assert.NoError(t, err)
+	database.DB.Model(expired).Update("expires_at", time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		contains     []string
+	}{
+		{
+			name:         "default_server",
+			url:          "/api/v1/products/gold/mql",
+			expectedCode: fiber.StatusOK,
+			contains: []string{
+				`#define LICENSE_SERVER         "http://example.com"`,
+				`#define LICENSE_PRODUCT_ID     "gold"`,
+				`#define LICENSE_PUBLIC_KEY     "` + licensing.EncodePublicKey(util.PublicKey()) + `"`,
+				`#define LICENSE_CLIENT_KEY     ""`,
+				`#define LICENSE_RETRIES        3`,
+			},
+		},
+		{
+			name:         "with_key",
+			url:          "/api/v1/products/gold/mql?server=https://license.example.com&key_id=" + key.KeyID + "&retries=5",
+			expectedCode: fiber.StatusOK,
+			contains: []string{
+				`#define LICENSE_SERVER         "https://license.example.com"`,
+				`#define LICENSE_CLIENT_SECRET  "` + key.Secret + `"`,
+				`#define LICENSE_RETRIES        5`,
+				`#define LICENSE_TOKEN_FILE     "License_gold.token"`,
+			},
+		},
+		{name: "unknown_product", url: "/api/v1/products/platinum/mql", expectedCode: fiber.StatusNotFound},
+		{name: "other_product_key", url: "/api/v1/products/gold/mql?key_id=" + other.KeyID, expectedCode: fiber.StatusNotFound},
+		{name: "expired_key", url: "/api/v1/products/gold/mql?key_id=" + expired.KeyID, expectedCode: fiber.StatusBadRequest},
+		{name: "invalid_server", url: "/api/v1/products/gold/mql?server=example.com", expectedCode: fiber.StatusBadRequest},
+		{name: "too_many_retries", url: "/api/v1/products/gold/mql?retries=11", expectedCode: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.url, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			if tt.expectedCode != fiber.StatusOK {
+				return
+			}
+
+			assert.Contains(t, resp.Header.Get("Content-Disposition"), `filename="License_gold.mqh"`)
+			body, _ := io.ReadAll(resp.Body)
+			for _, s := range tt.contains {
+				assert.Contains(t, string(body), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/pkg/licensing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProductInput 创建或更新产品的输入
+type ProductInput struct {
+	Code                string                  `json:"code"`
+	Name                string                  `json:"name"`
+	Description         string                  `json:"description"`
+	CurrentVersion      string                  `json:"current_version"`
+	VersionConstraint   string                  `json:"version_constraint"`
+	MaxMajorVersion     int                     `json:"max_major_version"`
+	ValidityDays        int                     `json:"validity_days"`
+	EntitlementSchema   model.EntitlementSchema `json:"entitlement_schema"`
+	DefaultEntitlements licensing.Entitlements  `json:"default_entitlements"`
+	DefaultMaxSeats     int                     `json:"default_max_seats"`
+	KeyPrefix           string                  `json:"key_prefix"`
+	OfflineGraceHours   int                     `json:"offline_grace_hours"`
+}
+
+// ProductUpdateInput 更新产品的输入，只修改请求中提供的字段
+type ProductUpdateInput struct {
+	Name                *string                  `json:"name"`
+	Description         *string                  `json:"description"`
+	CurrentVersion      *string                  `json:"current_version"`
+	VersionConstraint   *string                  `json:"version_constraint"`
+	MaxMajorVersion     *int                     `json:"max_major_version"`
+	ValidityDays        *int                     `json:"validity_days"`
+	EntitlementSchema   *model.EntitlementSchema `json:"entitlement_schema"`
+	DefaultEntitlements *licensing.Entitlements  `json:"default_entitlements"`
+	DefaultMaxSeats     *int                     `json:"default_max_seats"`
+	KeyPrefix           *string                  `json:"key_prefix"`
+	OfflineGraceHours   *int                     `json:"offline_grace_hours"`
+}
+
+// HandleGetProducts 获取产品目录
+func HandleGetProducts(c *fiber.Ctx) error {
+	var products []model.Product
+	if err := database.DB.Order("code ASC").Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品列表失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"products": products,
+	})
+}
+
+// HandleGetProduct 获取产品详情，包括试用策略和已发布版本
+func HandleGetProduct(c *fiber.Ctx) error {
+	product, err := service.GetProduct(c.Params("code"))
+	if errors.Is(err, service.ErrProductNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品失败",
+		})
+	}
+
+	if err := service.LoadTrialPolicy(product); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取试用策略失败",
+		})
+	}
+
+	var versions []model.ProductVersion
+	if err := database.DB.Where("product_code = ?", product.Code).Order("released_at DESC").Find(&versions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品版本失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"product":  product,
+		"versions": versions,
+	})
+}
+
+// HandleCreateProduct 创建产品
+func HandleCreateProduct(c *fiber.Ctx) error {
+	input := new(ProductInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+
+	product := &model.Product{
+		Code:                input.Code,
+		Name:                input.Name,
+		Description:         input.Description,
+		CurrentVersion:      input.CurrentVersion,
+		VersionConstraint:   input.VersionConstraint,
+		MaxMajorVersion:     input.MaxMajorVersion,
+		ValidityDays:        input.ValidityDays,
+		EntitlementSchema:   input.EntitlementSchema,
+		DefaultEntitlements: input.DefaultEntitlements,
+		DefaultMaxSeats:     input.DefaultMaxSeats,
+		KeyPrefix:           input.KeyPrefix,
+		OfflineGraceHours:   input.OfflineGraceHours,
+	}
+	if err := service.ValidateProduct(product); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if _, err := service.GetProduct(product.Code); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "产品代码已存在",
+		})
+	}
+
+	if err := database.DB.Create(product).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "产品创建失败",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(product)
+}
+
+// HandleUpdateProduct 更新产品，产品代码不可修改
+func HandleUpdateProduct(c *fiber.Ctx) error {
+	product, err := service.GetProduct(c.Params("code"))
+	if errors.Is(err, service.ErrProductNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品失败",
+		})
+	}
+
+	input := new(ProductUpdateInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+
+	// 未提供的字段保持不变，例如只修改名称时不能清空权益范围
+	if input.Name != nil {
+		product.Name = *input.Name
+	}
+	if input.Description != nil {
+		product.Description = *input.Description
+	}
+	if input.CurrentVersion != nil {
+		product.CurrentVersion = *input.CurrentVersion
+	}
+	if input.VersionConstraint != nil {
+		product.VersionConstraint = *input.VersionConstraint
+	}
+	if input.MaxMajorVersion != nil {
+		product.MaxMajorVersion = *input.MaxMajorVersion
+	}
+	if input.ValidityDays != nil {
+		product.ValidityDays = *input.ValidityDays
+	}
+	if input.EntitlementSchema != nil {
+		product.EntitlementSchema = *input.EntitlementSchema
+	}
+	if input.DefaultEntitlements != nil {
+		product.DefaultEntitlements = *input.DefaultEntitlements
+	}
+	if input.DefaultMaxSeats != nil {
+		product.DefaultMaxSeats = *input.DefaultMaxSeats
+	}
+	if input.KeyPrefix != nil {
+		product.KeyPrefix = *input.KeyPrefix
+	}
+	if input.OfflineGraceHours != nil {
+		product.OfflineGraceHours = *input.OfflineGraceHours
+	}
+
+	if err := service.ValidateProduct(product); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Save(product).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "更新产品失败",
+		})
+	}
+
+	return c.JSON(product)
+}
+
+// HandleDeleteProduct 删除产品，仍有许可证引用时拒绝删除
+func HandleDeleteProduct(c *fiber.Ctx) error {
+	product, err := service.GetProduct(c.Params("code"))
+	if errors.Is(err, service.ErrProductNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品失败",
+		})
+	}
+
+	var count int64
+	if err := database.DB.Model(&model.License{}).Where("product_id = ?", product.Code).Count(&count).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "查询产品许可证失败",
+		})
+	}
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":    "该产品仍有许可证，无法删除",
+			"licenses": count,
+		})
+	}
+
+	if err := database.DB.Delete(product).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "删除产品失败",
+		})
+	}
+	database.DB.Where("product_id = ?", product.Code).Delete(&model.TrialPolicy{})
+	database.DB.Where("product_code = ?", product.Code).Delete(&model.ProductVersion{})
+
+	return c.JSON(fiber.Map{
+		"message": "产品删除成功",
+	})
+}
+
+// HandleReleaseProductVersion 发布新版本并设为产品当前版本
+func HandleReleaseProductVersion(c *fiber.Ctx) error {
+	product, err := service.GetProduct(c.Params("code"))
+	if errors.Is(err, service.ErrProductNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取产品失败",
+		})
+	}
+
+	input := new(model.ProductVersion)
+	if err := c.BodyParser(input); err != nil || input.Version == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "版本号不能为空",
+		})
+	}
+	if _, err := semver.NewVersion(input.Version); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": service.ErrInvalidVersion.Error(),
+		})
+	}
+
+	version := &model.ProductVersion{
+		ProductCode:  product.Code,
+		Version:      input.Version,
+		ReleaseNotes: input.ReleaseNotes,
+		ReleasedAt:   time.Now(),
+	}
+	if err := database.DB.Create(version).Error; err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "该版本已发布",
+		})
+	}
+
+	product.CurrentVersion = version.Version
+	if err := database.DB.Save(product).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "更新产品当前版本失败",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(version)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"bytes"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleUpdateProduct(t *testing.T) {
+	app := fiber.New()
+	app.Put("/api/v1/products/:code", HandleUpdateProduct)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&model.Product{
+		Code:              "gold",
+		Name:              "Gold Scalper",
+		CurrentVersion:    "2.0.0",
+		KeyPrefix:         "GOLD",
+		OfflineGraceHours: 72,
+		EntitlementSchema: model.EntitlementSchema{Features: []string{"basic", "full"}, Symbols: []string{"XAUUSD"}},
+	})
+
+	tests := []struct {
+		name       string
+		code       string
+		body       string
+		wantStatus int
+	}{
+		{name: "rename_only", code: "gold", body: `{"name":"Gold Scalper Pro"}`, wantStatus: fiber.StatusOK},
+		{name: "clear_description", code: "gold", body: `{"description":""}`, wantStatus: fiber.StatusOK},
+		{name: "invalid_grace", code: "gold", body: `{"offline_grace_hours":-1}`, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown_product", code: "silver", body: `{"name":"Silver"}`, wantStatus: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", "/api/v1/products/"+tt.code, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+
+	// 只修改名称时其余字段保持不变
+	product, err := service.GetProduct("gold")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gold Scalper Pro", product.Name)
+	assert.Equal(t, []string{"basic", "full"}, product.EntitlementSchema.Features)
+	assert.Equal(t, []string{"XAUUSD"}, product.EntitlementSchema.Symbols)
+	assert.Equal(t, "GOLD", product.KeyPrefix)
+	assert.Equal(t, "2.0.0", product.CurrentVersion)
+	assert.Equal(t, 72, product.OfflineGraceHours)
+}
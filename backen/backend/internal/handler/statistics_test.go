@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupStatisticsTest(t *testing.T) *fiber.App {
+	app := fiber.New()
+	app.Get("/api/v1/licenses/statistics", HandleLicenseStatistics)
+	database.InitTestDB()
+	t.Cleanup(database.CleanTestDB)
+	return app
+}
+
+func getStatistics(t *testing.T, app *fiber.App, query string) (int, model.LicenseStatistics) {
+	req, _ := http.NewRequest("GET", "/api/v1/licenses/statistics"+query, nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+
+	var body struct {
+		Data model.LicenseStatistics `json:"data"`
+	}
+	if resp.StatusCode == fiber.StatusOK {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return resp.StatusCode, body.Data
+}
+
+func TestHandleLicenseStatistics(t *testing.T) {
+	app := setupStatisticsTest(t)
+
+	database.DB.Create(&[]model.License{
+		{Key: "STAT-1", Status: model.LicenseActive, ProductId: "gold", ValidUntil: time.Now().AddDate(0, 0, 10)},
+		{Key: "STAT-2", Status: model.LicenseActive, ProductId: "gold", ValidUntil: time.Now().AddDate(1, 0, 0)},
+		{Key: "STAT-3", Status: model.LicenseRevoked, ProductId: "gold", ValidUntil: time.Now().AddDate(1, 0, 0)},
+		{Key: "STAT-4", Status: model.LicenseActive, ProductId: "silver", ValidUntil: time.Now().AddDate(1, 0, 0)},
+	})
+
+	today := time.Now().Add(-time.Minute)
+	yesterday := today.AddDate(0, 0, -1)
+	database.DB.Create(&[]model.LicenseUsage{
+		{LicenseKey: "STAT-1", Action: "activate", Reason: "ok", Account: "1001", Country: "GB", Device: "desktop", Platform: "windows", Timestamp: yesterday},
+		{LicenseKey: "STAT-1", Action: "verify", Reason: "ok", Account: "1001", Country: "GB", Device: "desktop", Platform: "windows", Timestamp: yesterday},
+		{LicenseKey: "STAT-1", Action: "verify", Reason: "ok", Account: "1002", Country: "GB", Device: "desktop", Platform: "windows", Timestamp: today},
+		{LicenseKey: "STAT-2", Action: "verify", Account: "1002", Country: "JP", Device: "desktop", Platform: "linux", Timestamp: today},
+		{LicenseKey: "STAT-2", Action: "verify", Timestamp: today},
+		{LicenseKey: "STAT-3", Action: "verify", Reason: "revoked", Country: "GB", Device: "desktop", Platform: "windows", Timestamp: today},
+		{LicenseKey: "STAT-4", Action: "verify", Reason: "ok", Country: "DE", Timestamp: today},
+		// 统计范围之外
+		{LicenseKey: "STAT-2", Action: "verify", Country: "US", Timestamp: today.AddDate(0, 0, -40)},
+	})
+	database.DB.Create(&[]model.UsageSession{
+		{LeaseID: "lease-1", LicenseKey: "STAT-1", StartedAt: yesterday, LastSeenAt: yesterday, DurationSeconds: 3600},
+		{LeaseID: "lease-2", LicenseKey: "STAT-2", StartedAt: today, LastSeenAt: today, DurationSeconds: 7200},
+	})
+
+	// 汇总前没有使用量
+	status, stats := getStatistics(t, app, "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, int64(4), stats.TotalLicenses)
+	assert.Empty(t, stats.DailyUsage)
+
+	days, err := service.RollupUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, days)
+
+	var rollup model.DailyLicenseUsage
+	database.DB.Where("day = ? AND license_key = ?", today.Format("2006-01-02"), "STAT-3").First(&rollup)
+	assert.Equal(t, "gold", rollup.ProductId)
+	assert.Equal(t, int64(1), rollup.VerifyFailures)
+	var product model.DailyProductUsage
+	database.DB.Where("day = ? AND product_id = ?", today.Format("2006-01-02"), "gold").First(&product)
+	assert.Equal(t, int64(3), product.Licenses)
+	assert.Equal(t, int64(1), product.Accounts)
+
+	allCountries := map[string]int{"GB": 4, "JP": 1, "DE": 1, "unknown": 1}
+	goldCountries := map[string]int{"GB": 4, "JP": 1, "unknown": 1}
+	tests := []struct {
+		name         string
+		query        string
+		wantStatus   int
+		wantLicenses int64
+		wantCountry  map[string]int
+	}{
+		{name: "default_range", query: "", wantStatus: fiber.StatusOK, wantLicenses: 4, wantCountry: allCountries},
+		{name: "explicit_range", query: "?start_date=" + yesterday.Format("2006-01-02") + "&end_date=" + today.Format("2006-01-02"), wantStatus: fiber.StatusOK, wantLicenses: 4, wantCountry: allCountries},
+		{name: "product", query: "?product=gold", wantStatus: fiber.StatusOK, wantLicenses: 3, wantCountry: goldCountries},
+		{name: "invalid_start", query: "?start_date=2024/01/01", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid_granularity", query: "?granularity=year", wantStatus: fiber.StatusBadRequest},
+		{name: "reversed_range", query: "?start_date=" + today.Format("2006-01-02") + "&end_date=" + yesterday.Format("2006-01-02"), wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stats := getStatistics(t, app, tt.query)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "day", stats.Granularity)
+			assert.Equal(t, tt.wantLicenses, stats.TotalLicenses)
+			assert.Equal(t, tt.wantCountry, stats.UsageByCountry)
+			assert.Equal(t, int64(1), stats.RevokedLicenses)
+			assert.Equal(t, int64(1), stats.ExpiringLicenses)
+			assert.Equal(t, int64(1), stats.TotalActivations)
+			assert.Equal(t, int64(2), stats.TotalSessions)
+			assert.InDelta(t, 1.5, stats.AverageUsageDuration, 0.001)
+
+			if assert.Len(t, stats.DailyUsage, 2) {
+				assert.Equal(t, yesterday.Format("2006-01-02"), stats.DailyUsage[0].Date.Format("2006-01-02"))
+				assert.Equal(t, 1, stats.DailyUsage[0].ActiveUsers)
+				assert.Equal(t, 1, stats.DailyUsage[0].TotalChecks)
+				assert.Equal(t, 1, stats.DailyUsage[0].NewActivations)
+				assert.Equal(t, 1, stats.DailyUsage[1].Failures)
+			}
+		})
+	}
+
+	status, stats = getStatistics(t, app, "?product=silver")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, int64(1), stats.TotalLicenses)
+	assert.Equal(t, map[string]int{"DE": 1}, stats.UsageByCountry)
+	assert.Equal(t, map[string]int{"unknown": 1}, stats.UsageByDevice)
+	assert.Equal(t, int64(0), stats.TotalSessions)
+
+	// 增量汇总只重新计算有新记录的日期
+	database.DB.Create(&model.LicenseUsage{LicenseKey: "STAT-4", Action: "verify", Reason: "ok", Country: "DE", Timestamp: today})
+	days, err = service.RollupUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, days)
+	days, err = service.RollupUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, days)
+
+	_, stats = getStatistics(t, app, "?product=silver")
+	assert.Equal(t, map[string]int{"DE": 2}, stats.UsageByCountry)
+	if assert.Len(t, stats.DailyUsage, 1) {
+		assert.Equal(t, 2, stats.DailyUsage[0].TotalChecks)
+	}
+}
+
+func TestHandleLicenseStatisticsGranularity(t *testing.T) {
+	app := setupStatisticsTest(t)
+
+	database.DB.Create(&[]model.License{
+		{Key: "GRAN-1", Status: model.LicenseActive, ProductId: "gold"},
+		{Key: "GRAN-2", Status: model.LicenseActive, ProductId: "gold"},
+	})
+	// 2024-03-04 是星期一
+	at := func(day int) time.Time { return time.Date(2024, 3, day, 12, 0, 0, 0, time.Local) }
+	database.DB.Create(&[]model.LicenseUsage{
+		{LicenseKey: "GRAN-1", Action: "verify", Timestamp: at(4)},
+		{LicenseKey: "GRAN-1", Action: "verify", Timestamp: at(6)},
+		{LicenseKey: "GRAN-2", Action: "verify", Timestamp: at(6)},
+		{LicenseKey: "GRAN-2", Action: "verify", Timestamp: at(12)},
+	})
+	_, err := service.RollupUsage()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		granularity string
+		wantDates   []string
+		wantActive  []int
+		wantChecks  []int
+	}{
+		{name: "day", granularity: "day", wantDates: []string{"2024-03-04", "2024-03-06", "2024-03-12"}, wantActive: []int{1, 2, 1}, wantChecks: []int{1, 2, 1}},
+		{name: "week", granularity: "week", wantDates: []string{"2024-03-04", "2024-03-11"}, wantActive: []int{2, 1}, wantChecks: []int{3, 1}},
+		{name: "month", granularity: "month", wantDates: []string{"2024-03-01"}, wantActive: []int{2}, wantChecks: []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stats := getStatistics(t, app, "?start_date=2024-03-01&end_date=2024-03-31&granularity="+tt.granularity)
+			assert.Equal(t, fiber.StatusOK, status)
+			assert.Equal(t, tt.granularity, stats.Granularity)
+
+			var dates []string
+			var active, checks []int
+			for _, usage := range stats.DailyUsage {
+				dates = append(dates, usage.Date.Format("2006-01-02"))
+				active = append(active, usage.ActiveUsers)
+				checks = append(checks, usage.TotalChecks)
+			}
+			assert.Equal(t, tt.wantDates, dates)
+			assert.Equal(t, tt.wantActive, active)
+			assert.Equal(t, tt.wantChecks, checks)
+		})
+	}
+}
+
+func TestHandleLicenseStatisticsPrunedDay(t *testing.T) {
+	app := setupStatisticsTest(t)
+
+	database.DB.Create(&model.License{Key: "PRUNE-1", Status: model.LicenseActive, ProductId: "gold"})
+	started := time.Now().AddDate(0, 0, -100)
+	day := started.Format("2006-01-02")
+	database.DB.Create(&[]model.LicenseUsage{
+		{LicenseKey: "PRUNE-1", Action: "verify", Reason: "ok", Timestamp: started},
+		{LicenseKey: "PRUNE-1", Action: "verify", Reason: "expired", Timestamp: started},
+	})
+	session := model.UsageSession{LeaseID: "lease-1", LicenseKey: "PRUNE-1", StartedAt: started, LastSeenAt: started, DurationSeconds: 600}
+	database.DB.Create(&session)
+	_, err := service.RollupUsage()
+	assert.NoError(t, err)
+
+	// 原始使用记录已被 prune_history 清理后，会话更新只重新计算会话时长
+	database.DB.Unscoped().Where("license_key = ?", "PRUNE-1").Delete(&model.LicenseUsage{})
+	database.DB.Model(&session).Updates(map[string]interface{}{"last_seen_at": time.Now(), "duration_seconds": 1800})
+	days, err := service.RollupUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, days)
+
+	var product model.DailyProductUsage
+	assert.NoError(t, database.DB.Where("day = ? AND product_id = ?", day, "gold").First(&product).Error)
+	assert.Equal(t, int64(2), product.Verifies)
+	assert.Equal(t, int64(1), product.VerifyFailures)
+	assert.Equal(t, int64(1), product.Sessions)
+	assert.Equal(t, int64(1800), product.SessionSeconds)
+
+	var license model.DailyLicenseUsage
+	assert.NoError(t, database.DB.Where("day = ? AND license_key = ?", day, "PRUNE-1").First(&license).Error)
+	assert.Equal(t, int64(2), license.Verifies)
+
+	_, stats := getStatistics(t, app, "?start_date="+day+"&end_date="+day)
+	if assert.Len(t, stats.DailyUsage, 1) {
+		assert.Equal(t, 2, stats.DailyUsage[0].TotalChecks)
+	}
+}
+
+func TestHandleLicenseStatisticsRecordedProduct(t *testing.T) {
+	app := setupStatisticsTest(t)
+
+	database.DB.Create(&model.License{Key: "PROD-1", Status: model.LicenseActive, ProductId: "gold", ValidUntil: time.Now().AddDate(1, 0, 0)})
+	today := time.Now().Add(-time.Minute)
+	day := today.Format("2006-01-02")
+	database.DB.Create(&[]model.LicenseUsage{
+		{LicenseKey: "PROD-1", ProductId: "gold", Action: "verify", Reason: "ok", Account: "1001", Timestamp: today},
+		// 升级前的记录没有产品，按许可证归属
+		{LicenseKey: "PROD-1", Action: "verify", Reason: "ok", Account: "1001", Timestamp: today},
+		// 不存在的密钥按客户端请求的产品归属
+		{LicenseKey: "NO-SUCH-KEY", ProductId: "gold", Action: "verify", Reason: "not_found", Account: "2002", Country: "FR", Timestamp: today},
+	})
+
+	_, err := service.RollupUsage()
+	assert.NoError(t, err)
+
+	var product model.DailyProductUsage
+	assert.NoError(t, database.DB.Where("day = ? AND product_id = ?", day, "gold").First(&product).Error)
+	assert.Equal(t, int64(2), product.Licenses)
+	assert.Equal(t, int64(3), product.Verifies)
+	assert.Equal(t, int64(1), product.VerifyFailures)
+	assert.Equal(t, int64(2), product.Accounts)
+
+	var license model.DailyLicenseUsage
+	assert.NoError(t, database.DB.Where("day = ? AND license_key = ?", day, "PROD-1").First(&license).Error)
+	assert.Equal(t, int64(2), license.Verifies)
+	assert.Equal(t, int64(1), license.Accounts)
+
+	status, stats := getStatistics(t, app, "?product=gold")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 1, stats.UsageByCountry["FR"])
+	if assert.Len(t, stats.DailyUsage, 1) {
+		assert.Equal(t, 3, stats.DailyUsage[0].TotalChecks)
+		assert.Equal(t, 1, stats.DailyUsage[0].Failures)
+	}
+}
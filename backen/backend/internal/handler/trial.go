@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrialInput 试用申请
+type TrialInput struct {
+	ProductId string `json:"productid"`
+	Email     string `json:"email"`
+	model.Fingerprint
+}
+
+// HandleTrialRequest 公开的试用申请接口，按产品试用策略发放试用许可证
+func HandleTrialRequest(c *fiber.Ctx) error {
+	input := new(TrialInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+
+	if input.ProductId == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "策略名称不能为空",
+		})
+	}
+	if !input.Fingerprint.IsComplete() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "设备指纹不能为空",
+		})
+	}
+
+	license, err := service.IssueTrial(service.TrialRequest{
+		ProductId:   input.ProductId,
+		Email:       input.Email,
+		IPAddress:   c.IP(),
+		Fingerprint: input.Fingerprint,
+	})
+	if errors.Is(err, service.ErrProductNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+	if errors.Is(err, service.ErrTrialUnavailable) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "该产品不提供试用",
+		})
+	}
+	if errors.Is(err, service.ErrTrialAlreadyUsed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "已申请过该产品的试用",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "试用许可证发放失败",
+		})
+	}
+
+	entitlements, err := service.ResolveEntitlements(license)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取许可证权益失败",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"key":          license.Key,
+		"productid":    license.ProductId,
+		"entitlements": entitlements,
+		"valid_until":  license.ValidUntil,
+	})
+}
+
+// HandleGetTrialPolicies 获取所有产品的试用策略
+func HandleGetTrialPolicies(c *fiber.Ctx) error {
+	var policies []model.TrialPolicy
+	if err := database.DB.Order("product_id ASC").Find(&policies).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取试用策略失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"policies": policies,
+	})
+}
+
+// HandleSaveTrialPolicy 创建或更新产品的试用策略
+func HandleSaveTrialPolicy(c *fiber.Ctx) error {
+	productId := c.Params("productid")
+	product, err := service.GetProduct(productId)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "产品不存在",
+		})
+	}
+
+	input := new(model.TrialPolicy)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+
+	if err := service.ValidateTrialPolicy(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "试用天数须在 1 到 " + strconv.Itoa(service.MaxTrialDurationDays) + " 天之间，申请间隔不能为负数",
+		})
+	}
+
+	if err := product.EntitlementSchema.Validate(input.Entitlements); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var policy model.TrialPolicy
+	database.DB.Where("product_id = ?", productId).First(&policy)
+
+	policy.ProductId = productId
+	policy.Enabled = input.Enabled
+	policy.DurationDays = input.DurationDays
+	policy.Entitlements = input.Entitlements
+	policy.RepeatWindowDays = input.RepeatWindowDays
+
+	if err := database.DB.Save(&policy).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "保存试用策略失败",
+		})
+	}
+
+	return c.JSON(policy)
+}
+
+// HandleGetTrialGrants 查询试用发放记录
+func HandleGetTrialGrants(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+
+	// 限制页面大小
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	db := database.DB.Model(&model.TrialGrant{})
+	if productId := c.Query("productid"); productId != "" {
+		db = db.Where("product_id = ?", productId)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取试用记录总数失败",
+		})
+	}
+
+	var grants []model.TrialGrant
+	offset := (page - 1) * pageSize
+	if err := db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&grants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "获取试用记录失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"grants": grants,
+		"total":  total,
+		"page":   page,
+		"size":   pageSize,
+	})
+}
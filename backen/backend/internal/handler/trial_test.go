@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestHandleTrialRequest(t *testing.T) {
+	// 每个用例使用不同的客户端 IP，避免 IP 条件掩盖账号和邮箱的检查
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Post("/api/v1/trials", HandleTrialRequest)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&[]model.Product{{Code: "gold", Name: "Gold"}, {Code: "silver", Name: "Silver"}})
+	database.DB.Create(&model.TrialPolicy{
+		ProductId:        "gold",
+		Enabled:          true,
+		DurationDays:     7,
+		Entitlements:     licensing.Entitlements{Features: []string{"basic"}},
+		RepeatWindowDays: 365,
+	})
+
+	fingerprint := func(terminal, account string) model.Fingerprint {
+		return model.Fingerprint{TerminalID: terminal, AccountNumber: account}
+	}
+
+	// 超过间隔期的旧记录不影响再次申请
+	database.DB.Create(&model.TrialGrant{
+		ProductId:   "gold",
+		Fingerprint: fingerprint("T-7", "1007").Hash(),
+		CreatedAt:   time.Now().AddDate(-2, 0, 0),
+	})
+
+	tests := []struct {
+		name       string
+		ip         string
+		input      TrialInput
+		wantStatus int
+	}{
+		{
+			name:       "first_trial",
+			ip:         "198.51.100.1",
+			input:      TrialInput{ProductId: "gold", Email: "a@example.com", Fingerprint: fingerprint("T-1", "1001")},
+			wantStatus: fiber.StatusCreated,
+		},
+		{
+			name:       "same_account",
+			ip:         "198.51.100.2",
+			input:      TrialInput{ProductId: "gold", Email: "b@example.com", Fingerprint: fingerprint("T-2", "1001")},
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name:       "same_email_different_case",
+			ip:         "198.51.100.3",
+			input:      TrialInput{ProductId: "gold", Email: "A@Example.com", Fingerprint: fingerprint("T-3", "1003")},
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name:       "same_ip",
+			ip:         "198.51.100.1",
+			input:      TrialInput{ProductId: "gold", Email: "c@example.com", Fingerprint: fingerprint("T-5", "1005")},
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name:       "new_client",
+			ip:         "198.51.100.6",
+			input:      TrialInput{ProductId: "gold", Email: "d@example.com", Fingerprint: fingerprint("T-6", "1006")},
+			wantStatus: fiber.StatusCreated,
+		},
+		{
+			name:       "after_repeat_window",
+			ip:         "198.51.100.7",
+			input:      TrialInput{ProductId: "gold", Email: "e@example.com", Fingerprint: fingerprint("T-7", "1007")},
+			wantStatus: fiber.StatusCreated,
+		},
+		{
+			name:       "unknown_product",
+			ip:         "198.51.100.8",
+			input:      TrialInput{ProductId: "bronze", Fingerprint: fingerprint("T-8", "1008")},
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name:       "no_policy",
+			ip:         "198.51.100.4",
+			input:      TrialInput{ProductId: "silver", Fingerprint: fingerprint("T-4", "1004")},
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name:       "missing_fingerprint",
+			ip:         "198.51.100.5",
+			input:      TrialInput{ProductId: "gold"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.input)
+			req, _ := http.NewRequest("POST", "/api/v1/trials", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(fiber.HeaderXForwardedFor, tt.ip)
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+
+	var license model.License
+	assert.NoError(t, database.DB.Where("is_trial = ?", true).Order("id").First(&license).Error)
+	assert.Equal(t, []string{"basic"}, license.Entitlements.Features)
+	assert.Equal(t, 1, license.MaxSeats)
+
+	var grants int64
+	database.DB.Model(&model.TrialGrant{}).Where("fingerprint = ?", fingerprint("T-7", "1007").Hash()).Count(&grants)
+	assert.Equal(t, int64(1), grants)
+}
+
+func TestHandleTrialRequestConcurrent(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/trials", HandleTrialRequest)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	database.DB.Create(&model.Product{Code: "gold", Name: "Gold"})
+	database.DB.Create(&model.TrialPolicy{ProductId: "gold", Enabled: true, DurationDays: 7, RepeatWindowDays: 365})
+	fp := model.Fingerprint{TerminalID: "T-1", AccountNumber: "1001"}
+
+	// 模拟另一个请求在检查之后、写入之前发放了同一设备的试用
+	hook := "test:concurrent_trial"
+	database.DB.Callback().Create().Before("gorm:create").Register(hook, func(db *gorm.DB) {
+		if db.Statement.Table != "licenses" {
+			return
+		}
+		db.Session(&gorm.Session{NewDB: true}).Create(&model.TrialGrant{ProductId: "gold", Fingerprint: fp.Hash(), CreatedAt: time.Now()})
+	})
+	defer database.DB.Callback().Create().Remove(hook)
+
+	body, _ := json.Marshal(TrialInput{ProductId: "gold", Fingerprint: fp})
+	req, _ := http.NewRequest("POST", "/api/v1/trials", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	var licenses int64
+	database.DB.Model(&model.License{}).Where("is_trial = ?", true).Count(&licenses)
+	assert.Equal(t, int64(0), licenses)
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"license-management-system/internal/database"
 	"license-management-system/internal/model"
+	"license-management-system/internal/service"
 	"license-management-system/internal/util"
+	"math"
 	"strconv"
 	"time"
 
@@ -74,34 +77,42 @@ func HandleUserLogin(c *fiber.Ctx) error {
 		})
 	}
 
-	var user model.User
-	result := database.DB.Where("username = ?", input.Username).First(&user)
-	if result.Error != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "用户名或密码错误",
+	result, err := service.Login(input.Username, input.Password, c.IP(), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "登录失败",
 		})
 	}
 
-	// 验证密码
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
-	if err != nil {
+	// 记录登录日志
+	entry := &model.LoginLog{Username: input.Username, Reason: string(result.Reason)}
+	if result.User != nil {
+		entry.UserID = result.User.ID
+	}
+	service.RecordLogin(entry, clientInfo(c), c.Get(fiber.HeaderUserAgent))
+
+	switch result.Reason {
+	case service.LoginThrottled:
+		seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "登录失败次数过多，请稍后再试",
+			"retry_after": seconds,
+		})
+	case service.LoginInactive, service.LoginLocked:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": service.UserStatusError(result.User.Status).Error(),
+		})
+	case service.LoginUnknownUser, service.LoginBadPassword:
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "用户名或密码错误",
 		})
 	}
 
-	// 记录登录日志
-	loginLog := &model.LoginLog{
-		UserID:    user.ID,
-		IP:        c.IP(),
-		UserAgent: c.Get("User-Agent"),
-		Status:    "success",
-		CreatedAt: time.Now(),
-	}
-	database.DB.Create(loginLog)
+	user := result.User
 	// 更新用户最后登录时间
 	user.LastLogin = time.Now()
-	database.DB.Save(&user)
+	database.DB.Save(user)
 
 	// 生成JWT令牌
 	token, err := util.GenerateToken(user.ID)
@@ -341,6 +352,12 @@ func HandleValidateToken(c *fiber.Ctx) error {
 			"error": "用户不存在",
 		})
 	}
+	if !user.IsActive() {
+		return c.JSON(fiber.Map{
+			"valid": false,
+			"error": service.UserStatusError(user.Status).Error(),
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"valid": true,
@@ -352,3 +369,124 @@ func HandleValidateToken(c *fiber.Ctx) error {
 		},
 	})
 }
+
+// HandleLoginFailures 管理员查询失败的登录记录，可按用户名、IP 和原因过滤，
+// 包含用户名不存在的尝试
+func HandleLoginFailures(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	db := database.DB.Model(&model.LoginLog{}).Where("status = ?", "failed")
+	if username := c.Query("username"); username != "" {
+		db = db.Where("username = ?", username)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		db = db.Where("ip = ?", ip)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		db = db.Where("reason = ?", reason)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "查询失败的登录记录失败",
+		})
+	}
+	var logs []model.LoginLog
+	if err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "查询失败的登录记录失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"failures": logs,
+		"total":    total,
+		"page":     page,
+		"size":     pageSize,
+	})
+}
+
+// HandleUnlockUser 管理员解除用户的登录限制，被锁定的用户恢复为 active
+func HandleUnlockUser(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的用户ID",
+		})
+	}
+
+	user, err := service.GetUser(uint(id))
+	if err == nil {
+		user, err = service.UnlockUser(user.Username)
+	}
+	if err != nil {
+		return userError(c, err)
+	}
+
+	user.Password = ""
+	return c.JSON(user)
+}
+
+// HandleUpdateUserStatus 管理员修改用户状态，非 active 的用户不能登录，已签发的令牌随即失效
+func HandleUpdateUserStatus(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的用户ID",
+		})
+	}
+	var input struct {
+		Status string `json:"status"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "无效的输入数据",
+		})
+	}
+	if uint(id) == c.Locals("userID").(uint) {
+		// 避免管理员停用自己后无法登录
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "不能修改自己的状态",
+		})
+	}
+
+	user, err := service.GetUser(uint(id))
+	if err == nil {
+		err = service.SetUserStatus(user, input.Status)
+	}
+	if err != nil {
+		return userError(c, err)
+	}
+
+	user.Password = ""
+	return c.JSON(user)
+}
+
+// userError 将用户管理的服务层错误转换为响应
+func userError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidUserStatus):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status 只能为 active、inactive 或 locked",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "更新用户失败",
+		})
+	}
+}
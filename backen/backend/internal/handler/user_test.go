@@ -4,8 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"license-management-system/internal/database"
+	"license-management-system/internal/middleware"
+	"license-management-system/internal/model"
+	"license-management-system/internal/service"
+	"license-management-system/internal/util"
 	"net/http"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -14,6 +21,7 @@ import (
 func TestHandleUserRegister(t *testing.T) {
 	// 初始化测试环境
 	app := fiber.New()
+	app.Post("/api/v1/users/register", HandleUserRegister)
 	database.InitTestDB() // 使用测试数据库
 	defer database.CleanTestDB()
 
@@ -57,3 +65,231 @@ func TestHandleUserRegister(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleUserLogin(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/users/login", HandleUserLogin)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+	util.SetJWTSecret("test-secret")
+
+	for _, u := range []struct{ name, status string }{
+		{"alice", model.UserActive},
+		{"bob", model.UserInactive},
+		{"carol", model.UserLocked},
+	} {
+		user, err := service.CreateUser(u.name, u.name+"@example.com", "password123", "user")
+		assert.NoError(t, err)
+		assert.NoError(t, service.SetUserStatus(user, u.status))
+	}
+
+	// 按顺序执行，前面的失败会影响后面的结果
+	tests := []struct {
+		name       string
+		input      LoginInput
+		wantStatus int
+	}{
+		{name: "valid", input: LoginInput{Username: "alice", Password: "password123"}, wantStatus: fiber.StatusOK},
+		{name: "unknown_user", input: LoginInput{Username: "mallory", Password: "password123"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "inactive", input: LoginInput{Username: "bob", Password: "password123"}, wantStatus: fiber.StatusForbidden},
+		{name: "locked", input: LoginInput{Username: "carol", Password: "password123"}, wantStatus: fiber.StatusForbidden},
+		{name: "inactive_bad_password", input: LoginInput{Username: "bob", Password: "wrong"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "bad_password_1", input: LoginInput{Username: "alice", Password: "wrong"}, wantStatus: fiber.StatusUnauthorized},
+		// 大小写不同的用户名计入同一用户名的失败次数
+		{name: "other_case", input: LoginInput{Username: "Alice", Password: "wrong"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "bad_password_2", input: LoginInput{Username: "alice", Password: "wrong"}, wantStatus: fiber.StatusUnauthorized},
+		// 连续失败 3 次后需要等待，即使密码正确
+		{name: "throttled", input: LoginInput{Username: "alice", Password: "password123"}, wantStatus: fiber.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.input)
+			req, _ := http.NewRequest("POST", "/api/v1/users/login", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus == fiber.StatusTooManyRequests {
+				assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+			}
+		})
+	}
+
+	reasons := map[string]int64{
+		string(service.LoginOK):          1,
+		string(service.LoginUnknownUser): 2,
+		string(service.LoginInactive):    1,
+		string(service.LoginLocked):      1,
+		string(service.LoginBadPassword): 3,
+		string(service.LoginThrottled):   1,
+	}
+	for reason, want := range reasons {
+		var count int64
+		database.DB.Model(&model.LoginLog{}).Where("reason = ?", reason).Count(&count)
+		assert.Equal(t, want, count, reason)
+	}
+	var unknown model.LoginLog
+	assert.NoError(t, database.DB.Where("username = ?", "mallory").First(&unknown).Error)
+	assert.Equal(t, "mallory", unknown.Username)
+	assert.Equal(t, "failed", unknown.Status)
+	assert.Zero(t, unknown.UserID)
+}
+
+func TestHandleUserLoginConcurrent(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/users/login", HandleUserLogin)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+	util.SetJWTSecret("test-secret")
+
+	_, err := service.CreateUser("alice", "alice@example.com", "password123", "user")
+	assert.NoError(t, err)
+
+	// 同时发出的错误密码请求不能一起通过检查，只有达到等待阈值前的尝试会校验密码
+	const attempts = 20
+	statuses := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "/api/v1/users/login", bytes.NewBufferString(`{"username":"alice","password":"wrong"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			if assert.NoError(t, err) {
+				statuses <- resp.StatusCode
+			}
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	assert.Equal(t, map[int]int{
+		fiber.StatusUnauthorized:    3,
+		fiber.StatusTooManyRequests: attempts - 3,
+	}, counts)
+
+	var throttle model.LoginThrottle
+	assert.NoError(t, database.DB.Where("scope = ? AND subject = ?", service.ThrottleUsername, "alice").First(&throttle).Error)
+	assert.Equal(t, 3, throttle.Failures)
+}
+
+func TestHandleUserStatus(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/v1/auth/validate-token", HandleValidateToken)
+	app.Post("/api/v1/users/login", HandleUserLogin)
+	users := app.Group("/api/v1/users", middleware.Auth())
+	users.Get("/info", HandleUserInfo)
+	users.Get("/login-failures", middleware.AdminOnly(), HandleLoginFailures)
+	users.Post("/:id/unlock", middleware.AdminOnly(), HandleUnlockUser)
+	users.Put("/:id/status", middleware.AdminOnly(), HandleUpdateUserStatus)
+	database.InitTestDB()
+	defer database.CleanTestDB()
+	util.SetJWTSecret("test-secret")
+
+	admin, err := service.CreateUser("admin", "admin@example.com", "password123", "admin")
+	assert.NoError(t, err)
+	alice, err := service.CreateUser("alice", "alice@example.com", "password123", "user")
+	assert.NoError(t, err)
+	adminToken, _ := util.GenerateToken(admin.ID)
+	aliceToken, _ := util.GenerateToken(alice.ID)
+	aliceID := strconv.Itoa(int(alice.ID))
+
+	send := func(method, path, token, body string) *http.Response {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// 按顺序执行，状态变更会影响后面的请求
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "active_token", method: "GET", path: "/api/v1/users/info", token: aliceToken, wantStatus: fiber.StatusOK},
+		{name: "not_admin", method: "PUT", path: "/api/v1/users/" + aliceID + "/status", token: aliceToken, body: `{"status":"inactive"}`, wantStatus: fiber.StatusForbidden},
+		{name: "invalid_status", method: "PUT", path: "/api/v1/users/" + aliceID + "/status", token: adminToken, body: `{"status":"banned"}`, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown_user", method: "PUT", path: "/api/v1/users/999/status", token: adminToken, body: `{"status":"locked"}`, wantStatus: fiber.StatusNotFound},
+		{name: "own_status", method: "PUT", path: "/api/v1/users/" + strconv.Itoa(int(admin.ID)) + "/status", token: adminToken, body: `{"status":"inactive"}`, wantStatus: fiber.StatusBadRequest},
+		{name: "lock", method: "PUT", path: "/api/v1/users/" + aliceID + "/status", token: adminToken, body: `{"status":"locked"}`, wantStatus: fiber.StatusOK},
+		// 锁定后已签发的令牌随即失效
+		{name: "locked_token", method: "GET", path: "/api/v1/users/info", token: aliceToken, wantStatus: fiber.StatusForbidden},
+		{name: "locked_login", method: "POST", path: "/api/v1/users/login", body: `{"username":"alice","password":"password123"}`, wantStatus: fiber.StatusForbidden},
+		{name: "unlock", method: "POST", path: "/api/v1/users/" + aliceID + "/unlock", token: adminToken, wantStatus: fiber.StatusOK},
+		{name: "unlocked_token", method: "GET", path: "/api/v1/users/info", token: aliceToken, wantStatus: fiber.StatusOK},
+		{name: "deactivate", method: "PUT", path: "/api/v1/users/" + aliceID + "/status", token: adminToken, body: `{"status":"inactive"}`, wantStatus: fiber.StatusOK},
+		{name: "inactive_token", method: "GET", path: "/api/v1/users/info", token: aliceToken, wantStatus: fiber.StatusForbidden},
+		// 解除锁定不会启用被停用的用户
+		{name: "unlock_inactive", method: "POST", path: "/api/v1/users/" + aliceID + "/unlock", token: adminToken, wantStatus: fiber.StatusOK},
+		{name: "still_inactive", method: "GET", path: "/api/v1/users/info", token: aliceToken, wantStatus: fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(tt.method, tt.path, tt.token, tt.body)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+
+	var validate struct {
+		Valid bool   `json:"valid"`
+		Error string `json:"error"`
+	}
+	resp := send("POST", "/api/v1/auth/validate-token", "", `{"token":"`+aliceToken+`"}`)
+	json.NewDecoder(resp.Body).Decode(&validate)
+	assert.False(t, validate.Valid)
+	assert.Equal(t, service.ErrUserInactive.Error(), validate.Error)
+
+	// 达到锁定阈值后，管理员解除锁定即可立即登录
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, service.RecordLoginFailure("admin", "203.0.113.9", now))
+	}
+	resp = send("POST", "/api/v1/users/login", "", `{"username":"admin","password":"password123"}`)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, _ := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.InDelta(t, service.LoginLockDuration.Seconds(), retryAfter, 5)
+	_, err = service.UnlockUser("admin")
+	assert.NoError(t, err)
+	resp = send("POST", "/api/v1/users/login", "", `{"username":"admin","password":"password123"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// 解除锁定同时清除该用户最近登录失败的 IP，其他 IP 保持锁定
+	locked := now.Add(service.LoginLockDuration)
+	database.DB.Create(&[]model.LoginThrottle{
+		{Scope: service.ThrottleIP, Subject: "198.51.100.20", Failures: 50, LastFailedAt: now, LockedUntil: locked},
+		{Scope: service.ThrottleIP, Subject: "198.51.100.21", Failures: 50, LastFailedAt: now, LockedUntil: locked},
+	})
+	database.DB.Create(&model.LoginLog{Username: "Admin", IP: "198.51.100.20", Status: "failed", Reason: string(service.LoginBadPassword), CreatedAt: now})
+	_, err = service.UnlockUser("admin")
+	assert.NoError(t, err)
+	var lockedIPs []string
+	database.DB.Model(&model.LoginThrottle{}).Where("scope = ?", service.ThrottleIP).Pluck("subject", &lockedIPs)
+	assert.NotContains(t, lockedIPs, "198.51.100.20")
+	assert.Contains(t, lockedIPs, "198.51.100.21")
+
+	var failures struct {
+		Failures []model.LoginLog `json:"failures"`
+		Total    int64            `json:"total"`
+	}
+	resp = send("GET", "/api/v1/users/login-failures?username=alice", adminToken, "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	json.NewDecoder(resp.Body).Decode(&failures)
+	assert.Equal(t, int64(1), failures.Total)
+	assert.Equal(t, string(service.LoginLocked), failures.Failures[0].Reason)
+}
@@ -0,0 +1,201 @@
+// Package keygen 生成带校验位的许可证密钥。
+//
+// 密钥由 crypto/rand 生成，按组分隔（如 XXXXX-XXXXX-XXXXX-XXXXX），
+// 最后一位为 Luhn mod N 校验位，可选地带有产品前缀（如 GOLD-XXXXX-...）。
+package keygen
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidOptions = errors.New("keygen: 无效的密钥格式配置")
+	ErrInvalidPrefix  = errors.New("keygen: 无效的产品前缀")
+	ErrFormat         = errors.New("keygen: 密钥格式不匹配")
+	ErrChecksum       = errors.New("keygen: 密钥校验位错误")
+)
+
+// Options 密钥格式配置
+type Options struct {
+	Alphabet  string // 可用字符集，每个字符只能出现一次
+	GroupSize int    // 每组字符数
+	Groups    int    // 组数（不含前缀）
+	Separator string // 组分隔符
+}
+
+// DefaultOptions 默认格式：去除易混淆字符(0/O/1/I)的 32 字符集，4 组 5 位
+var DefaultOptions = Options{
+	Alphabet:  "23456789ABCDEFGHJKLMNPQRSTUVWXYZ",
+	GroupSize: 5,
+	Groups:    4,
+	Separator: "-",
+}
+
+// Default 使用默认格式的生成器
+var Default = MustNew(DefaultOptions)
+
+// Generator 许可证密钥生成器
+type Generator struct {
+	opts  Options
+	index map[rune]int
+}
+
+// New 根据配置创建生成器
+func New(opts Options) (*Generator, error) {
+	if opts.GroupSize < 1 || opts.Groups < 1 || opts.Separator == "" {
+		return nil, ErrInvalidOptions
+	}
+
+	alphabet := []rune(opts.Alphabet)
+	if len(alphabet) < 2 {
+		return nil, ErrInvalidOptions
+	}
+
+	index := make(map[rune]int, len(alphabet))
+	for i, r := range alphabet {
+		if _, dup := index[r]; dup || strings.ContainsRune(opts.Separator, r) {
+			return nil, ErrInvalidOptions
+		}
+		index[r] = i
+	}
+
+	return &Generator{opts: opts, index: index}, nil
+}
+
+// MustNew 与 New 相同，配置错误时 panic
+func MustNew(opts Options) *Generator {
+	g, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Generate 生成新的许可证密钥，prefix 为空时不带前缀
+func (g *Generator) Generate(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if !g.validPrefix(prefix) {
+		return "", ErrInvalidPrefix
+	}
+
+	alphabet := []rune(g.opts.Alphabet)
+	n := big.NewInt(int64(len(alphabet)))
+
+	body := make([]rune, g.opts.GroupSize*g.opts.Groups)
+	for i := 0; i < len(body)-1; i++ {
+		v, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		body[i] = alphabet[v.Int64()]
+	}
+	body[len(body)-1] = alphabet[g.checkDigit(body[:len(body)-1])]
+
+	groups := make([]string, 0, g.opts.Groups+1)
+	if prefix != "" {
+		groups = append(groups, prefix)
+	}
+	for i := 0; i < g.opts.Groups; i++ {
+		groups = append(groups, string(body[i*g.opts.GroupSize:(i+1)*g.opts.GroupSize]))
+	}
+
+	return strings.Join(groups, g.opts.Separator), nil
+}
+
+// Validate 校验密钥格式与校验位。
+// 不符合本格式的密钥（如旧版时间戳密钥）返回 ErrFormat，
+// 格式正确但校验位不符时返回 ErrChecksum。
+func (g *Generator) Validate(key string) error {
+	parts := strings.Split(strings.ToUpper(key), g.opts.Separator)
+	if len(parts) < g.opts.Groups {
+		return ErrFormat
+	}
+
+	prefix := strings.Join(parts[:len(parts)-g.opts.Groups], g.opts.Separator)
+	if !g.validPrefix(prefix) {
+		return ErrFormat
+	}
+
+	body := make([]rune, 0, g.opts.GroupSize*g.opts.Groups)
+	for _, part := range parts[len(parts)-g.opts.Groups:] {
+		runes := []rune(part)
+		if len(runes) != g.opts.GroupSize {
+			return ErrFormat
+		}
+		for _, r := range runes {
+			if _, ok := g.index[r]; !ok {
+				return ErrFormat
+			}
+		}
+		body = append(body, runes...)
+	}
+
+	if g.checkDigit(body[:len(body)-1]) != g.index[body[len(body)-1]] {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// ValidatePrefix 校验产品前缀是否可用于生成密钥
+func (g *Generator) ValidatePrefix(prefix string) error {
+	if !g.validPrefix(strings.ToUpper(strings.TrimSpace(prefix))) {
+		return ErrInvalidPrefix
+	}
+	return nil
+}
+
+// checkDigit 计算 Luhn mod N 校验位在字符集中的下标
+func (g *Generator) checkDigit(body []rune) int {
+	n := len(g.index)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * g.index[body[i]]
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+		sum += addend/n + addend%n
+	}
+	return (n - sum%n) % n
+}
+
+// validPrefix 前缀只允许大写字母和数字，可由分隔符连接多段
+func (g *Generator) validPrefix(prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	if len(prefix) > 32 {
+		return false
+	}
+	for _, part := range strings.Split(prefix, g.opts.Separator) {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Generate 使用默认生成器生成密钥
+func Generate(prefix string) (string, error) {
+	return Default.Generate(prefix)
+}
+
+// ValidatePrefix 使用默认生成器校验产品前缀
+func ValidatePrefix(prefix string) error {
+	return Default.ValidatePrefix(prefix)
+}
+
+// Validate 使用默认生成器校验密钥
+func Validate(key string) error {
+	return Default.Validate(key)
+}
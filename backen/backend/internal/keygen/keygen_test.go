@@ -0,0 +1,79 @@
+package keygen
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAndValidate(t *testing.T) {
+	pattern := regexp.MustCompile(`^([A-Z0-9]+-)?[2-9A-HJ-NP-Z]{5}(-[2-9A-HJ-NP-Z]{5}){3}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key, err := Generate("")
+		assert.NoError(t, err)
+		assert.Regexp(t, pattern, key)
+		assert.NoError(t, Validate(key))
+		assert.False(t, seen[key], "重复的密钥: %s", key)
+		seen[key] = true
+	}
+
+	key, err := Generate("gold")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^GOLD-`, key)
+	assert.NoError(t, Validate(key))
+
+	_, err = Generate("bad prefix!")
+	assert.Equal(t, ErrInvalidPrefix, err)
+}
+
+func TestValidateDetectsTypos(t *testing.T) {
+	key, _ := Generate("")
+
+	// 单个字符错误必须被校验位发现
+	alphabet := []rune(DefaultOptions.Alphabet)
+	runes := []rune(key)
+	for _, r := range alphabet {
+		if r == runes[0] {
+			continue
+		}
+		typo := append([]rune{r}, runes[1:]...)
+		assert.Equal(t, ErrChecksum, Validate(string(typo)))
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want error
+	}{
+		{name: "legacy_timestamp", key: "20250401000024", want: ErrFormat},
+		{name: "short_group", key: "ABCDE-FGHJK-LMNPQ-RST", want: ErrFormat},
+		{name: "ambiguous_char", key: "ABCDE-FGHJK-LMNPQ-RSTU0", want: ErrFormat},
+		{name: "empty", key: "", want: ErrFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Validate(tt.key))
+		})
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	_, err := New(Options{Alphabet: "AAB", GroupSize: 4, Groups: 4, Separator: "-"})
+	assert.Equal(t, ErrInvalidOptions, err)
+
+	_, err = New(Options{Alphabet: "AB-", GroupSize: 4, Groups: 4, Separator: "-"})
+	assert.Equal(t, ErrInvalidOptions, err)
+
+	g, err := New(Options{Alphabet: "0123456789", GroupSize: 4, Groups: 3, Separator: " "})
+	assert.NoError(t, err)
+	key, err := g.Generate("")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\d{4} \d{4} \d{4}$`, key)
+	assert.NoError(t, g.Validate(key))
+}
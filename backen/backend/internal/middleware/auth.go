@@ -3,6 +3,7 @@ package middleware
 import (
 	"license-management-system/internal/database"
 	"license-management-system/internal/model"
+	"license-management-system/internal/service"
 	"license-management-system/internal/util"
 	"strings"
 
@@ -34,8 +35,22 @@ func Auth() fiber.Handler {
 			})
 		}
 
-		// 将用户ID存储在上下文中
+		// 令牌签发后用户可能已被删除、停用或锁定
+		var user model.User
+		if err := database.DB.First(&user, userID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "用户不存在",
+			})
+		}
+		if !user.IsActive() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": service.UserStatusError(user.Status).Error(),
+			})
+		}
+
+		// 将用户ID和用户存储在上下文中
 		c.Locals("userID", userID)
+		c.Locals("user", &user)
 		return c.Next()
 	}
 }
@@ -43,12 +58,9 @@ func Auth() fiber.Handler {
 func AdminOnly() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
-		userID := c.Locals("userID").(uint)
-
-		// 从数据库获取用户信息并检查角色
-		var user model.User
-		result := database.DB.First(&user, userID)
-		if result.Error != nil || user.Role != "admin" {
+		// 用户信息由 Auth 加载
+		user, ok := c.Locals("user").(*model.User)
+		if !ok || user.Role != "admin" {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "需要管理员权限",
 			})
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"license-management-system/internal/service"
+	"license-management-system/pkg/licensing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientAuth 校验客户端程序使用产品 API 密钥签名的请求，
+// 通过后将密钥所属的产品存储在上下文的 clientProduct 中
+func ClientAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyID := c.Get(licensing.HeaderClientKey)
+		if keyID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "未提供 API 密钥",
+			})
+		}
+
+		key, err := service.VerifyClientRequest(service.ClientRequest{
+			KeyID:     keyID,
+			Timestamp: c.Get(licensing.HeaderClientTimestamp),
+			Nonce:     c.Get(licensing.HeaderClientNonce),
+			Signature: c.Get(licensing.HeaderClientSignature),
+			Method:    c.Method(),
+			URI:       c.OriginalURL(),
+			Body:      c.Body(),
+		})
+		switch {
+		case errors.Is(err, service.ErrClientKeyInvalid),
+			errors.Is(err, service.ErrSignatureInvalid),
+			errors.Is(err, service.ErrRequestExpired),
+			errors.Is(err, service.ErrNonceReused),
+			errors.Is(err, service.ErrNonceInvalid):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "校验请求签名失败",
+			})
+		}
+
+		// API 密钥只能访问所属产品的许可证
+		if productID := c.Query("productid"); productID != "" && productID != key.ProductId {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "API 密钥与产品不匹配",
+			})
+		}
+
+		c.Locals("clientProduct", key.ProductId)
+		return c.Next()
+	}
+}
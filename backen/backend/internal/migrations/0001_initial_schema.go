@@ -0,0 +1,221 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 以下结构是版本 1 时各表的快照，JSON 序列化的字段在数据库中均为文本列。
+// 已有数据库升级时执行 AutoMigrate 只会补充缺少的表和列，不会删除数据。
+
+type userV1 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Username  string `gorm:"unique;not null"`
+	Password  string `gorm:"not null"`
+	Email     string `gorm:"unique;not null"`
+	Role      string `gorm:"default:'user'"`
+	Status    string `gorm:"default:'active'"`
+	Company   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	LastLogin time.Time
+}
+
+func (userV1) TableName() string { return "users" }
+
+type licenseV1 struct {
+	gorm.Model
+	Key               string `gorm:"primaryKey;uniqueIndex;size:191"`
+	Status            string `gorm:"not null"`
+	ValidUntil        time.Time
+	IssuedTo          uint
+	Version           string
+	VersionConstraint string
+	MaxMajorVersion   int
+	Entitlements      string
+	UserId            string
+	ProductId         string
+	LastActivatedAt   time.Time
+	MaxSeats          int
+	MaxConcurrent     int
+	IsTrial           bool
+	AllowedAccounts   string
+	MaxAccounts       int
+	AllowedBrokers    string
+	AccountType       string
+}
+
+func (licenseV1) TableName() string { return "licenses" }
+
+type licenseActivationV1 struct {
+	gorm.Model
+	LicenseKey    string `gorm:"index;not null"`
+	Fingerprint   string `gorm:"index;not null"`
+	TerminalID    string
+	AccountNumber string
+	MachineHash   string
+	IPAddress     string
+	LastSeenAt    time.Time
+}
+
+func (licenseActivationV1) TableName() string { return "license_activations" }
+
+type licenseLeaseV1 struct {
+	ID         uint   `gorm:"primaryKey"`
+	LeaseID    string `gorm:"size:64;uniqueIndex;not null"`
+	LicenseKey string `gorm:"index;not null"`
+	ClientID   string `gorm:"index"`
+	IPAddress  string
+	ExpiresAt  time.Time `gorm:"index"`
+	RenewedAt  time.Time
+	CreatedAt  time.Time
+}
+
+func (licenseLeaseV1) TableName() string { return "license_leases" }
+
+type licenseUsageV1 struct {
+	gorm.Model
+	LicenseKey string `gorm:"index"`
+	Action     string
+	IPAddress  string
+	UserAgent  string
+	Account    string `gorm:"index"`
+	Broker     string
+	Server     string
+	Timestamp  time.Time
+}
+
+func (licenseUsageV1) TableName() string { return "license_usages" }
+
+type dailyLicenseUsageV1 struct {
+	ID          uint   `gorm:"primaryKey"`
+	Day         string `gorm:"size:10;uniqueIndex:idx_daily_license_usage_day_key;not null"`
+	LicenseKey  string `gorm:"size:191;uniqueIndex:idx_daily_license_usage_day_key;not null"`
+	Verifies    int64
+	Activations int64
+}
+
+func (dailyLicenseUsageV1) TableName() string { return "daily_license_usages" }
+
+type jobRunV1 struct {
+	ID         uint   `gorm:"primaryKey"`
+	JobName    string `gorm:"index;not null"`
+	Trigger    string
+	Status     string
+	Result     string
+	Error      string
+	StartedAt  time.Time `gorm:"index"`
+	FinishedAt time.Time
+	DurationMs int64
+}
+
+func (jobRunV1) TableName() string { return "job_runs" }
+
+type trialPolicyV1 struct {
+	ID               uint   `gorm:"primaryKey"`
+	ProductId        string `gorm:"size:64;uniqueIndex;not null"`
+	Enabled          bool
+	DurationDays     int
+	Entitlements     string
+	RepeatWindowDays int
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (trialPolicyV1) TableName() string { return "trial_policies" }
+
+type trialGrantV1 struct {
+	ID            uint   `gorm:"primaryKey"`
+	ProductId     string `gorm:"index;not null"`
+	LicenseKey    string
+	AccountNumber string    `gorm:"index"`
+	Fingerprint   string    `gorm:"index"`
+	Email         string    `gorm:"index"`
+	IPAddress     string    `gorm:"index"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+func (trialGrantV1) TableName() string { return "trial_grants" }
+
+type productV1 struct {
+	ID                  uint   `gorm:"primaryKey"`
+	Code                string `gorm:"size:64;uniqueIndex;not null"`
+	Name                string `gorm:"not null"`
+	Description         string
+	CurrentVersion      string
+	VersionConstraint   string
+	MaxMajorVersion     int
+	ValidityDays        int
+	EntitlementSchema   string
+	DefaultEntitlements string
+	DefaultMaxSeats     int
+	KeyPrefix           string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (productV1) TableName() string { return "products" }
+
+type productVersionV1 struct {
+	ID           uint   `gorm:"primaryKey"`
+	ProductCode  string `gorm:"size:64;uniqueIndex:idx_product_versions_code_version;not null"`
+	Version      string `gorm:"size:64;uniqueIndex:idx_product_versions_code_version;not null"`
+	ReleaseNotes string
+	ReleasedAt   time.Time
+}
+
+func (productVersionV1) TableName() string { return "product_versions" }
+
+type operationLogV1 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint
+	Action    string
+	Target    string
+	TargetID  string
+	Details   string
+	CreatedAt time.Time
+}
+
+func (operationLogV1) TableName() string { return "operation_logs" }
+
+type loginLogV1 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint
+	IP        string
+	UserAgent string
+	Status    string
+	CreatedAt time.Time
+}
+
+func (loginLogV1) TableName() string { return "login_logs" }
+
+// tablesV1 版本 1 的全部表
+var tablesV1 = []interface{}{
+	&userV1{},
+	&licenseV1{},
+	&licenseActivationV1{},
+	&licenseLeaseV1{},
+	&licenseUsageV1{},
+	&dailyLicenseUsageV1{},
+	&jobRunV1{},
+	&trialPolicyV1{},
+	&trialGrantV1{},
+	&productV1{},
+	&productVersionV1{},
+	&operationLogV1{},
+	&loginLogV1{},
+}
+
+// initialSchema 创建初始表结构。早期版本通过 AutoMigrate 建立的数据库
+// 执行该迁移时会补齐缺少的表，例如从未创建过的 license_usages
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(tablesV1...)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(tablesV1...)
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// legacyLicenseStatuses 旧版本写入的状态值与新状态的对应关系
+var legacyLicenseStatuses = map[string]string{
+	"":                  "inactive",
+	"未激活":               "inactive",
+	"activation_failed": "inactive",
+	"已激活":               "active",
+	"已暂停":               "suspended",
+	"已吊销":               "revoked",
+	"已过期":               "expired",
+}
+
+// knownLicenseStatuses 版本 2 支持的许可证状态
+var knownLicenseStatuses = []string{"inactive", "active", "suspended", "revoked", "expired"}
+
+// licenseStatuses 将旧版本的中文或非标准状态改写为统一的状态取值，
+// 旧状态无法还原，回滚时不做处理
+var licenseStatuses = Migration{
+	Version: 2,
+	Name:    "license_statuses",
+	Up: func(tx *gorm.DB) error {
+		for legacy, status := range legacyLicenseStatuses {
+			result := tx.Table("licenses").
+				Where("status = ?", legacy).
+				Update("status", status)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("许可证状态迁移: %q -> %q, %d 条", legacy, status, result.RowsAffected)
+			}
+		}
+
+		// 无法识别的状态保留原值，由管理员手动处理
+		var unknown int64
+		if err := tx.Table("licenses").
+			Where("status NOT IN ?", knownLicenseStatuses).
+			Count(&unknown).Error; err != nil {
+			return err
+		}
+		if unknown > 0 {
+			log.Printf("警告: 有 %d 条许可证的状态无法识别，请手动处理", unknown)
+		}
+		return nil
+	},
+	Down: noop,
+}
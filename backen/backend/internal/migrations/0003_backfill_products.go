@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// backfillProducts 为历史许可证中出现但不在产品目录里的产品代码创建产品记录，
+// 回滚时不删除产品，避免误删管理员后来补充的信息
+var backfillProducts = Migration{
+	Version: 3,
+	Name:    "backfill_products",
+	Up: func(tx *gorm.DB) error {
+		var codes []string
+		if err := tx.Table("licenses").
+			Where("product_id <> '' AND product_id NOT IN (?)", tx.Table("products").Select("code")).
+			Distinct().
+			Pluck("product_id", &codes).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, code := range codes {
+			if err := tx.Table("products").Create(map[string]interface{}{
+				"code":       code,
+				"name":       code,
+				"created_at": now,
+				"updated_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			log.Printf("已根据历史许可证创建产品: %s", code)
+		}
+		return nil
+	},
+	Down: noop,
+}
@@ -0,0 +1,153 @@
+package migrations
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// entitlementsV4 版本 4 时许可证权益的 JSON 格式，迁移只写入功能开关
+type entitlementsV4 struct {
+	Features []string `json:"features,omitempty"`
+}
+
+// entitlementSchemaV4 版本 4 时产品权益范围的 JSON 格式，
+// 需要包含全部字段，否则重新写入时会丢失产品已有的设置
+type entitlementSchemaV4 struct {
+	Features      []string `json:"features"`
+	MaxLotSize    float64  `json:"max_lot_size"`
+	MaxSymbols    int      `json:"max_symbols"`
+	MaxOpenTrades int      `json:"max_open_trades"`
+	Symbols       []string `json:"symbols"`
+	Timeframes    []string `json:"timeframes"`
+}
+
+// legacyPermissionColumns 保存旧版本权限字符串的列及转换后写入的列
+var legacyPermissionColumns = []struct {
+	table   string
+	product string // 产品代码所在的列
+	legacy  string
+	column  string
+}{
+	{"licenses", "product_id", "permissions", "entitlements"},
+	{"products", "code", "default_permissions", "default_entitlements"},
+	{"trial_policies", "product_id", "permissions", "entitlements"},
+}
+
+// permissionsToEntitlements 将旧版本的权限字符串转换为结构化权益。
+// 逗号分隔的权限逐项转换为功能开关，并加入所属产品的权益范围，
+// 转换后清空旧字段。旧字段不会删除，回滚时不做处理
+var permissionsToEntitlements = Migration{
+	Version: 4,
+	Name:    "permissions_to_entitlements",
+	Up: func(tx *gorm.DB) error {
+		// 产品代码 -> 历史数据中出现过的功能
+		features := make(map[string][]string)
+
+		for _, c := range legacyPermissionColumns {
+			if !tx.Migrator().HasColumn(c.table, c.legacy) {
+				continue
+			}
+
+			var rows []struct {
+				ID          uint
+				Product     string
+				Permissions string
+			}
+			if err := tx.Table(c.table).
+				Select("id, " + c.product + " AS product, " + c.legacy + " AS permissions").
+				Where(c.legacy + " <> ''").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				parsed := parsePermissionsV4(row.Permissions)
+				data, err := json.Marshal(entitlementsV4{Features: parsed})
+				if err != nil {
+					return err
+				}
+				if err := tx.Table(c.table).Where("id = ?", row.ID).Updates(map[string]interface{}{
+					c.column: string(data),
+					c.legacy: "",
+				}).Error; err != nil {
+					return err
+				}
+				features[row.Product] = append(features[row.Product], parsed...)
+			}
+			if len(rows) > 0 {
+				log.Printf("%s 权限迁移: %d 条", c.table, len(rows))
+			}
+		}
+
+		// 历史权限加入产品的权益范围，保证转换后的权益仍然生效
+		for code, list := range features {
+			if err := extendEntitlementSchema(tx, code, list); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: noop,
+}
+
+// extendEntitlementSchema 将功能加入产品的权益范围，产品不存在时忽略
+func extendEntitlementSchema(tx *gorm.DB, code string, features []string) error {
+	var rows []struct {
+		ID                uint
+		EntitlementSchema string
+	}
+	if err := tx.Table("products").
+		Select("id, entitlement_schema").
+		Where("code = ?", code).
+		Scan(&rows).Error; err != nil || len(rows) == 0 {
+		return err
+	}
+
+	var schema entitlementSchemaV4
+	if rows[0].EntitlementSchema != "" {
+		if err := json.Unmarshal([]byte(rows[0].EntitlementSchema), &schema); err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	for _, feature := range features {
+		if containsFoldV4(schema.Features, feature) {
+			continue
+		}
+		schema.Features = append(schema.Features, feature)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	log.Printf("产品 %s 的权益范围已加入历史权限: %v", code, schema.Features)
+	return tx.Table("products").Where("id = ?", rows[0].ID).Update("entitlement_schema", string(data)).Error
+}
+
+// parsePermissionsV4 将逗号分隔的权限字符串转换为功能开关列表
+func parsePermissionsV4(permissions string) []string {
+	var features []string
+	for _, p := range strings.Split(permissions, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			features = append(features, p)
+		}
+	}
+	return features
+}
+
+func containsFoldV4(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
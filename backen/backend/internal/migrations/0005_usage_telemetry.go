@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 5 的使用记录和登录日志增加客户端的国家、设备和操作系统
+
+type licenseUsageV5 struct {
+	gorm.Model
+	LicenseKey string `gorm:"index"`
+	Action     string
+	IPAddress  string
+	UserAgent  string
+	Country    string
+	Device     string
+	Platform   string
+	Account    string `gorm:"index"`
+	Broker     string
+	Server     string
+	Timestamp  time.Time `gorm:"index"`
+}
+
+func (licenseUsageV5) TableName() string { return "license_usages" }
+
+type loginLogV5 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint
+	IP        string
+	UserAgent string
+	Country   string
+	Device    string
+	Platform  string
+	Status    string
+	CreatedAt time.Time
+}
+
+func (loginLogV5) TableName() string { return "login_logs" }
+
+type usageSessionV5 struct {
+	ID              uint   `gorm:"primaryKey"`
+	LeaseID         string `gorm:"size:64;uniqueIndex;not null"`
+	LicenseKey      string `gorm:"index;not null"`
+	ClientID        string
+	IPAddress       string
+	Country         string
+	Device          string
+	Platform        string
+	StartedAt       time.Time `gorm:"index"`
+	LastSeenAt      time.Time
+	EndedAt         time.Time
+	Heartbeats      int
+	DurationSeconds int64
+}
+
+func (usageSessionV5) TableName() string { return "usage_sessions" }
+
+// telemetryColumns 版本 5 新增的客户端信息列
+var telemetryColumns = []string{"Country", "Device", "Platform"}
+
+// usageTelemetry 增加客户端信息列和浮动许可证的使用会话表，
+// 历史记录的客户端信息为空，统计时归为 unknown
+var usageTelemetry = Migration{
+	Version: 5,
+	Name:    "usage_telemetry",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&licenseUsageV5{}, &loginLogV5{}, &usageSessionV5{})
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&usageSessionV5{}); err != nil {
+			return err
+		}
+		if m.HasIndex(&licenseUsageV5{}, "Timestamp") {
+			if err := m.DropIndex(&licenseUsageV5{}, "Timestamp"); err != nil {
+				return err
+			}
+		}
+		for _, table := range []interface{}{&licenseUsageV5{}, &loginLogV5{}} {
+			for _, column := range telemetryColumns {
+				if err := m.DropColumn(table, column); err != nil {
+					return err
+				}
+			}
+		}
+		// SQLite 删除列时会重建表，需要补回原有的索引
+		return tx.AutoMigrate(&licenseUsageV1{}, &loginLogV1{})
+	},
+}
@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 6 的使用记录增加结果，每日汇总增加产品、失败次数和交易账号数，
+// 并增加按产品的每日汇总表
+
+type licenseUsageV6 struct {
+	gorm.Model
+	LicenseKey string `gorm:"index"`
+	Action     string
+	Reason     string
+	IPAddress  string
+	UserAgent  string
+	Country    string
+	Device     string
+	Platform   string
+	Account    string `gorm:"index"`
+	Broker     string
+	Server     string
+	Timestamp  time.Time `gorm:"index"`
+}
+
+func (licenseUsageV6) TableName() string { return "license_usages" }
+
+type dailyLicenseUsageV6 struct {
+	ID                 uint   `gorm:"primaryKey"`
+	Day                string `gorm:"size:10;uniqueIndex:idx_daily_license_usage_day_key;not null"`
+	LicenseKey         string `gorm:"size:191;uniqueIndex:idx_daily_license_usage_day_key;not null"`
+	ProductId          string `gorm:"size:64;index"`
+	Verifies           int64
+	Activations        int64
+	VerifyFailures     int64
+	ActivationFailures int64
+	Accounts           int64
+}
+
+func (dailyLicenseUsageV6) TableName() string { return "daily_license_usages" }
+
+type dailyProductUsageV6 struct {
+	ID                 uint   `gorm:"primaryKey"`
+	Day                string `gorm:"size:10;uniqueIndex:idx_daily_product_usage_day_product;not null"`
+	ProductId          string `gorm:"size:64;uniqueIndex:idx_daily_product_usage_day_product;not null"`
+	Licenses           int64
+	Verifies           int64
+	Activations        int64
+	VerifyFailures     int64
+	ActivationFailures int64
+	Accounts           int64
+	Sessions           int64
+	SessionSeconds     int64
+}
+
+func (dailyProductUsageV6) TableName() string { return "daily_product_usages" }
+
+type dailyUsageDimensionV6 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Day       string `gorm:"size:10;uniqueIndex:idx_daily_usage_dimension;not null"`
+	ProductId string `gorm:"size:64;uniqueIndex:idx_daily_usage_dimension;not null"`
+	Dimension string `gorm:"size:16;uniqueIndex:idx_daily_usage_dimension;not null"`
+	Value     string `gorm:"size:64;uniqueIndex:idx_daily_usage_dimension;not null"`
+	Count     int64
+}
+
+func (dailyUsageDimensionV6) TableName() string { return "daily_usage_dimensions" }
+
+type rollupCheckpointV6 struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	LastID    uint
+	LastRunAt time.Time
+}
+
+func (rollupCheckpointV6) TableName() string { return "rollup_checkpoints" }
+
+// rollupColumnsV6 版本 6 在每日许可证汇总中新增的列
+var rollupColumnsV6 = []string{"ProductId", "VerifyFailures", "ActivationFailures", "Accounts"}
+
+// usageRollups 增加汇总表。已有的每日汇总没有产品信息，
+// 汇总进度为空时定时任务会根据保留的使用记录重新计算全部日期
+var usageRollups = Migration{
+	Version: 6,
+	Name:    "usage_rollups",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&licenseUsageV6{},
+			&dailyLicenseUsageV6{},
+			&dailyProductUsageV6{},
+			&dailyUsageDimensionV6{},
+			&rollupCheckpointV6{},
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&dailyProductUsageV6{}, &dailyUsageDimensionV6{}, &rollupCheckpointV6{}); err != nil {
+			return err
+		}
+		if m.HasIndex(&dailyLicenseUsageV6{}, "ProductId") {
+			if err := m.DropIndex(&dailyLicenseUsageV6{}, "ProductId"); err != nil {
+				return err
+			}
+		}
+		for _, column := range rollupColumnsV6 {
+			if err := m.DropColumn(&dailyLicenseUsageV6{}, column); err != nil {
+				return err
+			}
+		}
+		if err := m.DropColumn(&licenseUsageV6{}, "Reason"); err != nil {
+			return err
+		}
+		// SQLite 删除列时会重建表，需要补回原有的索引
+		return tx.AutoMigrate(&licenseUsageV5{}, &dailyLicenseUsageV1{})
+	},
+}
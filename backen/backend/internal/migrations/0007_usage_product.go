@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 版本 7 的使用记录增加产品，用于按产品查询失败的校验和激活
+
+type licenseUsageV7 struct {
+	gorm.Model
+	LicenseKey string `gorm:"index"`
+	ProductId  string `gorm:"size:64;index"`
+	Action     string
+	Reason     string
+	IPAddress  string
+	UserAgent  string
+	Country    string
+	Device     string
+	Platform   string
+	Account    string `gorm:"index"`
+	Broker     string
+	Server     string
+	Timestamp  time.Time `gorm:"index"`
+}
+
+func (licenseUsageV7) TableName() string { return "license_usages" }
+
+// usageProduct 增加产品列，历史记录按许可证当前所属的产品补齐
+var usageProduct = Migration{
+	Version: 7,
+	Name:    "usage_product",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&licenseUsageV7{}); err != nil {
+			return err
+		}
+		// key 是 MySQL 的保留字，由方言负责加引号
+		productOf := tx.Table("licenses").
+			Select("product_id").
+			Where(clause.Eq{
+				Column: clause.Column{Table: "licenses", Name: "key"},
+				Value:  clause.Column{Table: "license_usages", Name: "license_key"},
+			}).
+			Limit(1)
+		return tx.Table("license_usages").
+			Where("product_id IS NULL OR product_id = ''").
+			Update("product_id", gorm.Expr("COALESCE((?), '')", productOf)).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if m.HasIndex(&licenseUsageV7{}, "ProductId") {
+			if err := m.DropIndex(&licenseUsageV7{}, "ProductId"); err != nil {
+				return err
+			}
+		}
+		if err := m.DropColumn(&licenseUsageV7{}, "ProductId"); err != nil {
+			return err
+		}
+		// SQLite 删除列时会重建表，需要补回原有的索引
+		return tx.AutoMigrate(&licenseUsageV6{})
+	},
+}
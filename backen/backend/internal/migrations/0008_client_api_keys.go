@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 8 增加客户端程序的 API 密钥和请求随机数
+
+type clientAPIKeyV8 struct {
+	ID         uint   `gorm:"primaryKey"`
+	KeyID      string `gorm:"size:64;uniqueIndex;not null"`
+	ProductId  string `gorm:"size:64;index;not null"`
+	Name       string
+	Secret     string `gorm:"size:128;not null"`
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+func (clientAPIKeyV8) TableName() string { return "client_api_keys" }
+
+type clientNonceV8 struct {
+	ID        uint      `gorm:"primaryKey"`
+	KeyID     string    `gorm:"size:64;uniqueIndex:idx_client_nonce;not null"`
+	Nonce     string    `gorm:"size:64;uniqueIndex:idx_client_nonce;not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (clientNonceV8) TableName() string { return "client_nonces" }
+
+// clientAPIKeys 增加客户端 API 密钥表和已使用的请求随机数表
+var clientAPIKeys = Migration{
+	Version: 8,
+	Name:    "client_api_keys",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&clientAPIKeyV8{}, &clientNonceV8{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&clientAPIKeyV8{}, &clientNonceV8{})
+	},
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 9 增加产品的离线凭证有效期和许可证最近一次签发离线凭证的时间
+
+type productV9 struct {
+	OfflineGraceHours int
+}
+
+func (productV9) TableName() string { return "products" }
+
+type licenseV9 struct {
+	TokenRenewedAt time.Time
+}
+
+func (licenseV9) TableName() string { return "licenses" }
+
+// offlineTokens 增加离线凭证相关的列，已有产品使用默认有效期
+var offlineTokens = Migration{
+	Version: 9,
+	Name:    "offline_tokens",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.AddColumn(&productV9{}, "OfflineGraceHours"); err != nil {
+			return err
+		}
+		return m.AddColumn(&licenseV9{}, "TokenRenewedAt")
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropColumn(&productV9{}, "OfflineGraceHours"); err != nil {
+			return err
+		}
+		if err := m.DropColumn(&licenseV9{}, "TokenRenewedAt"); err != nil {
+			return err
+		}
+		// SQLite 删除列时会重建表，需要补回原有的索引
+		return tx.AutoMigrate(&productV1{}, &licenseV1{})
+	},
+}
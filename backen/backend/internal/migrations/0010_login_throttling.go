@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 版本 10 的登录日志记录失败原因和登录时填写的用户名，并增加登录失败统计表
+
+type loginLogV10 struct {
+	Username string
+	Reason   string
+}
+
+func (loginLogV10) TableName() string { return "login_logs" }
+
+type loginThrottleV10 struct {
+	ID           uint   `gorm:"primaryKey"`
+	Scope        string `gorm:"size:16;uniqueIndex:idx_login_throttle;not null"`
+	Subject      string `gorm:"size:191;uniqueIndex:idx_login_throttle;not null"`
+	Failures     int
+	LastFailedAt time.Time `gorm:"index"`
+	LockedUntil  time.Time
+}
+
+func (loginThrottleV10) TableName() string { return "login_throttles" }
+
+// loginThrottling 增加登录失败记录所需的列和表。此前只记录成功的登录，
+// 已有日志的原因补为 ok；状态为空的用户补为 active，避免启用状态校验后无法登录
+var loginThrottling = Migration{
+	Version: 10,
+	Name:    "login_throttling",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.AddColumn(&loginLogV10{}, "Username"); err != nil {
+			return err
+		}
+		if err := m.AddColumn(&loginLogV10{}, "Reason"); err != nil {
+			return err
+		}
+		if err := tx.Table("login_logs").
+			Where("status = ?", "success").
+			Update("reason", "ok").Error; err != nil {
+			return err
+		}
+		if err := tx.Table("users").
+			Where("status IS NULL OR status = ?", "").
+			Update("status", "active").Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(&loginThrottleV10{})
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&loginThrottleV10{}); err != nil {
+			return err
+		}
+		if err := m.DropColumn(&loginLogV10{}, "Username"); err != nil {
+			return err
+		}
+		return m.DropColumn(&loginLogV10{}, "Reason")
+	},
+}
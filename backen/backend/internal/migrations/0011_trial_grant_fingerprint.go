@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// 版本 11 为试用发放记录增加产品和设备指纹的唯一索引
+
+type trialGrantV11 struct {
+	ID          uint   `gorm:"primaryKey"`
+	ProductId   string `gorm:"index;not null;uniqueIndex:idx_trial_grant_fingerprint"`
+	Fingerprint string `gorm:"index;uniqueIndex:idx_trial_grant_fingerprint"`
+}
+
+func (trialGrantV11) TableName() string { return "trial_grants" }
+
+// trialGrantFingerprint 同一设备对同一产品只保留一条试用记录，由数据库拒绝并发的重复申请。
+// 建索引前删除重复记录，每个产品和设备只保留最新的一条
+var trialGrantFingerprint = Migration{
+	Version: 11,
+	Name:    "trial_grant_fingerprint",
+	Up: func(tx *gorm.DB) error {
+		// MySQL 不能在子查询中直接引用正在删除的表，需要包一层派生表
+		if err := tx.Exec("DELETE FROM trial_grants WHERE id NOT IN " +
+			"(SELECT id FROM (SELECT MAX(id) AS id FROM trial_grants GROUP BY product_id, fingerprint) AS latest)").Error; err != nil {
+			return err
+		}
+		return tx.Migrator().CreateIndex(&trialGrantV11{}, "idx_trial_grant_fingerprint")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropIndex(&trialGrantV11{}, "idx_trial_grant_fingerprint")
+	},
+}
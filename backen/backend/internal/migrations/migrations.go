@@ -0,0 +1,196 @@
+// Package migrations 管理数据库结构的版本化迁移。
+//
+// 每个迁移有递增的版本号以及对应的升级、回滚操作，已执行的版本记录在
+// schema_version 表中。迁移只能使用本包内的结构快照或表名操作数据，
+// 不能依赖 model 包的当前定义，否则模型变更后旧迁移的行为会随之改变。
+// 新增或修改模型字段时，需要在 registry 末尾追加新的迁移。
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次数据库迁移
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaVersion schema_version 表中的一条记录，表示已执行的迁移
+type SchemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:191;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+var (
+	ErrSchemaOutdated = errors.New("数据库结构版本低于程序版本")
+	ErrSchemaTooNew   = errors.New("数据库结构版本高于程序版本")
+)
+
+// registry 全部迁移，按版本号递增排列
+var registry = []Migration{
+	initialSchema,
+	licenseStatuses,
+	backfillProducts,
+	permissionsToEntitlements,
+	usageTelemetry,
+	usageRollups,
+	usageProduct,
+	clientAPIKeys,
+	offlineTokens,
+	loginThrottling,
+	trialGrantFingerprint,
+}
+
+// Latest 返回程序所需的数据库结构版本
+func Latest() int {
+	if len(registry) == 0 {
+		return 0
+	}
+	return registry[len(registry)-1].Version
+}
+
+// Current 返回数据库当前的结构版本，尚未执行过迁移时返回 0
+func Current(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&SchemaVersion{}) {
+		return 0, nil
+	}
+
+	var version int
+	if err := db.Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Check 检查数据库结构版本是否与程序一致
+func Check(db *gorm.DB) error {
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	switch latest := Latest(); {
+	case current < latest:
+		return fmt.Errorf("%w: 当前 %d，需要 %d", ErrSchemaOutdated, current, latest)
+	case current > latest:
+		return fmt.Errorf("%w: 当前 %d，程序支持 %d", ErrSchemaTooNew, current, latest)
+	}
+	return nil
+}
+
+// Up 按顺序执行全部未执行的迁移，返回本次执行的迁移
+func Up(db *gorm.DB) ([]Migration, error) {
+	if err := db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range registry {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down 按版本从高到低回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(registry) - 1; i >= 0 && len(done) < steps; i-- {
+		m := registry[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaVersion{}, m.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("回滚 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// List 返回全部迁移及其执行状态
+func List(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(registry))
+	for _, m := range registry {
+		s := Status{Version: m.Version, Name: m.Name}
+		if v, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &v.AppliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// appliedVersions 读取已执行的迁移
+func appliedVersions(db *gorm.DB) (map[int]SchemaVersion, error) {
+	applied := make(map[int]SchemaVersion)
+	if !db.Migrator().HasTable(&SchemaVersion{}) {
+		return applied, nil
+	}
+
+	var rows []SchemaVersion
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// noop 不需要回滚的数据迁移使用的空操作
+func noop(*gorm.DB) error {
+	return nil
+}
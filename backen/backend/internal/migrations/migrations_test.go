@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"license-management-system/internal/model"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接相互独立
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestUpDown(t *testing.T) {
+	db := openTestDB(t)
+
+	assert.ErrorIs(t, Check(db), ErrSchemaOutdated)
+
+	done, err := Up(db)
+	assert.NoError(t, err)
+	assert.Len(t, done, len(registry))
+	assert.NoError(t, Check(db))
+
+	// 重复执行不会再次迁移
+	done, err = Up(db)
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+
+	statuses, err := List(db)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Name)
+	}
+
+	done, err = Down(db, 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	current, err := Current(db)
+	assert.NoError(t, err)
+	assert.Equal(t, Latest()-1, current)
+
+	_, err = Down(db, len(registry))
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasTable("licenses"))
+	current, err = Current(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, current)
+
+	// 数据库版本高于程序
+	assert.NoError(t, db.Create(&SchemaVersion{Version: Latest() + 1, Name: "future", AppliedAt: time.Now()}).Error)
+	assert.ErrorIs(t, Check(db), ErrSchemaTooNew)
+}
+
+// 迁移后的表结构必须包含模型的全部字段，修改模型后忘记追加迁移时该测试失败
+func TestSchemaMatchesModels(t *testing.T) {
+	db := openTestDB(t)
+	_, err := Up(db)
+	assert.NoError(t, err)
+
+	models := []interface{}{
+		&model.User{},
+		&model.License{},
+		&model.LicenseActivation{},
+		&model.LicenseLease{},
+		&model.LicenseUsage{},
+		&model.DailyLicenseUsage{},
+		&model.JobRun{},
+		&model.TrialPolicy{},
+		&model.TrialGrant{},
+		&model.Product{},
+		&model.ProductVersion{},
+		&model.OperationLog{},
+		&model.LoginLog{},
+		&model.UsageSession{},
+		&model.DailyProductUsage{},
+		&model.DailyUsageDimension{},
+		&model.RollupCheckpoint{},
+		&model.ClientAPIKey{},
+		&model.ClientNonce{},
+		&model.LoginThrottle{},
+	}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(m))
+		if !assert.True(t, db.Migrator().HasTable(stmt.Schema.Table), stmt.Schema.Table) {
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(stmt.Schema.Table, field.DBName), stmt.Schema.Table+"."+field.DBName)
+		}
+	}
+}
+
+// 早期版本通过 AutoMigrate 建立的数据库升级后，历史数据被转换为当前格式
+func TestUpLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	type legacyLicense struct {
+		gorm.Model
+		Key         string
+		Status      string
+		ProductId   string
+		Permissions string
+	}
+	assert.NoError(t, db.Table("licenses").AutoMigrate(&legacyLicense{}))
+	assert.NoError(t, db.Table("licenses").Create(&legacyLicense{
+		Key:         "LEGACY-1",
+		Status:      "已激活",
+		ProductId:   "gold",
+		Permissions: "export, backtest",
+	}).Error)
+	assert.NoError(t, db.AutoMigrate(&licenseUsageV1{}))
+	assert.NoError(t, db.Create(&licenseUsageV1{LicenseKey: "LEGACY-1", Action: "verify"}).Error)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+
+	var license model.License
+	assert.NoError(t, db.Where("id = ?", 1).First(&license).Error)
+	assert.Equal(t, model.LicenseActive, license.Status)
+	assert.Equal(t, []string{"export", "backtest"}, license.Entitlements.Features)
+
+	var product model.Product
+	assert.NoError(t, db.Where("code = ?", "gold").First(&product).Error)
+	assert.ElementsMatch(t, []string{"export", "backtest"}, product.EntitlementSchema.Features)
+	assert.NoError(t, product.EntitlementSchema.Validate(license.Entitlements))
+
+	var usage model.LicenseUsage
+	assert.NoError(t, db.Where("license_key = ?", "LEGACY-1").First(&usage).Error)
+	assert.Equal(t, "gold", usage.ProductId)
+}
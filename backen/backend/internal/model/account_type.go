@@ -0,0 +1,33 @@
+package model
+
+import "strings"
+
+// AccountType 交易账户类型限制
+type AccountType string
+
+const (
+	AccountTypeAny  AccountType = "any"
+	AccountTypeDemo AccountType = "demo"
+	AccountTypeLive AccountType = "live"
+)
+
+// ParseAccountType 解析客户端上报的账户类型，MT5 的 real 视为 live，contest 视为 demo
+func ParseAccountType(s string) (AccountType, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any":
+		return AccountTypeAny, true
+	case "demo", "contest":
+		return AccountTypeDemo, true
+	case "live", "real":
+		return AccountTypeLive, true
+	}
+	return "", false
+}
+
+// Allows 限制是否允许指定类型的账户，未设置限制时允许所有账户
+func (t AccountType) Allows(actual AccountType) bool {
+	if t == "" || t == AccountTypeAny {
+		return true
+	}
+	return t == actual
+}
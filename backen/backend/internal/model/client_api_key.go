@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ClientAPIKey 客户端程序（EA）调用 /api/v1/client 接口的产品级 API 密钥。
+// 请求使用 Secret 做 HMAC 签名；轮换后旧密钥在 ExpiresAt 之前仍然有效
+type ClientAPIKey struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	KeyID      string    `json:"key_id" gorm:"size:64;uniqueIndex;not null"`
+	ProductId  string    `json:"productid" gorm:"size:64;index;not null"`
+	Name       string    `json:"name"`
+	Secret     string    `json:"-" gorm:"size:128;not null"`
+	ExpiresAt  time.Time `json:"expires_at"` // 零值表示长期有效
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsActive 密钥在指定时间是否可用
+func (k *ClientAPIKey) IsActive(now time.Time) bool {
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// ClientNonce 已使用过的请求随机数，用于拒绝时间窗口内的重放请求
+type ClientNonce struct {
+	ID        uint      `gorm:"primaryKey"`
+	KeyID     string    `gorm:"size:64;uniqueIndex:idx_client_nonce;not null"`
+	Nonce     string    `gorm:"size:64;uniqueIndex:idx_client_nonce;not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
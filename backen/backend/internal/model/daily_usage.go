@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// DailyLicenseUsage 按许可证汇总的每日使用量，由定时任务根据 LicenseUsage 计算
+type DailyLicenseUsage struct {
+	ID                 uint   `json:"-" gorm:"primaryKey"`
+	Day                string `json:"day" gorm:"size:10;uniqueIndex:idx_daily_license_usage_day_key;not null"` // YYYY-MM-DD
+	LicenseKey         string `json:"license_key" gorm:"size:191;uniqueIndex:idx_daily_license_usage_day_key;not null"`
+	ProductId          string `json:"productid" gorm:"size:64;index"`
+	Verifies           int64  `json:"verifies"`
+	Activations        int64  `json:"activations"`
+	VerifyFailures     int64  `json:"verify_failures"`
+	ActivationFailures int64  `json:"activation_failures"`
+	Accounts           int64  `json:"accounts"` // 使用过的不同交易账号数
+}
+
+// DailyProductUsage 按产品汇总的每日使用量，与 DailyLicenseUsage 同时计算
+type DailyProductUsage struct {
+	ID                 uint   `json:"-" gorm:"primaryKey"`
+	Day                string `json:"day" gorm:"size:10;uniqueIndex:idx_daily_product_usage_day_product;not null"`
+	ProductId          string `json:"productid" gorm:"size:64;uniqueIndex:idx_daily_product_usage_day_product;not null"`
+	Licenses           int64  `json:"licenses"` // 有使用记录的许可证数
+	Verifies           int64  `json:"verifies"`
+	Activations        int64  `json:"activations"`
+	VerifyFailures     int64  `json:"verify_failures"`
+	ActivationFailures int64  `json:"activation_failures"`
+	Accounts           int64  `json:"accounts"`
+	Sessions           int64  `json:"sessions"`        // 当天开始的浮动许可证会话数
+	SessionSeconds     int64  `json:"session_seconds"` // 这些会话的总时长
+}
+
+// DailyUsageDimension 按产品汇总的每日使用次数在国家、设备或操作系统上的分布
+type DailyUsageDimension struct {
+	ID        uint   `json:"-" gorm:"primaryKey"`
+	Day       string `json:"day" gorm:"size:10;uniqueIndex:idx_daily_usage_dimension;not null"`
+	ProductId string `json:"productid" gorm:"size:64;uniqueIndex:idx_daily_usage_dimension;not null"`
+	Dimension string `json:"dimension" gorm:"size:16;uniqueIndex:idx_daily_usage_dimension;not null"` // country、device 或 platform
+	Value     string `json:"value" gorm:"size:64;uniqueIndex:idx_daily_usage_dimension;not null"`
+	Count     int64  `json:"count"`
+}
+
+// RollupCheckpoint 增量汇总的进度，记录已汇总的最后一条使用记录和上次汇总的时间
+type RollupCheckpoint struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:64"`
+	LastID    uint      `json:"last_id"`
+	LastRunAt time.Time `json:"last_run_at"`
+}
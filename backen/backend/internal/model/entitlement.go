@@ -0,0 +1,176 @@
+package model
+
+import (
+	"fmt"
+	"license-management-system/pkg/licensing"
+	"strings"
+)
+
+// Timeframes MetaTrader 支持的图表周期
+var Timeframes = []string{
+	"M1", "M2", "M3", "M4", "M5", "M6", "M10", "M12", "M15", "M20", "M30",
+	"H1", "H2", "H3", "H4", "H6", "H8", "H12", "D1", "W1", "MN1",
+}
+
+// EntitlementSchema 产品的权益范围，许可证的权益不能超出该范围。
+// 数值上限为 0、列表为空表示不限制。
+type EntitlementSchema struct {
+	Features      []string `json:"features"`        // 产品支持的功能开关
+	MaxLotSize    float64  `json:"max_lot_size"`    // 单笔手数上限
+	MaxSymbols    int      `json:"max_symbols"`     // 品种数上限
+	MaxOpenTrades int      `json:"max_open_trades"` // 持仓单数上限
+	Symbols       []string `json:"symbols"`         // 可选品种
+	Timeframes    []string `json:"timeframes"`      // 可选周期
+}
+
+// EntitlementError 权益超出产品范围
+type EntitlementError struct {
+	Field   string
+	Message string
+}
+
+func (e *EntitlementError) Error() string {
+	return fmt.Sprintf("权益 %s 无效: %s", e.Field, e.Message)
+}
+
+// Validate 校验权益是否在产品范围内
+func (s EntitlementSchema) Validate(e licensing.Entitlements) error {
+	for _, feature := range e.Features {
+		if len(s.Features) == 0 || !containsFold(s.Features, feature) {
+			return &EntitlementError{Field: "features", Message: "产品不支持功能 " + feature}
+		}
+	}
+
+	if e.MaxLotSize < 0 || (s.MaxLotSize > 0 && e.MaxLotSize > s.MaxLotSize) {
+		return &EntitlementError{Field: "max_lot_size", Message: fmt.Sprintf("须在 0 到 %g 之间", s.MaxLotSize)}
+	}
+	if e.MaxSymbols < 0 || (s.MaxSymbols > 0 && e.MaxSymbols > s.MaxSymbols) {
+		return &EntitlementError{Field: "max_symbols", Message: fmt.Sprintf("须在 0 到 %d 之间", s.MaxSymbols)}
+	}
+	if e.MaxOpenTrades < 0 || (s.MaxOpenTrades > 0 && e.MaxOpenTrades > s.MaxOpenTrades) {
+		return &EntitlementError{Field: "max_open_trades", Message: fmt.Sprintf("须在 0 到 %d 之间", s.MaxOpenTrades)}
+	}
+
+	for _, symbol := range e.Symbols {
+		if len(s.Symbols) > 0 && !containsFold(s.Symbols, symbol) {
+			return &EntitlementError{Field: "symbols", Message: "产品不支持品种 " + symbol}
+		}
+	}
+	for _, tf := range e.Timeframes {
+		if !containsFold(Timeframes, tf) || (len(s.Timeframes) > 0 && !containsFold(s.Timeframes, tf)) {
+			return &EntitlementError{Field: "timeframes", Message: "不支持的周期 " + tf}
+		}
+	}
+
+	return nil
+}
+
+// ValidateSchema 校验产品权益范围本身是否合法
+func (s EntitlementSchema) ValidateSchema() error {
+	if s.MaxLotSize < 0 || s.MaxSymbols < 0 || s.MaxOpenTrades < 0 {
+		return &EntitlementError{Field: "schema", Message: "数值上限不能为负数"}
+	}
+	for _, tf := range s.Timeframes {
+		if !containsFold(Timeframes, tf) {
+			return &EntitlementError{Field: "schema.timeframes", Message: "不支持的周期 " + tf}
+		}
+	}
+	return nil
+}
+
+// Resolve 合并产品默认权益与许可证单独设置的权益，并裁剪到产品范围内。
+// 许可证未设置的字段使用产品默认值。
+func (s EntitlementSchema) Resolve(defaults, overrides licensing.Entitlements) licensing.Entitlements {
+	resolved := defaults
+	if overrides.Features != nil {
+		resolved.Features = overrides.Features
+	}
+	if overrides.MaxLotSize != 0 {
+		resolved.MaxLotSize = overrides.MaxLotSize
+	}
+	if overrides.MaxSymbols != 0 {
+		resolved.MaxSymbols = overrides.MaxSymbols
+	}
+	if overrides.MaxOpenTrades != 0 {
+		resolved.MaxOpenTrades = overrides.MaxOpenTrades
+	}
+	if overrides.Symbols != nil {
+		resolved.Symbols = overrides.Symbols
+	}
+	if overrides.Timeframes != nil {
+		resolved.Timeframes = overrides.Timeframes
+	}
+
+	// 产品范围调整后，已发放的权益按新范围裁剪
+	resolved.Features = intersectFold(resolved.Features, s.Features, false)
+	resolved.Symbols = restrictFold(resolved.Symbols, s.Symbols)
+	resolved.Timeframes = restrictFold(resolved.Timeframes, s.Timeframes)
+	resolved.MaxLotSize = capFloat(resolved.MaxLotSize, s.MaxLotSize)
+	resolved.MaxSymbols = capInt(resolved.MaxSymbols, s.MaxSymbols)
+	resolved.MaxOpenTrades = capInt(resolved.MaxOpenTrades, s.MaxOpenTrades)
+
+	return resolved
+}
+
+// ParsePermissions 将旧版逗号分隔的权限字符串转换为功能开关列表
+func ParsePermissions(permissions string) []string {
+	var features []string
+	for _, p := range strings.Split(permissions, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			features = append(features, p)
+		}
+	}
+	return features
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectFold 保留 list 中属于 allowed 的项，allowed 为空时按 emptyAllowsAll 决定是否全部保留
+func intersectFold(list, allowed []string, emptyAllowsAll bool) []string {
+	if len(allowed) == 0 {
+		if emptyAllowsAll {
+			return list
+		}
+		return nil
+	}
+	var result []string
+	for _, item := range list {
+		if containsFold(allowed, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// restrictFold 将列表限制裁剪到产品范围。未限制时使用产品范围；
+// 原有的限制全部不在产品范围内时返回 licensing.NoneAllowed，不放宽为产品范围
+func restrictFold(list, allowed []string) []string {
+	if len(list) == 0 {
+		return allowed
+	}
+	if result := intersectFold(list, allowed, true); len(result) > 0 {
+		return result
+	}
+	return []string{licensing.NoneAllowed}
+}
+
+func capFloat(v, max float64) float64 {
+	if max > 0 && (v == 0 || v > max) {
+		return max
+	}
+	return v
+}
+
+func capInt(v, max int) int {
+	if max > 0 && (v == 0 || v > max) {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"license-management-system/pkg/licensing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntitlementSchemaValidate(t *testing.T) {
+	schema := EntitlementSchema{
+		Features:   []string{"news_filter", "trailing_stop"},
+		MaxLotSize: 5,
+		MaxSymbols: 3,
+		Symbols:    []string{"XAUUSD", "EURUSD"},
+		Timeframes: []string{"M5", "H1"},
+	}
+
+	tests := []struct {
+		name    string
+		input   licensing.Entitlements
+		wantErr bool
+	}{
+		{"empty", licensing.Entitlements{}, false},
+		{"within_schema", licensing.Entitlements{Features: []string{"NEWS_FILTER"}, MaxLotSize: 2, Symbols: []string{"xauusd"}}, false},
+		{"unknown_feature", licensing.Entitlements{Features: []string{"grid"}}, true},
+		{"lot_size_exceeds", licensing.Entitlements{MaxLotSize: 5.5}, true},
+		{"negative_limit", licensing.Entitlements{MaxOpenTrades: -1}, true},
+		{"symbol_not_allowed", licensing.Entitlements{Symbols: []string{"GBPUSD"}}, true},
+		{"timeframe_not_allowed", licensing.Entitlements{Timeframes: []string{"D1"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(tt.input)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+
+	assert.Error(t, EntitlementSchema{Timeframes: []string{"M7"}}.ValidateSchema())
+}
+
+func TestEntitlementSchemaResolve(t *testing.T) {
+	schema := EntitlementSchema{
+		Features:   []string{"news_filter", "trailing_stop"},
+		MaxLotSize: 5,
+		Symbols:    []string{"XAUUSD", "EURUSD"},
+	}
+	defaults := licensing.Entitlements{Features: []string{"news_filter"}, MaxLotSize: 1}
+
+	// 未单独设置时使用产品默认值，数值上限取产品范围
+	got := schema.Resolve(defaults, licensing.Entitlements{MaxOpenTrades: 10})
+	assert.Equal(t, []string{"news_filter"}, got.Features)
+	assert.Equal(t, 1.0, got.MaxLotSize)
+	assert.Equal(t, 10, got.MaxOpenTrades)
+	assert.Equal(t, []string{"XAUUSD", "EURUSD"}, got.Symbols)
+
+	// 产品范围缩小后，已发放的权益被裁剪
+	got = schema.Resolve(defaults, licensing.Entitlements{Features: []string{"grid", "trailing_stop"}, MaxLotSize: 8})
+	assert.Equal(t, []string{"trailing_stop"}, got.Features)
+	assert.Equal(t, 5.0, got.MaxLotSize)
+
+	// 限制的品种全部从产品范围移除后不允许任何品种，不能放宽为产品的全部品种
+	got = schema.Resolve(defaults, licensing.Entitlements{Symbols: []string{"GBPUSD"}})
+	assert.Equal(t, []string{licensing.NoneAllowed}, got.Symbols)
+	assert.False(t, got.AllowsSymbol("XAUUSD"))
+	assert.False(t, got.AllowsSymbol("GBPUSD"))
+
+	// 品种和周期同样处理
+	narrowed := EntitlementSchema{Symbols: []string{"XAUUSD"}, Timeframes: []string{"H1"}}
+	got = narrowed.Resolve(licensing.Entitlements{Timeframes: []string{"M5"}}, licensing.Entitlements{Symbols: []string{"EURUSD"}})
+	assert.Equal(t, []string{licensing.NoneAllowed}, got.Symbols)
+	assert.Equal(t, []string{licensing.NoneAllowed}, got.Timeframes)
+	assert.False(t, got.AllowsTimeframe("H1"))
+}
+
+func TestParsePermissions(t *testing.T) {
+	assert.Equal(t, []string{"full", "news"}, ParsePermissions(" full, ,news "))
+	assert.Nil(t, ParsePermissions(""))
+}
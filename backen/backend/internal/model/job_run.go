@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunFailed  = "failed"
+
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// JobRun 定时任务执行记录
+type JobRun struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	JobName    string    `json:"job_name" gorm:"index;not null"`
+	Trigger    string    `json:"trigger"` // schedule, manual
+	Status     string    `json:"status"`  // running, success, failed
+	Result     string    `json:"result"`
+	Error      string    `json:"error"`
+	StartedAt  time.Time `json:"started_at" gorm:"index"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"license-management-system/pkg/licensing"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,15 +9,25 @@ import (
 
 type License struct {
 	gorm.Model
-	Key             string    `json:"key" gorm:"primaryKey"`
-	Status          string    `json:"status" gorm:"not null"`
-	ValidUntil      time.Time `json:"valid_until"`
-	IssuedTo        uint      `json:"issued_to"`
-	Version         string    `json:"version"`
-	Permissions     string    `json:"permissions"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	UserId          string    `json:"userid"`
-	ProductId       string    `json:"productid"`
-	LastActivatedAt time.Time `json:"last_activated_at"`
+	Key               string                 `json:"key" gorm:"primaryKey;uniqueIndex;size:191"`
+	Status            LicenseStatus          `json:"status" gorm:"not null"`
+	ValidUntil        time.Time              `json:"valid_until"`
+	IssuedTo          uint                   `json:"issued_to"`
+	Version           string                 `json:"version"`
+	VersionConstraint string                 `json:"version_constraint"`                  // 允许的客户端版本范围（semver），为空时使用产品设置
+	MaxMajorVersion   int                    `json:"max_major_version"`                   // 允许的最大主版本号，0 表示使用产品设置
+	Entitlements      licensing.Entitlements `json:"entitlements" gorm:"serializer:json"` // 单独设置的权益，未设置的字段使用产品默认值
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	UserId            string                 `json:"userid"`
+	ProductId         string                 `json:"productid"`
+	LastActivatedAt   time.Time              `json:"last_activated_at"`
+	MaxSeats          int                    `json:"max_seats"`      // 可同时激活的设备数，0 表示不限制；激活过设备后只允许已激活的设备校验
+	MaxConcurrent     int                    `json:"max_concurrent"` // 浮动许可证的并发实例数，0 表示不限制
+	IsTrial           bool                   `json:"is_trial"`
+	AllowedAccounts   []string               `json:"allowed_accounts" gorm:"serializer:json"` // 允许使用的交易账号
+	MaxAccounts       int                    `json:"max_accounts"`                            // 账号数上限，未满时自动绑定新账号；为 0 且列表为空时不限制账号
+	AllowedBrokers    []string               `json:"allowed_brokers" gorm:"serializer:json"`  // 允许的经纪商或服务器名称（通配符），为空表示不限制
+	AccountType       AccountType            `json:"account_type"`                            // 账户类型限制，为空或 any 表示不限制
+	TokenRenewedAt    time.Time              `json:"token_renewed_at"`                        // 最近一次续发离线凭证的时间
 }
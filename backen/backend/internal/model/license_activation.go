@@ -0,0 +1,44 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LicenseActivation 许可证激活记录，每条记录占用一个席位
+type LicenseActivation struct {
+	gorm.Model
+	LicenseKey    string    `json:"license_key" gorm:"index;not null"`
+	Fingerprint   string    `json:"fingerprint" gorm:"index;not null"`
+	TerminalID    string    `json:"terminal_id"`
+	AccountNumber string    `json:"account_number"`
+	MachineHash   string    `json:"machine_hash"`
+	IPAddress     string    `json:"ip_address"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+}
+
+// Fingerprint 客户端设备指纹
+type Fingerprint struct {
+	TerminalID    string `json:"terminal_id" query:"terminal_id"`
+	AccountNumber string `json:"account_number" query:"account"`
+	MachineHash   string `json:"machine_hash" query:"machine_hash"`
+}
+
+// IsComplete 终端ID和MT账号为必填项，机器哈希可选
+func (f Fingerprint) IsComplete() bool {
+	return strings.TrimSpace(f.TerminalID) != "" && strings.TrimSpace(f.AccountNumber) != ""
+}
+
+// Hash 计算指纹摘要，用于比较两个指纹是否属于同一设备
+func (f Fingerprint) Hash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.TrimSpace(f.TerminalID),
+		strings.TrimSpace(f.AccountNumber),
+		strings.ToLower(strings.TrimSpace(f.MachineHash)),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
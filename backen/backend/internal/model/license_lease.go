@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// LicenseLease 浮动许可证租约，客户端通过心跳续期，过期后自动回收
+type LicenseLease struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	LeaseID    string    `json:"lease_id" gorm:"size:64;uniqueIndex;not null"`
+	LicenseKey string    `json:"license_key" gorm:"index;not null"`
+	ClientID   string    `json:"client_id" gorm:"index"`
+	IPAddress  string    `json:"ip_address"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,81 @@
+package model
+
+// LicenseStatus 许可证状态，数据库中只保存英文取值，展示文本通过 Label 获取
+type LicenseStatus string
+
+const (
+	LicenseInactive  LicenseStatus = "inactive"  // 已生成，尚未激活
+	LicenseActive    LicenseStatus = "active"    // 已激活
+	LicenseSuspended LicenseStatus = "suspended" // 已暂停，可恢复
+	LicenseRevoked   LicenseStatus = "revoked"   // 已吊销，终态
+	LicenseExpired   LicenseStatus = "expired"   // 已过期，续期后可重新激活
+)
+
+// LicenseStatuses 所有合法的许可证状态
+var LicenseStatuses = []LicenseStatus{
+	LicenseInactive,
+	LicenseActive,
+	LicenseSuspended,
+	LicenseRevoked,
+	LicenseExpired,
+}
+
+// licenseTransitions 允许的状态变更
+var licenseTransitions = map[LicenseStatus][]LicenseStatus{
+	LicenseInactive:  {LicenseActive, LicenseRevoked, LicenseExpired},
+	LicenseActive:    {LicenseSuspended, LicenseRevoked, LicenseExpired},
+	LicenseSuspended: {LicenseActive, LicenseRevoked, LicenseExpired},
+	LicenseExpired:   {LicenseActive, LicenseRevoked},
+	LicenseRevoked:   {},
+}
+
+// licenseStatusLabels 各语言的状态展示文本
+var licenseStatusLabels = map[string]map[LicenseStatus]string{
+	"zh": {
+		LicenseInactive:  "未激活",
+		LicenseActive:    "已激活",
+		LicenseSuspended: "已暂停",
+		LicenseRevoked:   "已吊销",
+		LicenseExpired:   "已过期",
+	},
+	"en": {
+		LicenseInactive:  "Inactive",
+		LicenseActive:    "Active",
+		LicenseSuspended: "Suspended",
+		LicenseRevoked:   "Revoked",
+		LicenseExpired:   "Expired",
+	},
+}
+
+// IsValid 是否为合法的状态取值
+func (s LicenseStatus) IsValid() bool {
+	_, ok := licenseTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 是否允许变更为目标状态
+func (s LicenseStatus) CanTransitionTo(to LicenseStatus) bool {
+	for _, next := range licenseTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitions 返回允许变更到的状态
+func (s LicenseStatus) Transitions() []LicenseStatus {
+	return licenseTransitions[s]
+}
+
+// Label 返回指定语言的展示文本，未知语言使用中文
+func (s LicenseStatus) Label(lang string) string {
+	labels, ok := licenseStatusLabels[lang]
+	if !ok {
+		labels = licenseStatusLabels["zh"]
+	}
+	if label, ok := labels[s]; ok {
+		return label
+	}
+	return string(s)
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLicenseStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from LicenseStatus
+		to   LicenseStatus
+		want bool
+	}{
+		{LicenseInactive, LicenseActive, true},
+		{LicenseActive, LicenseSuspended, true},
+		{LicenseActive, LicenseRevoked, true},
+		{LicenseActive, LicenseExpired, true},
+		{LicenseSuspended, LicenseActive, true},
+		{LicenseExpired, LicenseActive, true},
+		{LicenseActive, LicenseInactive, false},
+		{LicenseRevoked, LicenseActive, false},
+		{LicenseInactive, LicenseSuspended, false},
+		{LicenseStatus("已激活"), LicenseActive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
+
+func TestLicenseStatusLabel(t *testing.T) {
+	assert.Equal(t, "已吊销", LicenseRevoked.Label("zh"))
+	assert.Equal(t, "Revoked", LicenseRevoked.Label("en"))
+	assert.Equal(t, "已吊销", LicenseRevoked.Label("fr"))
+	assert.False(t, LicenseStatus("已激活").IsValid())
+}
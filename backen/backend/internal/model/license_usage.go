@@ -9,8 +9,16 @@ import (
 type LicenseUsage struct {
 	gorm.Model
 	LicenseKey string    `json:"license_key" gorm:"index"`
-	Action     string    `json:"action"` // "verify", "activate", etc.
+	ProductId  string    `json:"productid" gorm:"size:64;index"` // 许可证不存在时为客户端请求的产品
+	Action     string    `json:"action"`                         // "verify", "activate", etc.
+	Reason     string    `json:"reason"`                         // 校验或激活的结果，ok 表示成功
 	IPAddress  string    `json:"ip_address"`
 	UserAgent  string    `json:"user_agent"`
-	Timestamp  time.Time `json:"timestamp"`
+	Country    string    `json:"country"`              // 根据 IP 解析的国家代码，无法识别时为空
+	Device     string    `json:"device"`               // desktop、mobile 或 tablet
+	Platform   string    `json:"platform"`             // 操作系统，优先使用客户端上报的平台
+	Account    string    `json:"account" gorm:"index"` // 客户端交易账号
+	Broker     string    `json:"broker"`
+	Server     string    `json:"server"`
+	Timestamp  time.Time `json:"timestamp" gorm:"index"`
 }
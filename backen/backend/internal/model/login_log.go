@@ -4,9 +4,24 @@ import "time"
 
 type LoginLog struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id"`
+	UserID    uint      `json:"user_id"`  // 用户不存在时为 0
+	Username  string    `json:"username"` // 登录时填写的用户名
 	IP        string    `json:"ip"`
 	UserAgent string    `json:"user_agent"`
+	Country   string    `json:"country"`
+	Device    string    `json:"device"`
+	Platform  string    `json:"platform"`
 	Status    string    `json:"status"` // success, failed
+	Reason    string    `json:"reason"` // 登录结果，ok 表示成功
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// LoginThrottle 按用户名或 IP 统计的连续登录失败次数，用于逐步延迟和临时锁定
+type LoginThrottle struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Scope        string    `json:"scope" gorm:"size:16;uniqueIndex:idx_login_throttle;not null"` // username 或 ip
+	Subject      string    `json:"subject" gorm:"size:191;uniqueIndex:idx_login_throttle;not null"`
+	Failures     int       `json:"failures"`
+	LastFailedAt time.Time `json:"last_failed_at" gorm:"index"`
+	LockedUntil  time.Time `json:"locked_until"` // 零值表示未锁定
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"license-management-system/pkg/licensing"
+	"time"
+)
+
+// Product 产品（交易策略/EA），License.ProductId 对应 Product.Code
+type Product struct {
+	ID                  uint                   `json:"id" gorm:"primaryKey"`
+	Code                string                 `json:"code" gorm:"size:64;uniqueIndex;not null"`
+	Name                string                 `json:"name" gorm:"not null"`
+	Description         string                 `json:"description"`
+	CurrentVersion      string                 `json:"current_version"`
+	VersionConstraint   string                 `json:"version_constraint"`                          // 许可证允许的客户端版本范围（semver）
+	MaxMajorVersion     int                    `json:"max_major_version"`                           // 许可证允许的最大主版本号，0 表示以许可证签发时的版本为准
+	ValidityDays        int                    `json:"validity_days"`                               // 生成许可证时的默认有效天数
+	EntitlementSchema   EntitlementSchema      `json:"entitlement_schema" gorm:"serializer:json"`   // 许可证可设置的权益范围
+	DefaultEntitlements licensing.Entitlements `json:"default_entitlements" gorm:"serializer:json"` // 许可证未单独设置时使用的权益
+	DefaultMaxSeats     int                    `json:"default_max_seats"`                           // 生成许可证时的默认席位数
+	KeyPrefix           string                 `json:"key_prefix"`                                  // 生成许可证密钥时的默认前缀
+	OfflineGraceHours   int                    `json:"offline_grace_hours"`                         // 离线凭证的有效小时数，0 表示使用默认值
+	TrialPolicy         *TrialPolicy           `json:"trial_policy,omitempty" gorm:"-"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+}
+
+// ProductVersion 产品发布的版本
+type ProductVersion struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ProductCode  string    `json:"product_code" gorm:"size:64;uniqueIndex:idx_product_versions_code_version;not null"`
+	Version      string    `json:"version" gorm:"size:64;uniqueIndex:idx_product_versions_code_version;not null"`
+	ReleaseNotes string    `json:"release_notes"`
+	ReleasedAt   time.Time `json:"released_at"`
+}
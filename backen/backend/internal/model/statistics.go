@@ -2,16 +2,18 @@ package model
 
 import "time"
 
-// DailyUsage 每日使用统计
+// DailyUsage 每日使用统计，按周或按月统计时 Date 为该周的周一或该月的第一天
 type DailyUsage struct {
 	Date           time.Time `json:"date"`
 	ActiveUsers    int       `json:"active_users"`
 	NewActivations int       `json:"new_activations"`
 	TotalChecks    int       `json:"total_checks"`
+	Failures       int       `json:"failures"` // 失败的校验和激活次数
 }
 
 // LicenseStatistics 许可证统计信息
 type LicenseStatistics struct {
+	Granularity          string         `json:"granularity"` // DailyUsage 的粒度: day、week 或 month
 	TotalLicenses        int64          `json:"total_licenses"`
 	ActiveLicenses       int64          `json:"active_licenses"`
 	ExpiredLicenses      int64          `json:"expired_licenses"`
@@ -22,7 +24,9 @@ type LicenseStatistics struct {
 	DailyUsage           []DailyUsage   `json:"daily_usage"`
 	UsageByCountry       map[string]int `json:"usage_by_country"`
 	UsageByDevice        map[string]int `json:"usage_by_device"`
-	AverageUsageDuration float64        `json:"average_usage_duration"`
+	UsageByPlatform      map[string]int `json:"usage_by_platform"`
+	TotalSessions        int64          `json:"total_sessions"`
+	AverageUsageDuration float64        `json:"average_usage_duration"` // 浮动许可证会话的平均时长，单位小时
 	TotalActivations     int64          `json:"total_activations"`
 	FailedActivations    int64          `json:"failed_activations"`
 }
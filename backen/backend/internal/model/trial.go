@@ -0,0 +1,30 @@
+package model
+
+import (
+	"license-management-system/pkg/licensing"
+	"time"
+)
+
+// TrialPolicy 产品的试用策略
+type TrialPolicy struct {
+	ID               uint                   `json:"id" gorm:"primaryKey"`
+	ProductId        string                 `json:"productid" gorm:"size:64;uniqueIndex;not null"`
+	Enabled          bool                   `json:"enabled"`
+	DurationDays     int                    `json:"duration_days"`                       // 试用天数
+	Entitlements     licensing.Entitlements `json:"entitlements" gorm:"serializer:json"` // 试用版的权益，未设置的字段使用产品默认值
+	RepeatWindowDays int                    `json:"repeat_window_days"`                  // 同一账号、设备、邮箱或IP再次申请试用的间隔天数
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+}
+
+// TrialGrant 试用发放记录，用于防止重复申请
+type TrialGrant struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ProductId     string    `json:"productid" gorm:"index;not null;uniqueIndex:idx_trial_grant_fingerprint"`
+	LicenseKey    string    `json:"license_key"`
+	AccountNumber string    `json:"account_number" gorm:"index"`
+	Fingerprint   string    `json:"fingerprint" gorm:"index;uniqueIndex:idx_trial_grant_fingerprint"` // 同一设备对每个产品只保留一条记录
+	Email         string    `json:"email" gorm:"index"`
+	IPAddress     string    `json:"ip_address" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
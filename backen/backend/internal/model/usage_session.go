@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// UsageSession 浮动许可证的一次使用会话，签出租约时开始，每次心跳更新时长，
+// 释放租约时结束；租约过期未释放的会话以最后一次心跳为结束时间
+type UsageSession struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	LeaseID         string    `json:"lease_id" gorm:"size:64;uniqueIndex;not null"`
+	LicenseKey      string    `json:"license_key" gorm:"index;not null"`
+	ClientID        string    `json:"client_id"`
+	IPAddress       string    `json:"ip_address"`
+	Country         string    `json:"country"`
+	Device          string    `json:"device"`
+	Platform        string    `json:"platform"`
+	StartedAt       time.Time `json:"started_at" gorm:"index"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	EndedAt         time.Time `json:"ended_at"` // 租约释放或过期回收的时间，未结束时为零值
+	Heartbeats      int       `json:"heartbeats"`
+	DurationSeconds int64     `json:"duration_seconds"`
+}
@@ -4,6 +4,16 @@ import (
 	"time"
 )
 
+// 用户状态，只有 active 的用户可以登录和使用已签发的令牌
+const (
+	UserActive   = "active"
+	UserInactive = "inactive" // 已停用
+	UserLocked   = "locked"   // 已被管理员锁定
+)
+
+// UserStatuses 所有合法的用户状态
+var UserStatuses = []string{UserActive, UserInactive, UserLocked}
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Username  string    `json:"username" gorm:"unique;not null"`
@@ -16,3 +26,8 @@ type User struct {
 	UpdatedAt time.Time `json:"updatedat"`
 	LastLogin time.Time `json:"lastlogin"`
 }
+
+// IsActive 用户是否可以登录
+func (u *User) IsActive() bool {
+	return u.Status == UserActive
+}
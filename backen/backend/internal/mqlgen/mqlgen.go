@@ -0,0 +1,155 @@
+// Package mqlgen 为产品生成 MQL4/MQL5 客户端 include 文件。
+//
+// 生成的文件包含服务地址、产品、公钥和 API 密钥，以及请求签名、响应签名校验、
+// 重试和离线凭证逻辑，EA 引入后调用 LicenseCheck 即可。
+package mqlgen
+
+import (
+	_ "embed"
+	"errors"
+	"io"
+	"license-management-system/pkg/licensing"
+	"license-management-system/pkg/licensing/mql"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 生成文件的默认参数
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 2 * time.Second
+	DefaultTimeout    = 5 * time.Second
+	MaxRetries        = 10
+)
+
+var (
+	ErrInvalidServerURL = errors.New("服务地址须为 http 或 https 开头的完整地址，且不能包含路径")
+	ErrInvalidProduct   = errors.New("产品不能为空")
+	ErrInvalidPublicKey = errors.New("无效的签名公钥")
+	ErrInvalidRetries   = errors.New("重试次数须在 0 到 10 之间")
+)
+
+//go:embed client.mqh.tmpl
+var clientSource string
+
+var clientTemplate = template.Must(template.New("client.mqh").Funcs(template.FuncMap{
+	"mqlstring": mqlString,
+	"comment":   comment,
+}).Parse(clientSource))
+
+// Config 生成 include 文件的参数，数值为 0 时使用默认值
+type Config struct {
+	ServerURL   string // 服务地址，例如 https://license.example.com
+	ProductId   string
+	ProductName string
+	PublicKey   string // base64 编码的 Ed25519 公钥
+	KeyID       string // 客户端 API 密钥，为空时需要在生成的文件中填写
+	Secret      string
+	Retries     int // 网络错误时的重试次数，负数表示不重试
+	RetryDelay  time.Duration
+	Timeout     time.Duration
+}
+
+// templateData 模板使用的数据
+type templateData struct {
+	Config
+	FileName     string
+	TokenFile    string
+	Origin       string
+	APIPath      string
+	Verifier     string
+	RetryDelayMs int64
+	TimeoutMs    int64
+}
+
+// Generate 按配置生成 include 文件的源码
+func Generate(w io.Writer, cfg Config) error {
+	data, err := prepare(cfg)
+	if err != nil {
+		return err
+	}
+	return clientTemplate.Execute(w, data)
+}
+
+// FileName 产品 include 文件的文件名
+func FileName(productID string) string {
+	return "License_" + unsafeFileChars.ReplaceAllString(productID, "_") + ".mqh"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// prepare 校验配置并填充默认值
+func prepare(cfg Config) (*templateData, error) {
+	u, err := url.Parse(strings.TrimSpace(cfg.ServerURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		// 服务端按收到的路径校验请求签名，经过改写路径的反向代理时签名无法通过
+		return nil, ErrInvalidServerURL
+	}
+	if strings.TrimSpace(cfg.ProductId) == "" {
+		return nil, ErrInvalidProduct
+	}
+	if _, err := licensing.ParsePublicKey(cfg.PublicKey); err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	switch {
+	case cfg.Retries == 0:
+		cfg.Retries = DefaultRetries
+	case cfg.Retries < 0:
+		cfg.Retries = 0
+	case cfg.Retries > MaxRetries:
+		return nil, ErrInvalidRetries
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRetryDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ProductName == "" {
+		cfg.ProductName = cfg.ProductId
+	}
+
+	return &templateData{
+		Config:       cfg,
+		FileName:     FileName(cfg.ProductId),
+		TokenFile:    strings.TrimSuffix(FileName(cfg.ProductId), ".mqh") + ".token",
+		Origin:       u.Scheme + "://" + u.Host,
+		APIPath:      strings.TrimRight(u.EscapedPath(), "/") + "/api/v1/client",
+		Verifier:     mql.LicenseVerify,
+		RetryDelayMs: cfg.RetryDelay.Milliseconds(),
+		TimeoutMs:    cfg.Timeout.Milliseconds(),
+	}, nil
+}
+
+// mqlString 转换为 MQL 字符串字面量
+func mqlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// comment 用于单行注释的文本，去掉换行
+func comment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
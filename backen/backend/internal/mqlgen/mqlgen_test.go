@@ -0,0 +1,113 @@
+package mqlgen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 生成的源码变化时，使用 go test ./internal/mqlgen -update 更新 testdata 中的文件
+var update = flag.Bool("update", false, "更新 golden 文件")
+
+// testPublicKey 固定的公钥，保证生成结果稳定
+const testPublicKey = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+
+func TestGenerateGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{
+			name: "with_key",
+			cfg: Config{
+				ServerURL:   "https://license.example.com",
+				ProductId:   "gold",
+				ProductName: "Gold Scalper",
+				PublicKey:   testPublicKey,
+				KeyID:       "ck_0123456789abcdef01234567",
+				Secret:      "5f0c8d7a2b9e4f61a3c5d7e9f1b3a5c7d9e1f3a5b7c9d1e3f5a7b9c1d3e5f7a9",
+			},
+		},
+		{
+			name: "without_key",
+			cfg: Config{
+				ServerURL: "http://127.0.0.1:8080/",
+				ProductId: "silver",
+				PublicKey: testPublicKey,
+				Retries:   -1,
+			},
+		},
+		{
+			// 需要转义的名称和密钥
+			name: "escaping",
+			cfg: Config{
+				ServerURL:   "https://example.com:8443",
+				ProductId:   "fx.trend/v2",
+				ProductName: "Trend \"Pro\"\nEA",
+				PublicKey:   testPublicKey,
+				KeyID:       "ck_fedcba9876543210fedcba98",
+				Secret:      "a\\b\"c",
+				Retries:     5,
+				RetryDelay:  500 * time.Millisecond,
+				Timeout:     10 * time.Second,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, Generate(&buf, tt.cfg))
+
+			path := filepath.Join("testdata", tt.name+".mqh.golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+			}
+			want, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestGenerateInvalidConfig(t *testing.T) {
+	valid := Config{ServerURL: "https://license.example.com", ProductId: "gold", PublicKey: testPublicKey}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr error
+	}{
+		{name: "valid", modify: func(cfg *Config) {}},
+		{name: "no_scheme", modify: func(cfg *Config) { cfg.ServerURL = "license.example.com" }, wantErr: ErrInvalidServerURL},
+		{name: "ftp", modify: func(cfg *Config) { cfg.ServerURL = "ftp://license.example.com" }, wantErr: ErrInvalidServerURL},
+		{name: "path", modify: func(cfg *Config) { cfg.ServerURL = "https://example.com/license" }, wantErr: ErrInvalidServerURL},
+		{name: "query", modify: func(cfg *Config) { cfg.ServerURL = "https://license.example.com/?a=1" }, wantErr: ErrInvalidServerURL},
+		{name: "no_product", modify: func(cfg *Config) { cfg.ProductId = " " }, wantErr: ErrInvalidProduct},
+		{name: "bad_public_key", modify: func(cfg *Config) { cfg.PublicKey = "not-a-key" }, wantErr: ErrInvalidPublicKey},
+		{name: "too_many_retries", modify: func(cfg *Config) { cfg.Retries = MaxRetries + 1 }, wantErr: ErrInvalidRetries},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			var buf bytes.Buffer
+			err := Generate(&buf, cfg)
+			assert.Equal(t, tt.wantErr, err)
+			if err != nil {
+				assert.Zero(t, buf.Len())
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "License_gold.mqh", FileName("gold"))
+	assert.Equal(t, "License_fx_trend_v2.mqh", FileName("fx.trend/v2"))
+}
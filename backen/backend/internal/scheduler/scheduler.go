@@ -0,0 +1,202 @@
+// Package scheduler 进程内定时任务调度，按 cron 表达式执行已注册的任务并记录每次执行结果。
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrJobExists   = errors.New("任务已存在")
+	ErrJobNotFound = errors.New("任务不存在")
+	ErrJobRunning  = errors.New("任务正在执行")
+)
+
+// JobFunc 任务函数，返回的字符串作为执行结果摘要记录
+type JobFunc func() (string, error)
+
+// Job 定时任务定义
+type Job struct {
+	Name        string
+	Spec        string // 标准五段式 cron 表达式，也支持 @every 1m、@daily 等写法
+	Description string
+	Run         JobFunc
+}
+
+// JobInfo 任务的当前调度信息
+type JobInfo struct {
+	Name        string    `json:"name"`
+	Spec        string    `json:"spec"`
+	Description string    `json:"description"`
+	Running     bool      `json:"running"`
+	NextRun     time.Time `json:"next_run"`
+	PrevRun     time.Time `json:"prev_run"`
+}
+
+type entry struct {
+	Job
+	id      cron.EntryID
+	running int32
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	cron  *cron.Cron
+	mu    sync.RWMutex
+	jobs  map[string]*entry
+	names []string
+}
+
+// Default 全局调度器
+var Default = New()
+
+// New 创建调度器
+func New() *Scheduler {
+	return &Scheduler{
+		cron: cron.New(),
+		jobs: make(map[string]*entry),
+	}
+}
+
+// Register 注册任务，cron 表达式无效时返回错误
+func (s *Scheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+
+	e := &entry{Job: job}
+	id, err := s.cron.AddFunc(job.Spec, func() {
+		s.run(e, model.JobTriggerSchedule)
+	})
+	if err != nil {
+		return fmt.Errorf("任务 %s 的 cron 表达式无效: %w", job.Name, err)
+	}
+	e.id = id
+
+	s.jobs[job.Name] = e
+	s.names = append(s.names, job.Name)
+	return nil
+}
+
+// Start 开始调度
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop 停止调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Jobs 返回所有已注册任务的调度信息
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]JobInfo, 0, len(s.names))
+	for _, name := range s.names {
+		e := s.jobs[name]
+		cronEntry := s.cron.Entry(e.id)
+		infos = append(infos, JobInfo{
+			Name:        e.Name,
+			Spec:        e.Spec,
+			Description: e.Description,
+			Running:     atomic.LoadInt32(&e.running) == 1,
+			NextRun:     cronEntry.Next,
+			PrevRun:     cronEntry.Prev,
+		})
+	}
+	return infos
+}
+
+// RunNow 立即执行任务并等待结果
+func (s *Scheduler) RunNow(name string) (*model.JobRun, error) {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return s.run(e, model.JobTriggerManual)
+}
+
+// run 执行任务并记录结果，同一任务不会并发执行
+func (s *Scheduler) run(e *entry, trigger string) (*model.JobRun, error) {
+	if !atomic.CompareAndSwapInt32(&e.running, 0, 1) {
+		return nil, ErrJobRunning
+	}
+	defer atomic.StoreInt32(&e.running, 0)
+
+	run := &model.JobRun{
+		JobName:   e.Name,
+		Trigger:   trigger,
+		Status:    model.JobRunRunning,
+		StartedAt: time.Now(),
+	}
+	if err := database.DB.Create(run).Error; err != nil {
+		log.Printf("记录任务 %s 执行失败: %v", e.Name, err)
+	}
+
+	result, err := safeRun(e.Run)
+
+	run.FinishedAt = time.Now()
+	run.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Result = result
+	if err != nil {
+		run.Status = model.JobRunFailed
+		run.Error = err.Error()
+		log.Printf("任务 %s 执行失败: %v", e.Name, err)
+	} else {
+		run.Status = model.JobRunSuccess
+	}
+
+	if err := database.DB.Save(run).Error; err != nil {
+		log.Printf("记录任务 %s 执行结果失败: %v", e.Name, err)
+	}
+
+	return run, nil
+}
+
+// safeRun 执行任务函数，任务 panic 时转换为错误，避免影响调度器
+func safeRun(fn JobFunc) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务 panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// GetJobRuns 分页获取任务的执行记录，name 为空时返回所有任务
+func GetJobRuns(name string, page, pageSize int) ([]model.JobRun, int64, error) {
+	var runs []model.JobRun
+	var total int64
+
+	db := database.DB.Model(&model.JobRun{})
+	if name != "" {
+		db = db.Where("job_name = ?", name)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("started_at DESC").Offset(offset).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
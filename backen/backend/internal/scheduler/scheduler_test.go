@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerRunNow(t *testing.T) {
+	database.InitTestDB()
+	defer database.CleanTestDB()
+
+	s := New()
+	assert.NoError(t, s.Register(Job{
+		Name: "ok",
+		Spec: "*/5 * * * *",
+		Run:  func() (string, error) { return "done", nil },
+	}))
+	assert.NoError(t, s.Register(Job{
+		Name: "fail",
+		Spec: "@every 1h",
+		Run:  func() (string, error) { return "", errors.New("boom") },
+	}))
+	assert.NoError(t, s.Register(Job{
+		Name: "panic",
+		Spec: "@daily",
+		Run:  func() (string, error) { panic("unexpected") },
+	}))
+
+	assert.ErrorIs(t, s.Register(Job{Name: "ok", Spec: "* * * * *"}), ErrJobExists)
+	assert.Error(t, s.Register(Job{Name: "bad", Spec: "not a cron"}))
+
+	run, err := s.RunNow("ok")
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobRunSuccess, run.Status)
+	assert.Equal(t, "done", run.Result)
+	assert.Equal(t, model.JobTriggerManual, run.Trigger)
+
+	run, err = s.RunNow("fail")
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobRunFailed, run.Status)
+	assert.Equal(t, "boom", run.Error)
+
+	run, err = s.RunNow("panic")
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobRunFailed, run.Status)
+
+	_, err = s.RunNow("missing")
+	assert.Equal(t, ErrJobNotFound, err)
+
+	runs, total, err := GetJobRuns("ok", 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, runs, 1)
+
+	assert.Len(t, s.Jobs(), 3)
+}
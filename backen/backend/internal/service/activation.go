@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSeatLimitReached   = errors.New("许可证席位已满")
+	ErrActivationNotFound = errors.New("设备未激活")
+)
+
+// ActivateSeat 为设备占用一个席位，同一设备重复激活不会重复占用
+func ActivateSeat(license *model.License, fp model.Fingerprint, ip string) (*model.LicenseActivation, error) {
+	activation := &model.LicenseActivation{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return activateSeat(tx, license, fp, ip, activation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return activation, nil
+}
+
+// ActivateSeatAndBind 在同一事务中占用席位并绑定交易账号，绑定被拒绝时不占用席位
+func ActivateSeatAndBind(license *model.License, fp model.Fingerprint, ip string, ctx TradingContext) (*model.LicenseActivation, VerifyReason, error) {
+	activation := &model.LicenseActivation{}
+	reason := ReasonOK
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := activateSeat(tx, license, fp, ip, activation); err != nil {
+			return err
+		}
+		var err error
+		if reason, err = bindAccount(tx, license, ctx); err != nil {
+			return err
+		}
+		if reason != ReasonOK {
+			return errBindingRejected
+		}
+		return nil
+	})
+	if errors.Is(err, errBindingRejected) {
+		return nil, reason, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return activation, ReasonOK, nil
+}
+
+// errBindingRejected 账号绑定被拒绝时回滚席位
+var errBindingRejected = errors.New("交易账号绑定被拒绝")
+
+// activateSeat 在事务中占用席位，已激活的设备只刷新 IP 和最后在线时间
+func activateSeat(tx *gorm.DB, license *model.License, fp model.Fingerprint, ip string, activation *model.LicenseActivation) error {
+	hash := fp.Hash()
+	err := tx.Where("license_key = ? AND fingerprint = ?", license.Key, hash).First(activation).Error
+	if err == nil {
+		activation.IPAddress = ip
+		activation.LastSeenAt = time.Now()
+		return tx.Save(activation).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// MaxSeats 为 0 表示不限制席位
+	if license.MaxSeats > 0 {
+		if err := lockLicense(tx, license.Key); err != nil {
+			return err
+		}
+		var used int64
+		if err := tx.Model(&model.LicenseActivation{}).Where("license_key = ?", license.Key).Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(license.MaxSeats) {
+			return ErrSeatLimitReached
+		}
+	}
+
+	*activation = model.LicenseActivation{
+		LicenseKey:    license.Key,
+		Fingerprint:   hash,
+		TerminalID:    fp.TerminalID,
+		AccountNumber: fp.AccountNumber,
+		MachineHash:   fp.MachineHash,
+		IPAddress:     ip,
+		LastSeenAt:    time.Now(),
+	}
+	return tx.Create(activation).Error
+}
+
+// lockLicense 在事务中锁定许可证行，使同一许可证的席位或租约检查与写入依次执行，
+// 否则并发请求在可重复读隔离级别下都会看到未满的计数。SQLite 不支持 FOR UPDATE，
+// 其写事务本身是串行的
+func lockLicense(tx *gorm.DB, key string) error {
+	var license model.License
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(database.ByLicenseKey(key)).
+		Select("id").
+		First(&license).Error
+}
+
+// DeactivateSeat 释放设备占用的席位
+func DeactivateSeat(key string, fp model.Fingerprint) error {
+	result := database.DB.Where("license_key = ? AND fingerprint = ?", key, fp.Hash()).Delete(&model.LicenseActivation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActivationNotFound
+	}
+	return nil
+}
+
+// DeactivateSeatByID 按激活记录ID释放席位，供用户在无法访问旧设备时使用
+func DeactivateSeatByID(key string, id uint) error {
+	result := database.DB.Where("license_key = ? AND id = ?", key, id).Delete(&model.LicenseActivation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActivationNotFound
+	}
+	return nil
+}
+
+// TouchSeat 检查设备是否已激活，已激活时刷新最后在线时间
+func TouchSeat(key string, fp model.Fingerprint) error {
+	result := database.DB.Model(&model.LicenseActivation{}).
+		Where("license_key = ? AND fingerprint = ?", key, fp.Hash()).
+		Update("last_seen_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActivationNotFound
+	}
+	return nil
+}
+
+// hasDeviceBinding 许可证是否要求设备已激活。不限制席位的许可证在激活过设备后同样绑定设备
+func hasDeviceBinding(license *model.License) (bool, error) {
+	if license.MaxSeats > 0 {
+		return true, nil
+	}
+	var ids []uint
+	err := database.DB.Model(&model.LicenseActivation{}).
+		Where("license_key = ?", license.Key).
+		Limit(1).
+		Pluck("id", &ids).Error
+	return len(ids) > 0, err
+}
+
+// GetActivations 获取许可证的所有激活设备
+func GetActivations(key string) ([]model.LicenseActivation, error) {
+	var activations []model.LicenseActivation
+	err := database.DB.Where("license_key = ?", key).Order("created_at ASC").Find(&activations).Error
+	return activations, err
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/model"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 交易账户绑定规则未通过时的原因代码
+const (
+	ReasonAccountNotAllowed     VerifyReason = "account_not_allowed"
+	ReasonAccountNotBound       VerifyReason = "account_not_bound" // 账号未绑定但未达到上限，激活后可用
+	ReasonAccountLimitReached   VerifyReason = "account_limit_reached"
+	ReasonBrokerNotAllowed      VerifyReason = "broker_not_allowed"
+	ReasonAccountTypeNotAllowed VerifyReason = "account_type_not_allowed"
+)
+
+var ErrInvalidBindingRule = errors.New("无效的账号绑定规则")
+
+// TradingContext 客户端所在的交易环境
+type TradingContext struct {
+	Account     string
+	Broker      string
+	Server      string
+	AccountType model.AccountType
+}
+
+// HasAccountBinding 许可证是否限制了交易账号
+func HasAccountBinding(license *model.License) bool {
+	return license.MaxAccounts > 0 || len(license.AllowedAccounts) > 0
+}
+
+// CheckBinding 依次检查账户类型、经纪商和交易账号规则，不绑定新账号。
+// 账号不在列表中但未达到上限时返回 ReasonAccountNotBound，由客户端激活后绑定
+func CheckBinding(license *model.License, ctx TradingContext) (VerifyReason, error) {
+	reason := checkBindingRules(license, ctx)
+	if reason != ReasonOK || !HasAccountBinding(license) || containsAccount(license.AllowedAccounts, ctx.Account) {
+		return reason, nil
+	}
+	return unboundReason(license.AllowedAccounts, license.MaxAccounts), nil
+}
+
+// checkBindingRules 检查账户类型和经纪商，限制了交易账号时要求提供账号
+func checkBindingRules(license *model.License, ctx TradingContext) VerifyReason {
+	if !license.AccountType.Allows(ctx.AccountType) {
+		return ReasonAccountTypeNotAllowed
+	}
+	if len(license.AllowedBrokers) > 0 && !matchBroker(license.AllowedBrokers, ctx.Broker, ctx.Server) {
+		return ReasonBrokerNotAllowed
+	}
+	if HasAccountBinding(license) && ctx.Account == "" {
+		return ReasonAccountNotAllowed
+	}
+	return ReasonOK
+}
+
+// bindAccount 激活时在事务中检查绑定规则，账号不在列表中但未达到上限时绑定该账号。
+// 账号放在最后检查，避免被其他规则拒绝的请求占用名额
+func bindAccount(tx *gorm.DB, license *model.License, ctx TradingContext) (VerifyReason, error) {
+	reason := checkBindingRules(license, ctx)
+	if reason != ReasonOK || !HasAccountBinding(license) {
+		return reason, nil
+	}
+
+	// 锁定许可证，避免并发激活的账号一起超过上限
+	if err := lockLicense(tx, license.Key); err != nil {
+		return "", err
+	}
+	var current model.License
+	if err := tx.Select("id", "allowed_accounts", "max_accounts").First(&current, license.ID).Error; err != nil {
+		return "", err
+	}
+	if containsAccount(current.AllowedAccounts, ctx.Account) {
+		return ReasonOK, nil
+	}
+	if reason = unboundReason(current.AllowedAccounts, current.MaxAccounts); reason != ReasonAccountNotBound {
+		return reason, nil
+	}
+
+	accounts := append(current.AllowedAccounts, ctx.Account)
+	if err := tx.Model(&model.License{}).Where("id = ?", license.ID).
+		Select("allowed_accounts").
+		Updates(&model.License{AllowedAccounts: accounts}).Error; err != nil {
+		return "", err
+	}
+	license.AllowedAccounts = accounts
+	return ReasonOK, nil
+}
+
+// unboundReason 账号不在列表中时的原因代码，只有设置了上限且未达到时可以绑定
+func unboundReason(accounts []string, maxAccounts int) VerifyReason {
+	if maxAccounts == 0 {
+		return ReasonAccountNotAllowed
+	}
+	if len(accounts) >= maxAccounts {
+		return ReasonAccountLimitReached
+	}
+	return ReasonAccountNotBound
+}
+
+func containsAccount(accounts []string, account string) bool {
+	for _, a := range accounts {
+		if a == account {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBroker 经纪商名称或服务器名称匹配任一通配符即通过，不区分大小写
+func matchBroker(patterns []string, broker, server string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, name := range []string{broker, server} {
+			if name == "" {
+				continue
+			}
+			if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateBindingRules 校验账号、经纪商和账户类型规则，并规范账户类型的取值
+func ValidateBindingRules(license *model.License) error {
+	if license.MaxAccounts < 0 || (license.MaxAccounts > 0 && len(license.AllowedAccounts) > license.MaxAccounts) {
+		return ErrInvalidBindingRule
+	}
+	accountType, ok := model.ParseAccountType(string(license.AccountType))
+	if !ok {
+		return ErrInvalidBindingRule
+	}
+	license.AccountType = accountType
+	for _, a := range license.AllowedAccounts {
+		if strings.TrimSpace(a) == "" {
+			return ErrInvalidBindingRule
+		}
+	}
+	for _, pattern := range license.AllowedBrokers {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil || pattern == "" {
+			return ErrInvalidBindingRule
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"crypto/hmac"
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SignatureWindow 客户端请求时间戳允许的偏差，窗口内的随机数不能重复使用
+const SignatureWindow = 5 * time.Minute
+
+// DefaultKeyRotationGrace 轮换密钥时旧密钥默认的宽限期
+const DefaultKeyRotationGrace = 24 * time.Hour
+
+var (
+	ErrClientKeyNotFound  = errors.New("API 密钥不存在")
+	ErrClientKeyInvalid   = errors.New("API 密钥无效或已过期")
+	ErrSignatureInvalid   = errors.New("请求签名错误")
+	ErrRequestExpired     = errors.New("请求时间戳超出允许范围")
+	ErrNonceReused        = errors.New("请求随机数已使用")
+	ErrNonceInvalid       = errors.New("请求随机数不能为空且不能超过 64 个字符")
+	ErrInvalidGracePeriod = errors.New("宽限期不能为负数")
+)
+
+// newClientKey 生成新的 API 密钥，Secret 只在创建时返回给管理员
+func newClientKey(productID, name string) (*model.ClientAPIKey, error) {
+	keyID, err := util.RandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := util.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ClientAPIKey{
+		KeyID:     "ck_" + keyID,
+		ProductId: productID,
+		Name:      name,
+		Secret:    secret,
+	}, nil
+}
+
+// CreateClientKey 为产品创建 API 密钥，同一产品可以同时有多个有效的密钥
+func CreateClientKey(productID, name string) (*model.ClientAPIKey, error) {
+	if _, err := GetProduct(productID); err != nil {
+		return nil, err
+	}
+	key, err := newClientKey(productID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListClientKeys 列出产品的全部 API 密钥，包括已过期的
+func ListClientKeys(productID string) ([]model.ClientAPIKey, error) {
+	var keys []model.ClientAPIKey
+	err := database.DB.Where("product_id = ?", productID).Order("id ASC").Find(&keys).Error
+	return keys, err
+}
+
+// getClientKey 按 ID 查询产品的 API 密钥
+func getClientKey(tx *gorm.DB, productID string, id uint) (*model.ClientAPIKey, error) {
+	var key model.ClientAPIKey
+	err := tx.Where("id = ? AND product_id = ?", id, productID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrClientKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RotateClientKey 生成替换的新密钥，旧密钥在宽限期后失效，
+// 期间新旧密钥都可使用，客户端可以逐步更新而不中断服务
+func RotateClientKey(productID string, id uint, grace time.Duration) (*model.ClientAPIKey, error) {
+	if grace < 0 {
+		return nil, ErrInvalidGracePeriod
+	}
+
+	var rotated *model.ClientAPIKey
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		old, err := getClientKey(tx, productID, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !old.IsActive(now) {
+			return ErrClientKeyInvalid
+		}
+
+		rotated, err = newClientKey(old.ProductId, old.Name)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(rotated).Error; err != nil {
+			return err
+		}
+
+		// 已设置更早的失效时间时保持不变
+		expiresAt := now.Add(grace)
+		if old.ExpiresAt.IsZero() || expiresAt.Before(old.ExpiresAt) {
+			return tx.Model(old).Update("expires_at", expiresAt).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
+
+// RevokeClientKey 立即停用 API 密钥
+func RevokeClientKey(productID string, id uint) (*model.ClientAPIKey, error) {
+	key, err := getClientKey(database.DB, productID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.IsActive(now) {
+		key.ExpiresAt = now
+		if err := database.DB.Model(key).Update("expires_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ClientRequest 待校验的客户端请求，字段取自请求头和请求内容
+type ClientRequest struct {
+	KeyID     string
+	Timestamp string // Unix 时间戳（秒）
+	Nonce     string
+	Signature string
+	Method    string
+	URI       string // 路径和查询参数
+	Body      []byte
+}
+
+// VerifyClientRequest 校验客户端请求的 API 密钥、签名和时间戳，并记录随机数防止重放，
+// 返回请求使用的密钥
+func VerifyClientRequest(req ClientRequest) (*model.ClientAPIKey, error) {
+	var key model.ClientAPIKey
+	err := database.DB.Where("key_id = ?", req.KeyID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrClientKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrClientKeyInvalid
+	}
+
+	expected := licensing.SignRequest(key.Secret, req.Method, req.URI, req.Timestamp, req.Nonce, req.Body)
+	if !hmac.Equal([]byte(expected), []byte(req.Signature)) {
+		return nil, ErrSignatureInvalid
+	}
+
+	seconds, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrRequestExpired
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > SignatureWindow || skew < -SignatureWindow {
+		return nil, ErrRequestExpired
+	}
+
+	if req.Nonce == "" || len(req.Nonce) > 64 {
+		return nil, ErrNonceInvalid
+	}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ClientNonce{
+		KeyID:     key.KeyID,
+		Nonce:     req.Nonce,
+		CreatedAt: now,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNonceReused
+	}
+
+	// 最后使用时间只用于展示，每分钟最多更新一次
+	if now.Sub(key.LastUsedAt) > time.Minute {
+		database.DB.Model(&key).Update("last_used_at", now)
+	}
+	return &key, nil
+}
+
+// PruneClientNonces 删除超出时间窗口的随机数，这些请求已因时间戳过期而无法重放
+func PruneClientNonces() (int64, error) {
+	result := database.DB.Where("created_at < ?", time.Now().Add(-2*SignatureWindow)).Delete(&model.ClientNonce{})
+	return result.RowsAffected, result.Error
+}
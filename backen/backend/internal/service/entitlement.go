@@ -0,0 +1,30 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+)
+
+// ValidateEntitlements 校验权益是否在产品的权益范围内
+func ValidateEntitlements(productId string, entitlements licensing.Entitlements) error {
+	product, err := GetProduct(productId)
+	if err != nil {
+		return err
+	}
+	return product.EntitlementSchema.Validate(entitlements)
+}
+
+// ResolveEntitlements 计算许可证最终生效的权益：许可证单独设置的权益优先，
+// 其余使用产品默认值，并裁剪到产品当前的权益范围内。
+// 产品已不在目录中时原样返回许可证的权益。
+func ResolveEntitlements(license *model.License) (licensing.Entitlements, error) {
+	product, err := GetProduct(license.ProductId)
+	if errors.Is(err, ErrProductNotFound) {
+		return license.Entitlements, nil
+	}
+	if err != nil {
+		return licensing.Entitlements{}, err
+	}
+	return product.EntitlementSchema.Resolve(product.DefaultEntitlements, license.Entitlements), nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/scheduler"
+	"log"
+	"time"
+)
+
+// Retention 历史记录保留时长
+type Retention struct {
+	Usage   time.Duration // 许可证使用记录
+	JobRuns time.Duration // 任务执行记录
+}
+
+// RegisterJobs 注册后台定时任务
+func RegisterJobs(s *scheduler.Scheduler, retention Retention) error {
+	jobs := []scheduler.Job{
+		{
+			Name:        "expire_licenses",
+			Spec:        "*/5 * * * *",
+			Description: "将已过有效期的许可证变更为已过期",
+			Run:         runExpireLicenses,
+		},
+		{
+			Name:        "reclaim_leases",
+			Spec:        "* * * * *",
+			Description: "回收过期的浮动许可证租约",
+			Run:         runReclaimLeases,
+		},
+		{
+			Name:        "prune_client_nonces",
+			Spec:        "*/10 * * * *",
+			Description: "清理超出时间窗口的客户端请求随机数",
+			Run:         runPruneClientNonces,
+		},
+		{
+			Name:        "prune_login_throttles",
+			Spec:        "*/10 * * * *",
+			Description: "清理已不再限制登录的失败次数统计",
+			Run:         runPruneLoginThrottles,
+		},
+		{
+			Name:        "prune_history",
+			Spec:        "30 3 * * *",
+			Description: "清理过期的许可证使用记录和任务执行记录",
+			Run: func() (string, error) {
+				return runPruneHistory(retention)
+			},
+		},
+		{
+			Name:        "daily_usage_rollup",
+			Spec:        "*/5 * * * *",
+			Description: "增量汇总每个许可证和产品的每日使用量",
+			Run:         runDailyUsageRollup,
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runExpireLicenses() (string, error) {
+	var licenses []model.License
+	err := database.DB.
+		Where("status IN ? AND valid_until <= ?", []model.LicenseStatus{
+			model.LicenseInactive, model.LicenseActive, model.LicenseSuspended,
+		}, time.Now()).
+		Find(&licenses).Error
+	if err != nil {
+		return "", err
+	}
+
+	// 单个许可证失败（例如状态被并发修改）时跳过，不影响其余许可证
+	expired, failed := 0, 0
+	for i := range licenses {
+		if err := ChangeLicenseStatus(&licenses[i], model.LicenseExpired); err != nil {
+			log.Printf("许可证 %s 变更为已过期失败: %v", licenses[i].Key, err)
+			failed++
+			continue
+		}
+		expired++
+	}
+
+	summary := fmt.Sprintf("已过期 %d 个许可证", expired)
+	if failed > 0 {
+		return summary, fmt.Errorf("%d 个许可证变更失败", failed)
+	}
+	return summary, nil
+}
+
+func runReclaimLeases() (string, error) {
+	n, err := ReclaimExpiredLeases()
+	return fmt.Sprintf("已回收 %d 个租约", n), err
+}
+
+func runPruneClientNonces() (string, error) {
+	n, err := PruneClientNonces()
+	return fmt.Sprintf("已清理 %d 个随机数", n), err
+}
+
+func runPruneLoginThrottles() (string, error) {
+	n, err := PruneLoginThrottles()
+	return fmt.Sprintf("已清理 %d 条登录失败统计", n), err
+}
+
+func runPruneHistory(retention Retention) (string, error) {
+	now := time.Now()
+
+	usage := database.DB.Unscoped().
+		Where("timestamp < ?", now.Add(-retention.Usage)).
+		Delete(&model.LicenseUsage{})
+	if usage.Error != nil {
+		return "", usage.Error
+	}
+
+	runs := database.DB.
+		Where("started_at < ?", now.Add(-retention.JobRuns)).
+		Delete(&model.JobRun{})
+	if runs.Error != nil {
+		return "", runs.Error
+	}
+
+	return fmt.Sprintf("已清理 %d 条使用记录, %d 条任务记录", usage.RowsAffected, runs.RowsAffected), nil
+}
+
+func runDailyUsageRollup() (string, error) {
+	days, err := RollupUsage()
+	return fmt.Sprintf("已重新汇总 %d 天的使用量", days), err
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/telemetry"
+	"license-management-system/internal/util"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLeaseTTL = 5 * time.Minute
+	MinLeaseTTL     = 30 * time.Second
+	MaxLeaseTTL     = time.Hour
+)
+
+var (
+	ErrLeaseLimitReached = errors.New("并发租约已达上限")
+	ErrLeaseNotFound     = errors.New("租约不存在或已过期")
+)
+
+// NormalizeLeaseTTL 将客户端请求的租期限制在允许范围内，未指定时使用默认租期
+func NormalizeLeaseTTL(ttl time.Duration) time.Duration {
+	switch {
+	case ttl <= 0:
+		return DefaultLeaseTTL
+	case ttl < MinLeaseTTL:
+		return MinLeaseTTL
+	case ttl > MaxLeaseTTL:
+		return MaxLeaseTTL
+	}
+	return ttl
+}
+
+// CheckoutLease 为客户端签出一个租约并开始使用会话。
+// 同一客户端重复签出时续期已有租约，不会额外占用并发数。
+func CheckoutLease(license *model.License, clientID string, client telemetry.Client, ttl time.Duration) (*model.LicenseLease, error) {
+	lease := &model.LicenseLease{}
+	now := time.Now()
+	ttl = NormalizeLeaseTTL(ttl)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 先锁定许可证，同一客户端的并发签出依次执行，不会各自创建租约
+		if err := lockLicense(tx, license.Key); err != nil {
+			return err
+		}
+
+		// 回收该许可证下已过期的租约
+		if _, err := reclaimLeases(tx.Where("license_key = ?", license.Key), now); err != nil {
+			return err
+		}
+
+		err := tx.Where("license_key = ? AND client_id = ?", license.Key, clientID).First(lease).Error
+		if err == nil {
+			lease.IPAddress = client.IPAddress
+			lease.ExpiresAt = now.Add(ttl)
+			lease.RenewedAt = now
+			if err := tx.Save(lease).Error; err != nil {
+				return err
+			}
+			return touchSession(tx, lease)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// MaxConcurrent 为 0 表示不限制并发数
+		if license.MaxConcurrent > 0 {
+			var active int64
+			if err := tx.Model(&model.LicenseLease{}).Where("license_key = ?", license.Key).Count(&active).Error; err != nil {
+				return err
+			}
+			if active >= int64(license.MaxConcurrent) {
+				return ErrLeaseLimitReached
+			}
+		}
+
+		leaseID, err := util.RandomToken(16)
+		if err != nil {
+			return err
+		}
+
+		*lease = model.LicenseLease{
+			LeaseID:    leaseID,
+			LicenseKey: license.Key,
+			ClientID:   clientID,
+			IPAddress:  client.IPAddress,
+			ExpiresAt:  now.Add(ttl),
+			RenewedAt:  now,
+		}
+		if err := tx.Create(lease).Error; err != nil {
+			return err
+		}
+		return startSession(tx, lease, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// GetLease 获取未过期的租约
+func GetLease(leaseID string) (*model.LicenseLease, error) {
+	var lease model.LicenseLease
+	err := database.DB.Where("lease_id = ? AND expires_at > ?", leaseID, time.Now()).First(&lease).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLeaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// RenewLease 处理心跳，延长租约有效期并更新会话时长
+func RenewLease(lease *model.LicenseLease, ttl time.Duration) error {
+	now := time.Now()
+	lease.ExpiresAt = now.Add(NormalizeLeaseTTL(ttl))
+	lease.RenewedAt = now
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(lease).Error; err != nil {
+			return err
+		}
+		return touchSession(tx, lease)
+	})
+}
+
+// ReleaseLease 客户端退出时主动释放租约并结束会话
+func ReleaseLease(leaseID string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("lease_id = ?", leaseID).Delete(&model.LicenseLease{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLeaseNotFound
+		}
+		return endSession(tx, leaseID, time.Now())
+	})
+}
+
+// GetActiveLeases 获取许可证当前有效的租约
+func GetActiveLeases(key string) ([]model.LicenseLease, error) {
+	var leases []model.LicenseLease
+	err := database.DB.Where("license_key = ? AND expires_at > ?", key, time.Now()).
+		Order("created_at ASC").Find(&leases).Error
+	return leases, err
+}
+
+// ReclaimExpiredLeases 回收所有已过期的租约并结束对应的会话，返回回收数量
+func ReclaimExpiredLeases() (int64, error) {
+	var reclaimed int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reclaimed, err = reclaimLeases(tx, time.Now())
+		return err
+	})
+	return reclaimed, err
+}
+
+// reclaimLeases 删除 scope 范围内已过期的租约，会话在最后一次心跳时结束，
+// 不计入客户端失联后到租约过期之间的时间
+func reclaimLeases(scope *gorm.DB, now time.Time) (int64, error) {
+	var leases []model.LicenseLease
+	if err := scope.Where("expires_at <= ?", now).Find(&leases).Error; err != nil {
+		return 0, err
+	}
+	if len(leases) == 0 {
+		return 0, nil
+	}
+
+	tx := scope.Session(&gorm.Session{NewDB: true})
+	ids := make([]uint, len(leases))
+	for i, lease := range leases {
+		ids[i] = lease.ID
+	}
+	if err := tx.Delete(&model.LicenseLease{}, ids).Error; err != nil {
+		return 0, err
+	}
+	for _, lease := range leases {
+		if err := endSession(tx, lease.LeaseID, lease.RenewedAt); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(leases)), nil
+}
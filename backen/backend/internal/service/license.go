@@ -0,0 +1,301 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 生成密钥时遇到重复的最大重试次数
+const maxKeyGenerateAttempts = 5
+
+// MaxBatchSize 批量生成许可证的最大数量
+const MaxBatchSize = 1000
+
+var (
+	ErrKeyExhausted            = errors.New("无法生成唯一的许可证密钥")
+	ErrInvalidValidUntil       = errors.New("有效期必须晚于当前时间")
+	ErrInvalidLicenseLimits    = errors.New("席位数和并发数不能为负数")
+	ErrInvalidStatus           = errors.New("无效的许可证状态")
+	ErrInvalidStatusTransition = errors.New("不允许的许可证状态变更")
+	ErrStatusConflict          = errors.New("许可证状态已被修改")
+	ErrInvalidBatchSize        = fmt.Errorf("批量生成数量须在 1 到 %d 之间", MaxBatchSize)
+	ErrLicenseNotFound         = errors.New("许可证不存在")
+)
+
+// LicenseSpec 生成许可证的参数，未指定的字段使用产品的默认值
+type LicenseSpec struct {
+	ProductId         string
+	UserId            string
+	Version           string
+	VersionConstraint string                 // 允许的客户端版本范围，为空时使用产品设置
+	MaxMajorVersion   int                    // 允许的最大主版本号，0 表示使用产品设置
+	Entitlements      licensing.Entitlements // 单独设置的权益，未设置的字段使用产品默认值
+	Prefix            string
+	ValidUntil        time.Time
+	MaxSeats          int
+	MaxConcurrent     int
+	AllowedAccounts   []string          // 允许使用的交易账号
+	MaxAccounts       int               // 账号数上限，未满时自动绑定
+	AllowedBrokers    []string          // 允许的经纪商或服务器名称（通配符）
+	AccountType       model.AccountType // 账户类型限制
+}
+
+// BuildLicense 校验参数、补全产品默认值并生成密钥，返回尚未保存的许可证
+func BuildLicense(spec LicenseSpec) (*model.License, error) {
+	product, err := GetProduct(spec.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if spec.ValidUntil.IsZero() {
+		days := product.ValidityDays
+		if days == 0 {
+			days = DefaultValidityDays
+		}
+		spec.ValidUntil = now.AddDate(0, 0, days)
+	} else if !spec.ValidUntil.After(now) {
+		return nil, ErrInvalidValidUntil
+	}
+
+	if spec.MaxSeats < 0 || spec.MaxConcurrent < 0 {
+		return nil, ErrInvalidLicenseLimits
+	}
+	if spec.MaxSeats == 0 {
+		spec.MaxSeats = product.DefaultMaxSeats
+	}
+	if spec.MaxSeats == 0 {
+		spec.MaxSeats = 1
+	}
+	if err := ValidateVersionRule(spec.VersionConstraint, spec.MaxMajorVersion); err != nil {
+		return nil, err
+	}
+	if err := product.EntitlementSchema.Validate(spec.Entitlements); err != nil {
+		return nil, err
+	}
+	if spec.Version == "" {
+		spec.Version = product.CurrentVersion
+	}
+	if spec.Prefix == "" {
+		spec.Prefix = product.KeyPrefix
+	}
+
+	key, err := GenerateLicenseKey(spec.Prefix)
+	if errors.Is(err, keygen.ErrInvalidPrefix) {
+		return nil, ErrInvalidKeyPrefix
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	license := &model.License{
+		Key:               key,
+		Status:            model.LicenseInactive,
+		ValidUntil:        spec.ValidUntil,
+		Version:           spec.Version,
+		VersionConstraint: spec.VersionConstraint,
+		MaxMajorVersion:   spec.MaxMajorVersion,
+		Entitlements:      spec.Entitlements,
+		UserId:            spec.UserId,
+		ProductId:         product.Code,
+		MaxSeats:          spec.MaxSeats,
+		MaxConcurrent:     spec.MaxConcurrent,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		AllowedAccounts:   spec.AllowedAccounts,
+		MaxAccounts:       spec.MaxAccounts,
+		AllowedBrokers:    spec.AllowedBrokers,
+		AccountType:       spec.AccountType,
+	}
+	if err := ValidateBindingRules(license); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+// GenerateLicenses 按同一参数批量生成许可证，在一个事务中保存，任一失败时全部回滚
+func GenerateLicenses(spec LicenseSpec, count int) ([]model.License, error) {
+	if count < 1 || count > MaxBatchSize {
+		return nil, ErrInvalidBatchSize
+	}
+
+	licenses := make([]model.License, 0, count)
+	keys := make(map[string]bool, count)
+	for len(licenses) < count {
+		license, err := BuildLicense(spec)
+		if err != nil {
+			return nil, err
+		}
+		// 同一批次内的密钥尚未保存，需要单独去重
+		if keys[license.Key] {
+			continue
+		}
+		keys[license.Key] = true
+		licenses = append(licenses, *license)
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(licenses, 100).Error
+	}); err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
+
+// GetLicense 按密钥查找许可证
+func GetLicense(key string) (*model.License, error) {
+	var license model.License
+	err := database.DB.Scopes(database.ByLicenseKey(key)).First(&license).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLicenseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &license, nil
+}
+
+// ExtendLicense 将许可证有效期延长至 validUntil，已过期的许可证恢复为已激活
+func ExtendLicense(license *model.License, validUntil time.Time) error {
+	if !validUntil.After(time.Now()) {
+		return ErrInvalidValidUntil
+	}
+	if license.Status == model.LicenseRevoked {
+		return fmt.Errorf("%w: 已吊销的许可证不能续期", ErrInvalidStatusTransition)
+	}
+	if license.Status == model.LicenseExpired {
+		if err := ChangeLicenseStatus(license, model.LicenseActive); err != nil {
+			return err
+		}
+	}
+
+	license.ValidUntil = validUntil
+	license.UpdatedAt = time.Now()
+	return database.DB.Model(license).Updates(map[string]interface{}{
+		"valid_until": license.ValidUntil,
+		"updated_at":  license.UpdatedAt,
+	}).Error
+}
+
+// IssueLicense 将许可证分配给用户
+func IssueLicense(license *model.License, userID uint) error {
+	var count int64
+	if err := database.DB.Model(&model.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+
+	license.IssuedTo = userID
+	license.UpdatedAt = time.Now()
+	return database.DB.Model(license).Updates(map[string]interface{}{
+		"issued_to":  license.IssuedTo,
+		"updated_at": license.UpdatedAt,
+	}).Error
+}
+
+// ChangeLicenseStatus 按状态机校验并持久化许可证状态变更。
+// 更新时以原状态为条件，避免并发修改覆盖彼此的结果。
+func ChangeLicenseStatus(license *model.License, to model.LicenseStatus) error {
+	return changeLicenseStatus(database.DB, license, to)
+}
+
+// CheckStatusChange 校验状态变更是否符合状态机规则，状态不变时返回 nil
+func CheckStatusChange(license *model.License, to model.LicenseStatus) error {
+	if !to.IsValid() {
+		return ErrInvalidStatus
+	}
+	if license.Status != to && !license.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, license.Status, to)
+	}
+	return nil
+}
+
+// SaveLicense 在一个事务中变更状态并保存其余字段，任一步失败时都不会写入。
+// to 为空时不变更状态
+func SaveLicense(license *model.License, to model.LicenseStatus) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if to != "" {
+			if err := changeLicenseStatus(tx, license, to); err != nil {
+				return err
+			}
+		}
+		license.UpdatedAt = time.Now()
+		return tx.Save(license).Error
+	})
+}
+
+func changeLicenseStatus(tx *gorm.DB, license *model.License, to model.LicenseStatus) error {
+	if err := CheckStatusChange(license, to); err != nil {
+		return err
+	}
+	if license.Status == to {
+		return nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": now,
+	}
+	if to == model.LicenseActive {
+		updates["last_activated_at"] = now
+	}
+
+	result := tx.Model(&model.License{}).
+		Where("id = ? AND status = ?", license.ID, license.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStatusConflict
+	}
+
+	license.Status = to
+	license.UpdatedAt = now
+	if to == model.LicenseActive {
+		license.LastActivatedAt = now
+	}
+	return nil
+}
+
+// ExpireIfOverdue 有效期已过但状态尚未更新的许可证变更为已过期
+func ExpireIfOverdue(license *model.License) error {
+	if time.Now().Before(license.ValidUntil) || !license.Status.CanTransitionTo(model.LicenseExpired) {
+		return nil
+	}
+	return ChangeLicenseStatus(license, model.LicenseExpired)
+}
+
+// IsLicenseUsable 许可证是否处于可使用状态
+func IsLicenseUsable(license *model.License) bool {
+	return license.Status == model.LicenseActive && time.Now().Before(license.ValidUntil)
+}
+
+// GenerateLicenseKey 生成唯一的许可证密钥，与已有密钥（包括已删除的）冲突时重新生成
+func GenerateLicenseKey(prefix string) (string, error) {
+	for i := 0; i < maxKeyGenerateAttempts; i++ {
+		key, err := keygen.Generate(prefix)
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		if err := database.DB.Model(&model.License{}).Unscoped().Scopes(database.ByLicenseKey(key)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return key, nil
+		}
+	}
+	return "", ErrKeyExhausted
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxImportSize 单次导入的最大记录数
+const MaxImportSize = 10000
+
+// maxKeyLength 许可证密钥的最大长度，与数据库列宽一致
+const maxKeyLength = 191
+
+var (
+	ErrInvalidImportSize = fmt.Errorf("导入数量须在 1 到 %d 之间", MaxImportSize)
+	ErrCSVMissingKey     = errors.New("CSV 缺少 key 列")
+)
+
+// LicenseCSVHeader 导出 CSV 的列，导入时按列名读取，未知的列会被忽略。
+// 列表字段以逗号分隔，entitlements 为完整权益的 JSON，设置后忽略 features 列
+var LicenseCSVHeader = []string{
+	"key", "productid", "userid", "status", "valid_until", "version",
+	"max_seats", "max_concurrent", "features", "issued_to", "created_at",
+	"version_constraint", "max_major_version", "allowed_accounts", "max_accounts",
+	"allowed_brokers", "account_type", "entitlements",
+}
+
+// LicenseImport 从旧系统导入的一条许可证记录
+type LicenseImport struct {
+	Key           string                 `json:"key"`
+	ProductId     string                 `json:"productid"`
+	UserId        string                 `json:"userid"`
+	Status        model.LicenseStatus    `json:"status"` // 为空时视为未激活
+	ValidUntil    time.Time              `json:"valid_until"`
+	Version       string                 `json:"version"`
+	MaxSeats      int                    `json:"max_seats"` // 为 0 时使用产品默认值
+	MaxConcurrent int                    `json:"max_concurrent"`
+	Entitlements  licensing.Entitlements `json:"entitlements"`
+	Permissions   string                 `json:"permissions"` // 旧系统逗号分隔的权限，转换为功能开关
+	IssuedTo      uint                   `json:"issued_to"`
+	CreatedAt     time.Time              `json:"created_at"` // 为空时使用导入时间
+
+	VersionConstraint string            `json:"version_constraint"`
+	MaxMajorVersion   int               `json:"max_major_version"`
+	AllowedAccounts   []string          `json:"allowed_accounts"`
+	MaxAccounts       int               `json:"max_accounts"`
+	AllowedBrokers    []string          `json:"allowed_brokers"`
+	AccountType       model.AccountType `json:"account_type"`
+}
+
+// ImportError 导入记录的校验错误，Row 为记录序号（从 1 开始，不含 CSV 表头）
+type ImportError struct {
+	Row   int    `json:"row"`
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportLicenses 校验并导入许可证，保留原有密钥。
+// 任一记录校验失败时不写入任何数据并返回全部错误，校验通过后在一个事务中保存。
+func ImportLicenses(records []LicenseImport) (int, []ImportError, error) {
+	if len(records) == 0 || len(records) > MaxImportSize {
+		return 0, nil, ErrInvalidImportSize
+	}
+
+	products := make(map[string]*model.Product)
+	seen := make(map[string]int, len(records))
+	licenses := make([]model.License, 0, len(records))
+	var errs []ImportError
+	now := time.Now()
+
+	for i, r := range records {
+		row := i + 1
+		fail := func(msg string) {
+			errs = append(errs, ImportError{Row: row, Key: r.Key, Error: msg})
+		}
+
+		r.Key = strings.TrimSpace(r.Key)
+		switch {
+		case r.Key == "":
+			fail("密钥不能为空")
+			continue
+		case len(r.Key) > maxKeyLength:
+			fail(fmt.Sprintf("密钥长度不能超过 %d 个字符", maxKeyLength))
+			continue
+		case errors.Is(keygen.Validate(r.Key), keygen.ErrChecksum):
+			fail("密钥校验位错误")
+			continue
+		}
+		if first, ok := seen[r.Key]; ok {
+			fail(fmt.Sprintf("与第 %d 条记录的密钥重复", first))
+			continue
+		}
+		seen[r.Key] = row
+
+		product, ok := products[r.ProductId]
+		if !ok {
+			var err error
+			product, err = GetProduct(r.ProductId)
+			if err != nil && !errors.Is(err, ErrProductNotFound) {
+				return 0, nil, err
+			}
+			products[r.ProductId] = product
+		}
+		if product == nil {
+			fail("产品不存在: " + r.ProductId)
+			continue
+		}
+
+		if r.Status == "" {
+			r.Status = model.LicenseInactive
+		}
+		if !r.Status.IsValid() {
+			fail("无效的许可证状态: " + string(r.Status))
+			continue
+		}
+		if r.ValidUntil.IsZero() {
+			fail("有效期不能为空")
+			continue
+		}
+		if r.MaxSeats < 0 || r.MaxConcurrent < 0 {
+			fail(ErrInvalidLicenseLimits.Error())
+			continue
+		}
+		if r.MaxSeats == 0 {
+			r.MaxSeats = product.DefaultMaxSeats
+		}
+		if r.MaxSeats == 0 {
+			r.MaxSeats = 1
+		}
+		if len(r.Entitlements.Features) == 0 {
+			r.Entitlements.Features = model.ParsePermissions(r.Permissions)
+		}
+		if err := product.EntitlementSchema.Validate(r.Entitlements); err != nil {
+			fail(err.Error())
+			continue
+		}
+		if err := ValidateVersionRule(r.VersionConstraint, r.MaxMajorVersion); err != nil {
+			fail(err.Error())
+			continue
+		}
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = now
+		}
+
+		license := model.License{
+			Key:               r.Key,
+			Status:            r.Status,
+			ValidUntil:        r.ValidUntil,
+			IssuedTo:          r.IssuedTo,
+			Version:           r.Version,
+			VersionConstraint: r.VersionConstraint,
+			MaxMajorVersion:   r.MaxMajorVersion,
+			Entitlements:      r.Entitlements,
+			CreatedAt:         r.CreatedAt,
+			UpdatedAt:         now,
+			UserId:            r.UserId,
+			ProductId:         product.Code,
+			MaxSeats:          r.MaxSeats,
+			MaxConcurrent:     r.MaxConcurrent,
+			AllowedAccounts:   r.AllowedAccounts,
+			MaxAccounts:       r.MaxAccounts,
+			AllowedBrokers:    r.AllowedBrokers,
+			AccountType:       r.AccountType,
+		}
+		if err := ValidateBindingRules(&license); err != nil {
+			fail(err.Error())
+			continue
+		}
+		licenses = append(licenses, license)
+	}
+
+	// 与已有许可证（包括已删除的）的密钥冲突
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	for start := 0; start < len(keys); start += 500 {
+		end := min(start+500, len(keys))
+		var existing []string
+		if err := database.DB.Model(&model.License{}).Unscoped().
+			Scopes(database.ByLicenseKeys(keys[start:end])).
+			Pluck("key", &existing).Error; err != nil {
+			return 0, nil, err
+		}
+		for _, key := range existing {
+			errs = append(errs, ImportError{Row: seen[key], Key: key, Error: "密钥已存在"})
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Row < errs[j].Row })
+		return 0, errs, nil
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(licenses, 100).Error
+	}); err != nil {
+		return 0, nil, err
+	}
+	return len(licenses), nil, nil
+}
+
+// ParseLicenseCSV 按表头列名解析 CSV，无法解析的字段作为对应记录的错误返回
+func ParseLicenseCSV(r io.Reader) ([]LicenseImport, []ImportError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, ErrCSVMissingKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[strings.ReplaceAll(name, "_id", "id")] = i
+	}
+	if _, ok := columns["key"]; !ok {
+		return nil, nil, ErrCSVMissingKey
+	}
+
+	var records []LicenseImport
+	var errs []ImportError
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		rec := LicenseImport{
+			Key:               get("key"),
+			ProductId:         get("productid"),
+			UserId:            get("userid"),
+			Status:            model.LicenseStatus(get("status")),
+			Version:           get("version"),
+			Permissions:       strings.Join([]string{get("features"), get("permissions")}, ","),
+			VersionConstraint: get("version_constraint"),
+			AllowedAccounts:   splitImportList(get("allowed_accounts")),
+			AllowedBrokers:    splitImportList(get("allowed_brokers")),
+			AccountType:       model.AccountType(get("account_type")),
+		}
+		row := len(records) + 1
+		fail := func(field string, err error) {
+			errs = append(errs, ImportError{Row: row, Key: rec.Key, Error: field + " 格式错误: " + err.Error()})
+		}
+
+		if rec.ValidUntil, err = parseImportTime(get("valid_until")); err != nil {
+			fail("valid_until", err)
+		}
+		if rec.CreatedAt, err = parseImportTime(get("created_at")); err != nil {
+			fail("created_at", err)
+		}
+		if rec.MaxSeats, err = parseImportInt(get("max_seats")); err != nil {
+			fail("max_seats", err)
+		}
+		if rec.MaxConcurrent, err = parseImportInt(get("max_concurrent")); err != nil {
+			fail("max_concurrent", err)
+		}
+		issuedTo, err := parseImportInt(get("issued_to"))
+		if err != nil || issuedTo < 0 {
+			fail("issued_to", errors.New("无效的用户 ID"))
+		}
+		rec.IssuedTo = uint(issuedTo)
+		if rec.MaxMajorVersion, err = parseImportInt(get("max_major_version")); err != nil {
+			fail("max_major_version", err)
+		}
+		if rec.MaxAccounts, err = parseImportInt(get("max_accounts")); err != nil {
+			fail("max_accounts", err)
+		}
+		if entitlements := get("entitlements"); entitlements != "" {
+			if err := json.Unmarshal([]byte(entitlements), &rec.Entitlements); err != nil {
+				fail("entitlements", err)
+			}
+		}
+
+		records = append(records, rec)
+	}
+	return records, errs, nil
+}
+
+// WriteLicenseCSV 按 LicenseCSVHeader 的列写出许可证，导入后可以还原除激活、租约和使用记录以外的全部设置
+func WriteLicenseCSV(w io.Writer, licenses []model.License) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(LicenseCSVHeader); err != nil {
+		return err
+	}
+	for _, l := range licenses {
+		entitlements, err := json.Marshal(l.Entitlements)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write([]string{
+			l.Key,
+			l.ProductId,
+			l.UserId,
+			string(l.Status),
+			l.ValidUntil.Format(time.RFC3339),
+			l.Version,
+			strconv.Itoa(l.MaxSeats),
+			strconv.Itoa(l.MaxConcurrent),
+			strings.Join(l.Entitlements.Features, ","),
+			strconv.FormatUint(uint64(l.IssuedTo), 10),
+			l.CreatedAt.Format(time.RFC3339),
+			l.VersionConstraint,
+			strconv.Itoa(l.MaxMajorVersion),
+			strings.Join(l.AllowedAccounts, ","),
+			strconv.Itoa(l.MaxAccounts),
+			strings.Join(l.AllowedBrokers, ","),
+			string(l.AccountType),
+			string(entitlements),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// importTimeLayouts 导入时支持的时间格式
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseImportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间 %q", s)
+}
+
+// splitImportList 解析逗号分隔的列表，忽略空项
+func splitImportList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseImportInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
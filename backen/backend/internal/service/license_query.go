@@ -0,0 +1,323 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidSortColumn = errors.New("不支持的排序字段")
+	ErrInvalidCursor     = errors.New("无效的分页游标")
+	ErrInvalidGroupBy    = errors.New("不支持的分组字段")
+)
+
+// LicenseFilter 查询许可证的条件，零值字段不参与过滤
+type LicenseFilter struct {
+	Status    model.LicenseStatus
+	ProductId string
+	UserId    string
+	IssuedTo  uint
+	Search    string // 按密钥或用户名模糊匹配
+	Limit     int    // 0 表示不限制
+
+	// 到期时间和创建时间范围，包含起点不包含终点
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// ListLicenses 按条件查询许可证，按创建时间倒序排列
+func ListLicenses(f LicenseFilter) ([]model.License, error) {
+	db := f.apply(database.DB.Model(&model.License{}))
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+
+	var licenses []model.License
+	if err := db.Order("created_at DESC").Order("id DESC").Find(&licenses).Error; err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
+
+// CountLicenses 统计满足条件的许可证数量
+func CountLicenses(f LicenseFilter) (int64, error) {
+	var total int64
+	err := f.apply(database.DB.Model(&model.License{})).Count(&total).Error
+	return total, err
+}
+
+// apply 将过滤条件加到查询上，不包括 Limit
+func (f LicenseFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.ProductId != "" {
+		db = db.Where("product_id = ?", f.ProductId)
+	}
+	if f.UserId != "" {
+		db = db.Where("user_id = ?", f.UserId)
+	}
+	if f.IssuedTo != 0 {
+		db = db.Where("issued_to = ?", f.IssuedTo)
+	}
+	if f.Search != "" {
+		pattern := "%" + likeEscaper.Replace(f.Search) + "%"
+		db = db.Where(clause.Or(
+			clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{clause.Column{Name: "key"}, pattern}},
+			clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{clause.Column{Name: "user_id"}, pattern}},
+		))
+	}
+	if !f.ExpiresAfter.IsZero() {
+		db = db.Where("valid_until >= ?", f.ExpiresAfter)
+	}
+	if !f.ExpiresBefore.IsZero() {
+		db = db.Where("valid_until < ?", f.ExpiresBefore)
+	}
+	if !f.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", f.CreatedBefore)
+	}
+	return db
+}
+
+// likeEscaper 转义 LIKE 的通配符，搜索词中的 % 和 _ 按字面匹配。转义字符使用 !，
+// 反斜杠在 MySQL 的字符串常量中需要双写而在其他数据库中不需要，无法写出通用的 ESCAPE 子句；
+// 指定 ESCAPE 后反斜杠也不再是转义字符
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// licenseSortColumns 可排序的列及取值方法，取值用于生成游标
+var licenseSortColumns = map[string]func(*model.License) interface{}{
+	"created_at":  func(l *model.License) interface{} { return l.CreatedAt },
+	"updated_at":  func(l *model.License) interface{} { return l.UpdatedAt },
+	"valid_until": func(l *model.License) interface{} { return l.ValidUntil },
+	"key":         func(l *model.License) interface{} { return l.Key },
+	"status":      func(l *model.License) interface{} { return string(l.Status) },
+	"product_id":  func(l *model.License) interface{} { return l.ProductId },
+	"user_id":     func(l *model.License) interface{} { return l.UserId },
+}
+
+// LicensePage 分页和排序参数
+type LicensePage struct {
+	Sort   string // 排序列，默认 created_at
+	Desc   bool
+	Offset int
+	Limit  int    // 每页数量，默认 10
+	Cursor string // 上一页返回的游标，设置后忽略 Offset，排序参数须与上一页一致
+}
+
+// LicensePageResult 一页许可证，NextCursor 为空表示没有下一页
+type LicensePageResult struct {
+	Licenses   []model.License
+	NextCursor string
+}
+
+// licenseCursor 游标记录上一页最后一条的排序值和 ID，
+// 按 (排序列, id) 继续查询，翻页期间插入或删除数据不会使后续页重复或跳过已有数据
+type licenseCursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// PageLicenses 按条件分页查询许可证，排序相同时按 ID 排序保证顺序稳定
+func PageLicenses(f LicenseFilter, p LicensePage) (*LicensePageResult, error) {
+	if p.Sort == "" {
+		p.Sort = "created_at"
+	}
+	valueOf, ok := licenseSortColumns[p.Sort]
+	if !ok {
+		return nil, ErrInvalidSortColumn
+	}
+	if p.Limit < 1 {
+		p.Limit = 10
+	}
+
+	sortCol := clause.Column{Name: p.Sort}
+	idCol := clause.Column{Name: "id"}
+	db := f.apply(database.DB.Model(&model.License{})).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: sortCol, Desc: p.Desc},
+			{Column: idCol, Desc: p.Desc},
+		}})
+
+	if p.Cursor != "" {
+		cur, value, err := decodeLicenseCursor(p.Cursor, valueOf(&model.License{}))
+		if err != nil || cur.Sort != p.Sort || cur.Desc != p.Desc {
+			return nil, ErrInvalidCursor
+		}
+		op := ">"
+		if p.Desc {
+			op = "<"
+		}
+		db = db.Where(clause.Expr{
+			SQL:  "(? " + op + " ? OR (? = ? AND ? " + op + " ?))",
+			Vars: []interface{}{sortCol, value, sortCol, value, idCol, cur.ID},
+		})
+	} else if p.Offset > 0 {
+		db = db.Offset(p.Offset)
+	}
+
+	// 多取一条判断是否还有下一页
+	var licenses []model.License
+	if err := db.Limit(p.Limit + 1).Find(&licenses).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LicensePageResult{Licenses: licenses}
+	if len(licenses) > p.Limit {
+		result.Licenses = licenses[:p.Limit]
+		last := &result.Licenses[p.Limit-1]
+		value, err := json.Marshal(valueOf(last))
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(licenseCursor{Sort: p.Sort, Desc: p.Desc, Value: value, ID: last.ID})
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return result, nil
+}
+
+// decodeLicenseCursor 解析游标，排序值按 zero 的类型解码
+func decodeLicenseCursor(s string, zero interface{}) (*licenseCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	cur := new(licenseCursor)
+	if err := json.Unmarshal(data, cur); err != nil {
+		return nil, nil, err
+	}
+
+	switch zero.(type) {
+	case time.Time:
+		var t time.Time
+		err = json.Unmarshal(cur.Value, &t)
+		return cur, t, err
+	default:
+		var v string
+		err = json.Unmarshal(cur.Value, &v)
+		return cur, v, err
+	}
+}
+
+// UsageFilter 查询使用记录的条件，零值字段不参与过滤
+type UsageFilter struct {
+	LicenseKey string
+	ProductId  string
+	IPAddress  string
+	Action     string
+	Reason     string
+	FailedOnly bool // 只查询失败的校验和激活
+	Since      time.Time
+	Until      time.Time
+	Offset     int
+	Limit      int // 0 表示不限制
+}
+
+// apply 将过滤条件应用到使用记录查询，不包括分页
+func (f UsageFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.LicenseKey != "" {
+		db = db.Where("license_key = ?", f.LicenseKey)
+	}
+	if f.ProductId != "" {
+		db = db.Where("product_id = ?", f.ProductId)
+	}
+	if f.IPAddress != "" {
+		db = db.Where("ip_address = ?", f.IPAddress)
+	}
+	if f.Action != "" {
+		db = db.Where("action = ?", f.Action)
+	}
+	if f.Reason != "" {
+		db = db.Where("reason = ?", f.Reason)
+	}
+	if f.FailedOnly {
+		db = db.Where(failedReason)
+	}
+	if !f.Since.IsZero() {
+		db = db.Where("timestamp >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		db = db.Where("timestamp < ?", f.Until)
+	}
+	return db
+}
+
+// ListUsages 按条件查询许可证使用记录，按时间倒序排列
+func ListUsages(f UsageFilter) ([]model.LicenseUsage, error) {
+	db := f.apply(database.DB.Model(&model.LicenseUsage{}))
+	if f.Offset > 0 {
+		db = db.Offset(f.Offset)
+	}
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+
+	var usages []model.LicenseUsage
+	if err := db.Order("timestamp DESC, id DESC").Find(&usages).Error; err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
+
+// CountUsages 统计满足条件的使用记录数，忽略分页
+func CountUsages(f UsageFilter) (int64, error) {
+	var total int64
+	err := f.apply(database.DB.Model(&model.LicenseUsage{})).Count(&total).Error
+	return total, err
+}
+
+// usageGroupColumns 使用记录可分组统计的字段
+var usageGroupColumns = map[string]string{
+	"key":     "license_key",
+	"ip":      "ip_address",
+	"product": "product_id",
+	"reason":  "reason",
+}
+
+// UsageGroup 分组统计的一行
+type UsageGroup struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// GroupUsages 按许可证、IP、产品或原因统计满足条件的使用记录数，按数量倒序排列
+func GroupUsages(f UsageFilter, groupBy string) ([]UsageGroup, error) {
+	column, ok := usageGroupColumns[groupBy]
+	if !ok {
+		return nil, ErrInvalidGroupBy
+	}
+
+	db := f.apply(database.DB.Model(&model.LicenseUsage{})).
+		Select(column + " AS value, COUNT(*) AS count").
+		Group(column).
+		Order("count DESC, value ASC")
+	if f.Offset > 0 {
+		db = db.Offset(f.Offset)
+	}
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+
+	groups := make([]UsageGroup, 0)
+	if err := db.Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/telemetry"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginReason 登录结果的原因代码，记录在登录日志中
+type LoginReason string
+
+const (
+	LoginOK          LoginReason = "ok"
+	LoginUnknownUser LoginReason = "unknown_user"
+	LoginBadPassword LoginReason = "bad_password"
+	LoginInactive    LoginReason = "inactive"  // 密码正确，但用户已停用
+	LoginLocked      LoginReason = "locked"    // 密码正确，但用户已被管理员锁定
+	LoginThrottled   LoginReason = "throttled" // 连续失败次数过多，未校验密码
+)
+
+// 登录失败限制：同一用户名或 IP 连续失败达到阈值后，下一次尝试须等待逐步加倍的时间，
+// 继续失败则临时锁定。超过 LoginFailureWindow 没有失败或锁定结束后重新计数
+const (
+	LoginFailureWindow = 15 * time.Minute
+	LoginLockDuration  = 15 * time.Minute
+	LoginMaxDelay      = time.Minute
+)
+
+// 登录失败的统计范围
+const (
+	ThrottleUsername = "username"
+	ThrottleIP       = "ip"
+)
+
+// loginLimit 统计范围的失败次数阈值
+type loginLimit struct {
+	DelayAfter int // 达到后每次尝试前需要等待
+	LockAfter  int // 达到后临时锁定
+}
+
+// loginLimits 同一 IP 可能有多个用户，阈值高于用户名
+var loginLimits = map[string]loginLimit{
+	ThrottleUsername: {DelayAfter: 3, LockAfter: 10},
+	ThrottleIP:       {DelayAfter: 10, LockAfter: 50},
+}
+
+// dummyPasswordHash 用户不存在时用于比较的哈希，成本与用户密码相同
+const dummyPasswordHash = "$2a$10$lusuNmexhM6S7ux8dwk0SOqIt9kXHTC0DPCgaA.aNsO2jv2p0SnNW"
+
+var (
+	ErrInvalidUserStatus = errors.New("无效的用户状态")
+	ErrUserInactive      = errors.New("账户已停用")
+	ErrUserLocked        = errors.New("账户已被锁定，请联系管理员")
+)
+
+// LoginResult 登录结果，Reason 为 LoginOK 时登录成功
+type LoginResult struct {
+	User       *model.User // 用户不存在时为 nil
+	Reason     LoginReason
+	RetryAfter time.Duration // Reason 为 LoginThrottled 时需要等待的时间
+}
+
+// Login 校验用户名和密码。校验密码前先占用一次尝试并计入失败次数，
+// 并发的请求不能绕过限制，密码正确后再撤销。密码正确后才检查用户状态，
+// 避免通过响应区分账户是否存在或被停用。用户不存在和密码错误都计入失败次数
+func Login(username, password, ip string, now time.Time) (*LoginResult, error) {
+	wait, err := reserveLoginAttempt(username, ip, now)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return &LoginResult{Reason: LoginThrottled, RetryAfter: wait}, nil
+	}
+
+	var user model.User
+	err = database.DB.Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 同样计算一次哈希，避免通过响应时间判断用户是否存在
+		bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return &LoginResult{Reason: LoginUnknownUser}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return &LoginResult{User: &user, Reason: LoginBadPassword}, nil
+	}
+	if err := releaseLoginAttempt(username, ip); err != nil {
+		return nil, err
+	}
+
+	switch user.Status {
+	case model.UserActive:
+		return &LoginResult{User: &user, Reason: LoginOK}, nil
+	case model.UserLocked:
+		return &LoginResult{User: &user, Reason: LoginLocked}, nil
+	default:
+		return &LoginResult{User: &user, Reason: LoginInactive}, nil
+	}
+}
+
+// RecordLogin 保存一次登录尝试，失败不影响登录结果
+func RecordLogin(entry *model.LoginLog, client telemetry.Client, userAgent string) {
+	entry.IP = client.IPAddress
+	entry.UserAgent = userAgent
+	entry.Country = client.Country
+	entry.Device = client.Device
+	entry.Platform = client.Platform
+	if entry.Reason == string(LoginOK) {
+		entry.Status = "success"
+	} else {
+		entry.Status = "failed"
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if err := database.DB.Create(entry).Error; err != nil {
+		log.Printf("保存登录日志失败: %v", err)
+	}
+}
+
+// CheckLoginThrottle 返回用户名或 IP 需要等待多久才能再次尝试登录，为 0 时允许登录
+func CheckLoginThrottle(username, ip string, now time.Time) (time.Duration, error) {
+	var throttles []model.LoginThrottle
+	err := database.DB.
+		Where("(scope = ? AND subject = ?) OR (scope = ? AND subject = ?)",
+			ThrottleUsername, throttleSubject(username), ThrottleIP, ip).
+		Find(&throttles).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for i := range throttles {
+		if w := throttleWait(&throttles[i], now); w > wait {
+			wait = w
+		}
+	}
+	return wait, nil
+}
+
+// RecordLoginFailure 增加用户名和 IP 的连续失败次数
+func RecordLoginFailure(username, ip string, now time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, t := range throttleKeys(username, ip) {
+			if _, err := recordThrottleFailure(tx, t.scope, t.subject, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// errLoginThrottled 占用尝试时发现需要等待，回滚本次计入的失败次数
+var errLoginThrottled = errors.New("login throttled")
+
+// reserveLoginAttempt 检查失败限制并计入一次失败，返回需要等待的时间，需要等待时不计入。
+// 计数在事务中原子递增并锁定统计行，并发的请求依次看到前一个请求计入的次数
+func reserveLoginAttempt(username, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, t := range throttleKeys(username, ip) {
+			w, err := recordThrottleFailure(tx, t.scope, t.subject, now)
+			if err != nil {
+				return err
+			}
+			wait = max(wait, w)
+		}
+		if wait > 0 {
+			return errLoginThrottled
+		}
+		return nil
+	})
+	if errors.Is(err, errLoginThrottled) {
+		return wait, nil
+	}
+	return 0, err
+}
+
+// releaseLoginAttempt 密码正确后撤销 reserveLoginAttempt 计入的失败：
+// 清除用户名的失败次数，IP 的失败次数减一
+func releaseLoginAttempt(username, ip string) error {
+	if err := ResetLoginFailures(username); err != nil {
+		return err
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.LoginThrottle{}).
+			Where("scope = ? AND subject = ? AND failures > 0", ThrottleIP, ip).
+			Update("failures", gorm.Expr("failures - 1"))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		// 锁定期间的尝试不会走到这里，未达到阈值时的锁定来自本次计入的失败
+		return tx.Model(&model.LoginThrottle{}).
+			Where("scope = ? AND subject = ? AND failures < ?", ThrottleIP, ip, loginLimits[ThrottleIP].LockAfter).
+			Update("locked_until", time.Time{}).Error
+	})
+}
+
+// ResetLoginFailures 登录成功后清除用户名的失败次数。IP 的失败次数保留，
+// 否则可以用自己的账户登录来绕过对其他账户的尝试限制
+func ResetLoginFailures(username string) error {
+	return database.DB.
+		Where("scope = ? AND subject = ?", ThrottleUsername, throttleSubject(username)).
+		Delete(&model.LoginThrottle{}).Error
+}
+
+// UnlockUser 清除用户名及该用户最近登录失败的 IP 的失败次数，并将被锁定的用户恢复为 active
+func UnlockUser(username string) (*model.User, error) {
+	var user model.User
+	err := database.DB.Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ResetLoginFailures(user.Username); err != nil {
+		return nil, err
+	}
+
+	// 锁定仍可能生效的时间内，以该用户名登录失败过的 IP
+	var ips []string
+	if err := database.DB.Model(&model.LoginLog{}).
+		Where("LOWER(username) = ? AND status = ? AND created_at >= ?",
+			throttleSubject(user.Username), "failed", time.Now().Add(-LoginFailureWindow-LoginLockDuration)).
+		Distinct().
+		Pluck("ip", &ips).Error; err != nil {
+		return nil, err
+	}
+	if len(ips) > 0 {
+		if err := database.DB.
+			Where("scope = ? AND subject IN ?", ThrottleIP, ips).
+			Delete(&model.LoginThrottle{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Status == model.UserLocked {
+		if err := SetUserStatus(&user, model.UserActive); err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// SetUserStatus 修改用户状态，非 active 的用户已签发的令牌随即失效
+func SetUserStatus(user *model.User, status string) error {
+	valid := false
+	for _, s := range model.UserStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		return ErrInvalidUserStatus
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	user.Status = status
+	user.UpdatedAt = now
+	return nil
+}
+
+// UserStatusError 非 active 用户登录或使用令牌时返回的错误
+func UserStatusError(status string) error {
+	if status == model.UserLocked {
+		return ErrUserLocked
+	}
+	return ErrUserInactive
+}
+
+// PruneLoginThrottles 清理已不再限制登录的失败统计
+func PruneLoginThrottles() (int64, error) {
+	now := time.Now()
+	result := database.DB.
+		Where("last_failed_at < ? AND locked_until < ?", now.Add(-LoginFailureWindow), now).
+		Delete(&model.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
+
+// throttleSubject 用户名不区分大小写，MySQL 等数据库默认的排序规则下大小写不同的用户名是同一账户
+func throttleSubject(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// throttleKeys 一次登录尝试需要统计的用户名和 IP
+func throttleKeys(username, ip string) []struct{ scope, subject string } {
+	return []struct{ scope, subject string }{
+		{ThrottleUsername, throttleSubject(username)},
+		{ThrottleIP, ip},
+	}
+}
+
+// recordThrottleFailure 原子地增加一个统计范围的失败次数，达到阈值时锁定。
+// 返回计入本次失败之前需要等待的时间，由调用方决定是否回滚
+func recordThrottleFailure(tx *gorm.DB, scope, subject string, now time.Time) (time.Duration, error) {
+	// 不存在时插入，存在时递增，统计行在事务结束前保持锁定
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "subject"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"failures": gorm.Expr("login_throttles.failures + 1")}),
+	}).Create(&model.LoginThrottle{Scope: scope, Subject: subject, Failures: 1, LastFailedAt: now}).Error; err != nil {
+		return 0, err
+	}
+
+	var t model.LoginThrottle
+	if err := tx.Where("scope = ? AND subject = ?", scope, subject).First(&t).Error; err != nil {
+		return 0, err
+	}
+
+	// 按计入本次失败之前的状态计算等待时间和是否重新计数
+	before := t
+	before.Failures--
+	wait := throttleWait(&before, now)
+	if throttleStale(&before, now) {
+		t.Failures = 1
+		t.LockedUntil = time.Time{}
+	}
+	t.LastFailedAt = now
+	if t.Failures >= loginLimits[scope].LockAfter {
+		t.LockedUntil = now.Add(LoginLockDuration)
+	}
+	return wait, tx.Model(&t).Select("failures", "last_failed_at", "locked_until").Updates(&t).Error
+}
+
+// throttleWait 距离允许下一次尝试还需要等待的时间
+func throttleWait(t *model.LoginThrottle, now time.Time) time.Duration {
+	if now.Before(t.LockedUntil) {
+		return t.LockedUntil.Sub(now)
+	}
+	if throttleStale(t, now) {
+		return 0
+	}
+	next := t.LastFailedAt.Add(loginLimits[t.Scope].delay(t.Failures))
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// throttleStale 锁定已结束或超过统计窗口没有失败，失败次数需要重新计算
+func throttleStale(t *model.LoginThrottle, now time.Time) bool {
+	return !t.LockedUntil.IsZero() && !now.Before(t.LockedUntil) ||
+		now.Sub(t.LastFailedAt) >= LoginFailureWindow
+}
+
+// delay 连续失败 failures 次后下一次尝试前需要等待的时间，从 1 秒开始每次加倍
+func (l loginLimit) delay(failures int) time.Duration {
+	if failures < l.DelayAfter {
+		return 0
+	}
+	d := time.Second
+	for i := l.DelayAfter; i < failures && d < LoginMaxDelay; i++ {
+		d *= 2
+	}
+	if d > LoginMaxDelay {
+		d = LoginMaxDelay
+	}
+	return d
+}
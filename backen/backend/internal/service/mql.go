@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/mqlgen"
+	"license-management-system/internal/util"
+	"license-management-system/pkg/licensing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSigningKeyMissing 未加载签名私钥，无法提供客户端校验响应所需的公钥
+var ErrSigningKeyMissing = errors.New("未加载签名私钥")
+
+// ClientIncludeOptions 生成 MQL 客户端 include 文件的参数
+type ClientIncludeOptions struct {
+	ServerURL string
+	KeyID     string // 嵌入的 API 密钥，为空时生成占位，由开发者自行填写
+	Retries   int
+}
+
+// GenerateClientInclude 生成产品的 MQL4/MQL5 客户端 include 文件，返回文件名和源码。
+// 嵌入的 API 密钥须属于该产品且未失效
+func GenerateClientInclude(productID string, opts ClientIncludeOptions) (string, []byte, error) {
+	product, err := GetProduct(productID)
+	if err != nil {
+		return "", nil, err
+	}
+	pub := util.PublicKey()
+	if pub == nil {
+		return "", nil, ErrSigningKeyMissing
+	}
+
+	cfg := mqlgen.Config{
+		ServerURL:   opts.ServerURL,
+		ProductId:   product.Code,
+		ProductName: product.Name,
+		PublicKey:   licensing.EncodePublicKey(pub),
+		Retries:     opts.Retries,
+	}
+	if opts.KeyID != "" {
+		var key model.ClientAPIKey
+		err := database.DB.Where("key_id = ? AND product_id = ?", opts.KeyID, product.Code).First(&key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, ErrClientKeyNotFound
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if !key.IsActive(time.Now()) {
+			return "", nil, ErrClientKeyInvalid
+		}
+		cfg.KeyID = key.KeyID
+		cfg.Secret = key.Secret
+	}
+
+	var buf bytes.Buffer
+	if err := mqlgen.Generate(&buf, cfg); err != nil {
+		return "", nil, err
+	}
+	return mqlgen.FileName(product.Code), buf.Bytes(), nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/keygen"
+	"license-management-system/internal/model"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+	"gorm.io/gorm"
+)
+
+// DefaultValidityDays 产品未配置有效期时生成许可证的默认有效天数
+const DefaultValidityDays = 30
+
+// MaxOfflineGraceHours 离线凭证有效期的上限，过长的离线期间吊销无法及时生效
+const MaxOfflineGraceHours = 30 * 24
+
+var (
+	ErrProductNotFound     = errors.New("产品不存在")
+	ErrInvalidProductCode  = errors.New("产品代码只能包含字母、数字、下划线和短横线")
+	ErrInvalidProductName  = errors.New("产品名称不能为空")
+	ErrInvalidProductInput = errors.New("有效天数和默认席位数不能为负数")
+	ErrInvalidKeyPrefix    = errors.New("无效的密钥前缀")
+	ErrInvalidVersion      = errors.New("版本号不是有效的语义化版本")
+	ErrInvalidOfflineGrace = errors.New("离线凭证有效期须在 0 到 720 小时之间")
+)
+
+var productCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidateProduct 校验产品字段
+func ValidateProduct(product *model.Product) error {
+	if !productCodePattern.MatchString(product.Code) {
+		return ErrInvalidProductCode
+	}
+	if product.Name == "" {
+		return ErrInvalidProductName
+	}
+	if product.ValidityDays < 0 || product.DefaultMaxSeats < 0 {
+		return ErrInvalidProductInput
+	}
+	if product.OfflineGraceHours < 0 || product.OfflineGraceHours > MaxOfflineGraceHours {
+		return ErrInvalidOfflineGrace
+	}
+	if keygen.ValidatePrefix(product.KeyPrefix) != nil {
+		return ErrInvalidKeyPrefix
+	}
+	if product.CurrentVersion != "" {
+		if _, err := semver.NewVersion(product.CurrentVersion); err != nil {
+			return ErrInvalidVersion
+		}
+	}
+	if err := ValidateVersionRule(product.VersionConstraint, product.MaxMajorVersion); err != nil {
+		return err
+	}
+	if err := product.EntitlementSchema.ValidateSchema(); err != nil {
+		return err
+	}
+	return product.EntitlementSchema.Validate(product.DefaultEntitlements)
+}
+
+// GetProduct 按产品代码获取产品
+func GetProduct(code string) (*model.Product, error) {
+	var product model.Product
+	err := database.DB.Where("code = ?", code).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// LoadTrialPolicy 加载产品的试用策略，未配置时保持为空
+func LoadTrialPolicy(product *model.Product) error {
+	var policy model.TrialPolicy
+	err := database.DB.Where("product_id = ?", product.Code).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		product.TrialPolicy = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	product.TrialPolicy = &policy
+	return nil
+}
+
+// CountLicensesByProduct 统计目录中每个产品的许可证数量，没有许可证的产品计为 0
+func CountLicensesByProduct() (map[string]int, error) {
+	var products []model.Product
+	if err := database.DB.Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(products))
+	codes := make([]string, 0, len(products))
+	for _, p := range products {
+		counts[p.Code] = 0
+		codes = append(codes, p.Code)
+	}
+	if len(codes) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ProductId string
+		Count     int
+	}
+	if err := database.DB.Model(&model.License{}).
+		Select("product_id, COUNT(*) AS count").
+		Where("product_id IN ?", codes).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ProductId] = row.Count
+	}
+
+	return counts, nil
+}
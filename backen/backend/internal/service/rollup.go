@@ -0,0 +1,387 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// usageRollupCheckpoint 每日使用量汇总在 RollupCheckpoint 中的名称
+const usageRollupCheckpoint = "daily_usage"
+
+// failedReason 校验或激活失败的条件，升级前的记录没有结果，视为成功
+const failedReason = "reason <> '' AND reason <> 'ok'"
+
+// RollupUsage 增量汇总每日使用量：重新计算上次汇总后有新使用记录的日期，
+// 只有会话更新的日期只重新计算会话数和时长。返回重新计算的天数
+func RollupUsage() (int, error) {
+	var checkpoint model.RollupCheckpoint
+	err := database.DB.Where("name = ?", usageRollupCheckpoint).First(&checkpoint).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	checkpoint.Name = usageRollupCheckpoint
+	runAt := time.Now()
+
+	// 先确定本次汇总的范围，汇总期间写入的记录留到下次
+	var lastID uint
+	if err := database.DB.Model(&model.LicenseUsage{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&lastID).Error; err != nil {
+		return 0, err
+	}
+
+	var usageDays []string
+	if lastID > checkpoint.LastID {
+		day := database.DateBucket(database.DB, "timestamp", database.BucketDay)
+		if err := database.DB.Model(&model.LicenseUsage{}).
+			Select(day).
+			Where("id > ? AND id <= ?", checkpoint.LastID, lastID).
+			Group(day).
+			Pluck(day, &usageDays).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	// 会话的时长随心跳增加，按开始日期重新计算
+	day := database.DateBucket(database.DB, "started_at", database.BucketDay)
+	var sessionDays []string
+	if err := database.DB.Model(&model.UsageSession{}).
+		Select(day).
+		Where("last_seen_at >= ? OR ended_at >= ?", checkpoint.LastRunAt, checkpoint.LastRunAt).
+		Group(day).
+		Pluck(day, &sessionDays).Error; err != nil {
+		return 0, err
+	}
+
+	// 有新使用记录的日期整体重新计算；会话开始的日期可能早于使用记录的保留期限，
+	// 原始使用记录已被清理，整体重新计算会清空当天的使用次数
+	days := make(map[string]bool)
+	for _, d := range usageDays {
+		days[d] = true
+	}
+	for _, d := range sessionDays {
+		if !days[d] {
+			days[d] = false
+		}
+	}
+
+	for d, full := range days {
+		date, err := time.ParseInLocation("2006-01-02", d, time.Local)
+		if err != nil {
+			continue
+		}
+		if full {
+			_, err = RollupDailyUsage(date)
+		} else {
+			err = RollupDailySessions(date)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if lastID > checkpoint.LastID {
+		checkpoint.LastID = lastID
+	}
+	checkpoint.LastRunAt = runAt
+	if err := database.DB.Save(&checkpoint).Error; err != nil {
+		return 0, err
+	}
+	return len(days), nil
+}
+
+// RollupDailyUsage 重新计算指定日期每个许可证和每个产品的使用量，返回写入的许可证记录数
+func RollupDailyUsage(day time.Time) (int, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	dayKey := start.Format("2006-01-02")
+
+	usages := func() *gorm.DB {
+		return database.DB.Model(&model.LicenseUsage{}).Where("timestamp >= ? AND timestamp < ?", start, end)
+	}
+
+	// 使用记录按记录中的产品归属，许可证不存在的失败也计入客户端请求的产品
+	var counts []struct {
+		LicenseKey         string
+		ProductId          string
+		Verifies           int64
+		Activations        int64
+		VerifyFailures     int64
+		ActivationFailures int64
+	}
+	if err := usages().
+		Select("license_key, product_id, " +
+			"SUM(CASE WHEN action = 'verify' THEN 1 ELSE 0 END) AS verifies, " +
+			"SUM(CASE WHEN action = 'activate' THEN 1 ELSE 0 END) AS activations, " +
+			"SUM(CASE WHEN action = 'verify' AND " + failedReason + " THEN 1 ELSE 0 END) AS verify_failures, " +
+			"SUM(CASE WHEN action = 'activate' AND " + failedReason + " THEN 1 ELSE 0 END) AS activation_failures").
+		Group("license_key, product_id").
+		Scan(&counts).Error; err != nil {
+		return 0, err
+	}
+
+	var accounts []struct {
+		LicenseKey string
+		ProductId  string
+		Account    string
+	}
+	if err := usages().
+		Distinct("license_key", "product_id", "account").
+		Where("account <> ''").
+		Scan(&accounts).Error; err != nil {
+		return 0, err
+	}
+
+	var dimensions []struct {
+		LicenseKey string
+		ProductId  string
+		Country    string
+		Device     string
+		Platform   string
+		Count      int64
+	}
+	if err := usages().
+		Select("license_key, product_id, country, device, platform, COUNT(*) AS count").
+		Group("license_key, product_id, country, device, platform").
+		Scan(&dimensions).Error; err != nil {
+		return 0, err
+	}
+
+	sessions, err := dailySessions(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	// 升级前的使用记录没有产品，会话也不记录产品，按许可证查询所属产品
+	keys := make([]string, 0, len(counts)+len(sessions))
+	for _, c := range counts {
+		if c.ProductId == "" {
+			keys = append(keys, c.LicenseKey)
+		}
+	}
+	for _, s := range sessions {
+		keys = append(keys, s.LicenseKey)
+	}
+	products, err := licenseProducts(keys)
+	if err != nil {
+		return 0, err
+	}
+	productOf := func(key, productID string) string {
+		if productID != "" {
+			return productID
+		}
+		return products[key]
+	}
+
+	productRollup := make(map[string]*model.DailyProductUsage)
+	rollupOf := func(productID string) *model.DailyProductUsage {
+		rollup, ok := productRollup[productID]
+		if !ok {
+			rollup = &model.DailyProductUsage{Day: dayKey, ProductId: productID}
+			productRollup[productID] = rollup
+		}
+		return rollup
+	}
+
+	// 不同交易账号数，许可证和产品分别去重
+	licenseAccounts := make(map[string]map[string]bool)
+	productAccounts := make(map[string]map[string]bool)
+	for _, a := range accounts {
+		productID := productOf(a.LicenseKey, a.ProductId)
+		if licenseAccounts[a.LicenseKey] == nil {
+			licenseAccounts[a.LicenseKey] = make(map[string]bool)
+		}
+		licenseAccounts[a.LicenseKey][a.Account] = true
+		if productAccounts[productID] == nil {
+			productAccounts[productID] = make(map[string]bool)
+		}
+		productAccounts[productID][a.Account] = true
+	}
+
+	// 同一密钥可能以不同产品请求，许可证记录合并计数，产品记录按产品分别计数
+	licenseRollups := make([]model.DailyLicenseUsage, 0, len(counts))
+	licenseIndex := make(map[string]int)
+	productLicenses := make(map[string]map[string]bool)
+	for _, c := range counts {
+		productID := productOf(c.LicenseKey, c.ProductId)
+		i, ok := licenseIndex[c.LicenseKey]
+		if !ok {
+			i = len(licenseRollups)
+			licenseIndex[c.LicenseKey] = i
+			licenseRollups = append(licenseRollups, model.DailyLicenseUsage{
+				Day:        dayKey,
+				LicenseKey: c.LicenseKey,
+				ProductId:  productID,
+				Accounts:   int64(len(licenseAccounts[c.LicenseKey])),
+			})
+		}
+		licenseRollups[i].Verifies += c.Verifies
+		licenseRollups[i].Activations += c.Activations
+		licenseRollups[i].VerifyFailures += c.VerifyFailures
+		licenseRollups[i].ActivationFailures += c.ActivationFailures
+
+		rollup := rollupOf(productID)
+		if productLicenses[productID] == nil {
+			productLicenses[productID] = make(map[string]bool)
+		}
+		if !productLicenses[productID][c.LicenseKey] {
+			productLicenses[productID][c.LicenseKey] = true
+			rollup.Licenses++
+		}
+		rollup.Verifies += c.Verifies
+		rollup.Activations += c.Activations
+		rollup.VerifyFailures += c.VerifyFailures
+		rollup.ActivationFailures += c.ActivationFailures
+	}
+	for _, s := range sessions {
+		rollup := rollupOf(products[s.LicenseKey])
+		rollup.Sessions += s.Count
+		rollup.SessionSeconds += s.Seconds
+	}
+
+	productRollups := make([]model.DailyProductUsage, 0, len(productRollup))
+	for productID, rollup := range productRollup {
+		rollup.Accounts = int64(len(productAccounts[productID]))
+		productRollups = append(productRollups, *rollup)
+	}
+
+	// 按产品汇总国家、设备和操作系统的分布，无法识别的值保存为空
+	type dimensionKey struct{ productID, dimension, value string }
+	dimensionCounts := make(map[dimensionKey]int64)
+	for _, d := range dimensions {
+		productID := productOf(d.LicenseKey, d.ProductId)
+		dimensionCounts[dimensionKey{productID, "country", d.Country}] += d.Count
+		dimensionCounts[dimensionKey{productID, "device", d.Device}] += d.Count
+		dimensionCounts[dimensionKey{productID, "platform", d.Platform}] += d.Count
+	}
+	dimensionRollups := make([]model.DailyUsageDimension, 0, len(dimensionCounts))
+	for k, count := range dimensionCounts {
+		dimensionRollups = append(dimensionRollups, model.DailyUsageDimension{
+			Day:       dayKey,
+			ProductId: k.productID,
+			Dimension: k.dimension,
+			Value:     k.value,
+			Count:     count,
+		})
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []interface{}{&model.DailyLicenseUsage{}, &model.DailyProductUsage{}, &model.DailyUsageDimension{}} {
+			if err := tx.Where("day = ?", dayKey).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		if len(licenseRollups) > 0 {
+			if err := tx.CreateInBatches(licenseRollups, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(productRollups) > 0 {
+			if err := tx.CreateInBatches(productRollups, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(dimensionRollups) > 0 {
+			return tx.CreateInBatches(dimensionRollups, 100).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(licenseRollups), nil
+}
+
+// RollupDailySessions 只重新计算指定日期每个产品的会话数和时长，保留已汇总的使用次数
+func RollupDailySessions(day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	dayKey := start.Format("2006-01-02")
+
+	sessions, err := dailySessions(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		keys = append(keys, s.LicenseKey)
+	}
+	products, err := licenseProducts(keys)
+	if err != nil {
+		return err
+	}
+
+	totals := make(map[string]*model.DailyProductUsage)
+	for _, s := range sessions {
+		productID := products[s.LicenseKey]
+		if totals[productID] == nil {
+			totals[productID] = &model.DailyProductUsage{Day: dayKey, ProductId: productID}
+		}
+		totals[productID].Sessions += s.Count
+		totals[productID].SessionSeconds += s.Seconds
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.DailyProductUsage{}).
+			Where("day = ?", dayKey).
+			Updates(map[string]interface{}{"sessions": 0, "session_seconds": 0}).Error; err != nil {
+			return err
+		}
+		for productID, total := range totals {
+			result := tx.Model(&model.DailyProductUsage{}).
+				Where("day = ? AND product_id = ?", dayKey, productID).
+				Updates(map[string]interface{}{"sessions": total.Sessions, "session_seconds": total.SessionSeconds})
+			if result.Error != nil {
+				return result.Error
+			}
+			// 当天没有使用记录的产品还没有汇总记录
+			if result.RowsAffected == 0 {
+				if err := tx.Create(total).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// dailySession 一个许可证在一天内开始的会话数和总时长
+type dailySession struct {
+	LicenseKey string
+	Count      int64
+	Seconds    int64
+}
+
+// dailySessions 按许可证统计 [start, end) 内开始的会话
+func dailySessions(start, end time.Time) ([]dailySession, error) {
+	var sessions []dailySession
+	err := database.DB.Model(&model.UsageSession{}).
+		Select("license_key, COUNT(*) AS count, COALESCE(SUM(duration_seconds), 0) AS seconds").
+		Where("started_at >= ? AND started_at < ?", start, end).
+		Group("license_key").
+		Scan(&sessions).Error
+	return sessions, err
+}
+
+// licenseProducts 查询许可证所属的产品，包括已删除的许可证
+func licenseProducts(keys []string) (map[string]string, error) {
+	products := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += 500 {
+		end := min(start+500, len(keys))
+		var licenses []model.License
+		if err := database.DB.Unscoped().
+			Select("key", "product_id").
+			Scopes(database.ByLicenseKeys(keys[start:end])).
+			Find(&licenses).Error; err != nil {
+			return nil, err
+		}
+		for _, license := range licenses {
+			products[license.Key] = license.ProductId
+		}
+	}
+	return products, nil
+}
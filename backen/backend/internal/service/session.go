@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/model"
+	"license-management-system/internal/telemetry"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startSession 签出新租约时开始使用会话
+func startSession(tx *gorm.DB, lease *model.LicenseLease, client telemetry.Client) error {
+	return tx.Create(&model.UsageSession{
+		LeaseID:    lease.LeaseID,
+		LicenseKey: lease.LicenseKey,
+		ClientID:   lease.ClientID,
+		IPAddress:  client.IPAddress,
+		Country:    client.Country,
+		Device:     client.Device,
+		Platform:   client.Platform,
+		StartedAt:  lease.RenewedAt,
+		LastSeenAt: lease.RenewedAt,
+	}).Error
+}
+
+// touchSession 心跳时更新会话的最后活跃时间和时长。
+// 升级前签出的租约没有会话记录，以租约的签出时间补建
+func touchSession(tx *gorm.DB, lease *model.LicenseLease) error {
+	var session model.UsageSession
+	err := tx.Where("lease_id = ?", lease.LeaseID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		session = model.UsageSession{
+			LeaseID:    lease.LeaseID,
+			LicenseKey: lease.LicenseKey,
+			ClientID:   lease.ClientID,
+			IPAddress:  lease.IPAddress,
+			StartedAt:  lease.CreatedAt,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	session.LastSeenAt = lease.RenewedAt
+	session.Heartbeats++
+	session.DurationSeconds = sessionSeconds(session.StartedAt, session.LastSeenAt)
+	return tx.Save(&session).Error
+}
+
+// endSession 释放租约时结束会话
+func endSession(tx *gorm.DB, leaseID string, now time.Time) error {
+	var session model.UsageSession
+	err := tx.Where("lease_id = ?", leaseID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	session.LastSeenAt = now
+	session.EndedAt = now
+	session.DurationSeconds = sessionSeconds(session.StartedAt, now)
+	return tx.Save(&session).Error
+}
+
+func sessionSeconds(start, end time.Time) int64 {
+	if end.Before(start) {
+		return 0
+	}
+	return int64(end.Sub(start) / time.Second)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/telemetry"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatisticsQuery 统计条件
+type StatisticsQuery struct {
+	Start, End  time.Time           // 统计 [Start, End) 期间的使用量，以天为单位
+	ProductId   string              // 为空时统计全部产品
+	Granularity database.BucketUnit // 每日使用量按天、周或月汇总，默认按天
+}
+
+// GetLicenseStatistics 统计许可证数量和期间的使用量。
+// 使用量读取定时任务汇总的每日使用量，尚未汇总的最新记录不计入
+func GetLicenseStatistics(q StatisticsQuery) (*model.LicenseStatistics, error) {
+	db := database.DB
+	if q.Granularity == "" {
+		q.Granularity = database.BucketDay
+	}
+	stats := &model.LicenseStatistics{
+		Granularity:     string(q.Granularity),
+		UsageByCountry:  make(map[string]int),
+		UsageByDevice:   make(map[string]int),
+		UsageByPlatform: make(map[string]int),
+		DailyUsage:      make([]model.DailyUsage, 0),
+	}
+
+	licenses := func() *gorm.DB {
+		query := db.Model(&model.License{})
+		if q.ProductId != "" {
+			query = query.Where("product_id = ?", q.ProductId)
+		}
+		return query
+	}
+
+	// 各状态的许可证数量
+	var statusRows []struct {
+		Status model.LicenseStatus
+		Count  int64
+	}
+	if err := licenses().Select("status, COUNT(*) AS count").Group("status").Scan(&statusRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range statusRows {
+		stats.TotalLicenses += row.Count
+		switch row.Status {
+		case model.LicenseActive:
+			stats.ActiveLicenses = row.Count
+		case model.LicenseExpired:
+			stats.ExpiredLicenses = row.Count
+		case model.LicenseSuspended:
+			stats.SuspendedLicenses = row.Count
+		case model.LicenseRevoked:
+			stats.RevokedLicenses = row.Count
+		}
+	}
+
+	// 即将过期的许可证数（30天内）
+	now := time.Now()
+	if err := licenses().
+		Where("status = ? AND valid_until > ? AND valid_until <= ?", model.LicenseActive, now, now.AddDate(0, 0, 30)).
+		Count(&stats.ExpiringLicenses).Error; err != nil {
+		return nil, err
+	}
+
+	licensesByProduct, err := CountLicensesByProduct()
+	if err != nil {
+		return nil, err
+	}
+	if q.ProductId != "" {
+		licensesByProduct = map[string]int{q.ProductId: licensesByProduct[q.ProductId]}
+	}
+	stats.LicensesByProduct = licensesByProduct
+
+	// 汇总表的日期为 YYYY-MM-DD 文本，按字符串比较即可
+	firstDay := q.Start.Format("2006-01-02")
+	lastDay := q.End.Add(-time.Nanosecond).Format("2006-01-02")
+	rollups := func(table interface{}) *gorm.DB {
+		query := db.Model(table).Where("day >= ? AND day <= ?", firstDay, lastDay)
+		if q.ProductId != "" {
+			query = query.Where("product_id = ?", q.ProductId)
+		}
+		return query
+	}
+
+	// 每个周期使用的许可证数、校验次数、激活次数和失败次数
+	bucket := database.TextDateBucket(db, "day", q.Granularity)
+	var activeRows []struct {
+		Date        string
+		ActiveUsers int
+	}
+	if err := rollups(&model.DailyLicenseUsage{}).
+		Select(bucket + " AS date, COUNT(DISTINCT license_key) AS active_users").
+		Group(bucket).
+		Scan(&activeRows).Error; err != nil {
+		return nil, err
+	}
+	activeUsers := make(map[string]int, len(activeRows))
+	for _, row := range activeRows {
+		activeUsers[row.Date] = row.ActiveUsers
+	}
+
+	var dailyRows []struct {
+		Date               string
+		Verifies           int
+		Activations        int
+		ActivationFailures int
+		Failures           int
+	}
+	if err := rollups(&model.DailyProductUsage{}).
+		Select(bucket + " AS date, SUM(verifies) AS verifies, SUM(activations) AS activations, " +
+			"SUM(activation_failures) AS activation_failures, SUM(verify_failures + activation_failures) AS failures").
+		Group(bucket).
+		Order("date ASC").
+		Scan(&dailyRows).Error; err != nil {
+		return nil, err
+	}
+	layout := "2006-01-02"
+	if q.Granularity == database.BucketMonth {
+		layout = "2006-01"
+	}
+	for _, row := range dailyRows {
+		date, err := time.ParseInLocation(layout, row.Date, time.Local)
+		if err != nil {
+			continue
+		}
+		stats.DailyUsage = append(stats.DailyUsage, model.DailyUsage{
+			Date:           date,
+			ActiveUsers:    activeUsers[row.Date],
+			NewActivations: row.Activations - row.ActivationFailures,
+			TotalChecks:    row.Verifies,
+			Failures:       row.Failures,
+		})
+		stats.TotalActivations += int64(row.Activations)
+		stats.FailedActivations += int64(row.ActivationFailures)
+	}
+
+	// 按国家、设备类型和操作系统统计使用次数，无法识别的归为 unknown
+	var dimensionRows []struct {
+		Dimension string
+		Value     string
+		Count     int
+	}
+	if err := rollups(&model.DailyUsageDimension{}).
+		Select("dimension, value, SUM(count) AS count").
+		Group("dimension, value").
+		Scan(&dimensionRows).Error; err != nil {
+		return nil, err
+	}
+	dimensions := map[string]map[string]int{
+		"country":  stats.UsageByCountry,
+		"device":   stats.UsageByDevice,
+		"platform": stats.UsageByPlatform,
+	}
+	for _, row := range dimensionRows {
+		dst, ok := dimensions[row.Dimension]
+		if !ok {
+			continue
+		}
+		if row.Value == "" {
+			row.Value = telemetry.Unknown
+		}
+		dst[row.Value] += row.Count
+	}
+
+	// 期间开始的浮动许可证会话的平均时长（小时）
+	var sessions struct {
+		Count   int64
+		Seconds float64
+	}
+	if err := rollups(&model.DailyProductUsage{}).
+		Select("COALESCE(SUM(sessions), 0) AS count, COALESCE(SUM(session_seconds), 0) AS seconds").
+		Scan(&sessions).Error; err != nil {
+		return nil, err
+	}
+	stats.TotalSessions = sessions.Count
+	if sessions.Count > 0 {
+		stats.AverageUsageDuration = sessions.Seconds / float64(sessions.Count) / 3600
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/pkg/licensing"
+	"time"
+)
+
+// DefaultOfflineGrace 产品未配置时离线凭证的有效期
+const DefaultOfflineGrace = 24 * time.Hour
+
+var (
+	// ErrTokenRenewalRefused 许可证已吊销、暂停或过期，不再续发离线凭证
+	ErrTokenRenewalRefused = errors.New("许可证不可用，不能续发离线凭证")
+	// ErrTokenTerminalRequired 客户端未提供终端 ID，不绑定终端的凭证可以复制到任意机器使用
+	ErrTokenTerminalRequired = errors.New("未提供终端 ID，不能签发离线凭证")
+)
+
+// OfflineGrace 产品离线凭证的有效期，产品不存在或未配置时使用默认值
+func OfflineGrace(product *model.Product) time.Duration {
+	if product == nil || product.OfflineGraceHours == 0 {
+		return DefaultOfflineGrace
+	}
+	return time.Duration(product.OfflineGraceHours) * time.Hour
+}
+
+// RenewOfflineToken 为校验通过的许可证续发离线凭证，并记录续发时间。
+// 凭证绑定终端，不会晚于许可证的到期时间；许可证被吊销后不再续发，已签发的凭证在有效期后失效
+func RenewOfflineToken(license *model.License, product *model.Product, terminalID, account string, entitlements licensing.Entitlements) (*licensing.Token, error) {
+	if !IsLicenseUsable(license) {
+		return nil, ErrTokenRenewalRefused
+	}
+	if terminalID == "" {
+		return nil, ErrTokenTerminalRequired
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(OfflineGrace(product))
+	if license.ValidUntil.Before(expiresAt) {
+		expiresAt = license.ValidUntil
+	}
+
+	// 续发时间只用于展示，不更新许可证的修改时间
+	if err := database.DB.Model(license).UpdateColumn("token_renewed_at", now).Error; err != nil {
+		return nil, err
+	}
+	license.TokenRenewedAt = now
+
+	return &licensing.Token{
+		Key:          license.Key,
+		ProductId:    license.ProductId,
+		TerminalId:   terminalID,
+		Account:      account,
+		Entitlements: entitlements,
+		IssuedAt:     now,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTrialRepeatWindowDays 未配置时重复申请试用的间隔天数
+	DefaultTrialRepeatWindowDays = 365
+	// MaxTrialDurationDays 试用期上限
+	MaxTrialDurationDays = 90
+)
+
+var (
+	ErrTrialUnavailable  = errors.New("该产品不提供试用")
+	ErrTrialAlreadyUsed  = errors.New("已申请过该产品的试用")
+	ErrInvalidTrialInput = errors.New("无效的试用策略")
+)
+
+// TrialRequest 试用申请
+type TrialRequest struct {
+	ProductId   string
+	Email       string
+	IPAddress   string
+	Fingerprint model.Fingerprint
+}
+
+// ValidateTrialPolicy 校验并补全试用策略
+func ValidateTrialPolicy(policy *model.TrialPolicy) error {
+	if policy.DurationDays < 1 || policy.DurationDays > MaxTrialDurationDays || policy.RepeatWindowDays < 0 {
+		return ErrInvalidTrialInput
+	}
+	if policy.RepeatWindowDays == 0 {
+		policy.RepeatWindowDays = DefaultTrialRepeatWindowDays
+	}
+	return nil
+}
+
+// IssueTrial 按产品的试用策略发放试用许可证，并绑定申请设备。
+// 同一账号、设备指纹、邮箱或IP在间隔期内只能申请一次。
+func IssueTrial(req TrialRequest) (*model.License, error) {
+	if _, err := GetProduct(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	var policy model.TrialPolicy
+	err := database.DB.Where("product_id = ? AND enabled = ?", req.ProductId, true).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTrialUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := GenerateLicenseKey("TRIAL")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	fingerprint := req.Fingerprint.Hash()
+
+	license := &model.License{
+		Key:             key,
+		Status:          model.LicenseActive,
+		ValidUntil:      now.AddDate(0, 0, policy.DurationDays),
+		Entitlements:    policy.Entitlements,
+		UserId:          req.Fingerprint.AccountNumber,
+		ProductId:       req.ProductId,
+		MaxSeats:        1,
+		IsTrial:         true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		LastActivatedAt: now,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		since := now.AddDate(0, 0, -policy.RepeatWindowDays)
+		conditions := tx.Where("account_number = ?", req.Fingerprint.AccountNumber).
+			Or("fingerprint = ?", fingerprint).
+			Or("ip_address = ?", req.IPAddress)
+		if email != "" {
+			conditions = conditions.Or("email = ?", email)
+		}
+
+		var used int64
+		if err := tx.Model(&model.TrialGrant{}).
+			Where("product_id = ? AND created_at >= ?", req.ProductId, since).
+			Where(conditions).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrTrialAlreadyUsed
+		}
+
+		// 同一设备只保留一条记录，超过间隔期的旧记录由本次申请替换
+		if err := tx.Where("product_id = ? AND fingerprint = ?", req.ProductId, fingerprint).
+			Delete(&model.TrialGrant{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(license).Error; err != nil {
+			return err
+		}
+
+		activation := &model.LicenseActivation{
+			LicenseKey:    key,
+			Fingerprint:   fingerprint,
+			TerminalID:    req.Fingerprint.TerminalID,
+			AccountNumber: req.Fingerprint.AccountNumber,
+			MachineHash:   req.Fingerprint.MachineHash,
+			IPAddress:     req.IPAddress,
+			LastSeenAt:    now,
+		}
+		if err := tx.Create(activation).Error; err != nil {
+			return err
+		}
+
+		// 并发的重复申请都通过了上面的检查时，由唯一索引拒绝后提交的一个
+		err := tx.Create(&model.TrialGrant{
+			ProductId:     req.ProductId,
+			LicenseKey:    key,
+			AccountNumber: req.Fingerprint.AccountNumber,
+			Fingerprint:   fingerprint,
+			Email:         email,
+			IPAddress:     req.IPAddress,
+			CreatedAt:     now,
+		}).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrTrialAlreadyUsed
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return license, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength 管理工具设置密码时的最小长度
+const MinPasswordLength = 8
+
+var (
+	ErrUserNotFound     = errors.New("用户不存在")
+	ErrUserExists       = errors.New("用户名或邮箱已存在")
+	ErrPasswordTooShort = errors.New("密码长度不能少于 8 个字符")
+)
+
+// CreateUser 创建指定角色的用户，用户名或邮箱重复时返回 ErrUserExists
+func CreateUser(username, email, password, role string) (*model.User, error) {
+	if len(password) < MinPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+
+	var count int64
+	if err := database.DB.Model(&model.User{}).
+		Where("username = ? OR email = ?", username, email).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUserExists
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Username:  username,
+		Password:  string(hashed),
+		Email:     email,
+		Role:      role,
+		Status:    model.UserActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser 按 ID 获取用户
+func GetUser(id uint) (*model.User, error) {
+	var user model.User
+	err := database.DB.First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResetPassword 重置用户密码
+func ResetPassword(username, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	result := database.DB.Model(&model.User{}).
+		Where("username = ?", username).
+		Updates(map[string]interface{}{
+			"password":   string(hashed),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
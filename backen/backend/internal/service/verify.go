@@ -0,0 +1,246 @@
+package service
+
+import (
+	"errors"
+	"license-management-system/internal/database"
+	"license-management-system/internal/model"
+	"license-management-system/internal/telemetry"
+	"license-management-system/pkg/licensing"
+	"log"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gorm.io/gorm"
+)
+
+// VerifyReason 校验结果的原因代码，供客户端程序判断
+type VerifyReason string
+
+const (
+	ReasonOK                  VerifyReason = "ok"
+	ReasonNotFound            VerifyReason = "not_found"
+	ReasonMismatch            VerifyReason = "mismatch"
+	ReasonFingerprintRequired VerifyReason = "fingerprint_required"
+	ReasonDeviceNotActivated  VerifyReason = "device_not_activated"
+	ReasonInactive            VerifyReason = "inactive"
+	ReasonSuspended           VerifyReason = "suspended"
+	ReasonRevoked             VerifyReason = "revoked"
+	ReasonExpired             VerifyReason = "expired"
+	ReasonInvalidVersion      VerifyReason = "invalid_version"
+	ReasonVersionNotAllowed   VerifyReason = "version_not_allowed"
+	ReasonUpgradeRequired     VerifyReason = "upgrade_required"
+	ReasonSeatLimit           VerifyReason = "seat_limit" // 激活时席位已满
+)
+
+var ErrInvalidVersionRule = errors.New("无效的版本范围或最大主版本号")
+
+// VerifyRequest 客户端校验请求
+type VerifyRequest struct {
+	Key         string
+	UserId      string
+	ProductId   string
+	Version     string // 客户端版本，为空时不校验版本
+	Fingerprint model.Fingerprint
+	Trading     TradingContext // 交易账户环境，账号为空时使用 UserId
+	IPAddress   string
+	UserAgent   string
+	Platform    string // 客户端上报的操作系统，为空时从 UserAgent 解析
+}
+
+// VerifyResult 校验结果
+type VerifyResult struct {
+	License       *model.License
+	Valid         bool
+	Reason        VerifyReason
+	Entitlements  licensing.Entitlements
+	LatestVersion string
+	Token         *licensing.Token // 校验通过时续发的离线凭证，尚未签名
+}
+
+// VerifyLicense 校验许可证：归属、设备、状态、交易账户规则和客户端版本，
+// 无论结果如何都记录一次 verify 使用记录。
+func VerifyLicense(req VerifyRequest) (*VerifyResult, error) {
+	if req.Trading.Account == "" {
+		req.Trading.Account = req.UserId
+	}
+
+	// 记录本次校验的结果，许可证不存在时记录客户端请求的产品
+	record := func(result *VerifyResult) (*VerifyResult, error) {
+		productID := req.ProductId
+		if result.License != nil {
+			productID = result.License.ProductId
+		}
+		RecordUsage(&model.LicenseUsage{
+			LicenseKey: req.Key,
+			ProductId:  productID,
+			Action:     "verify",
+			Reason:     string(result.Reason),
+			Account:    req.Trading.Account,
+			Broker:     req.Trading.Broker,
+			Server:     req.Trading.Server,
+		}, telemetry.Resolve(req.IPAddress, req.UserAgent, req.Platform), req.UserAgent)
+		return result, nil
+	}
+
+	var license model.License
+	err := database.DB.Scopes(database.ByLicenseKey(req.Key)).First(&license).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return record(&VerifyResult{Reason: ReasonNotFound})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 绑定了交易账号的许可证由账号规则校验 userid，不再要求与 UserId 一致
+	result := &VerifyResult{License: &license}
+	if license.ProductId != req.ProductId || (!HasAccountBinding(&license) && license.UserId != req.UserId) {
+		result.Reason = ReasonMismatch
+		return record(result)
+	}
+
+	// 限制席位或已有设备激活的许可证只允许已激活的设备使用
+	bound, err := hasDeviceBinding(&license)
+	if err != nil {
+		return nil, err
+	}
+	if bound {
+		if !req.Fingerprint.IsComplete() {
+			result.Reason = ReasonFingerprintRequired
+			return record(result)
+		}
+		err = TouchSeat(license.Key, req.Fingerprint)
+		if errors.Is(err, ErrActivationNotFound) {
+			result.Reason = ReasonDeviceNotActivated
+			return record(result)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 有效期已过的许可证在校验时更新为已过期
+	if err := ExpireIfOverdue(&license); err != nil {
+		return nil, err
+	}
+
+	product, err := GetProduct(license.ProductId)
+	if err != nil && !errors.Is(err, ErrProductNotFound) {
+		return nil, err
+	}
+	if product != nil {
+		result.LatestVersion = product.CurrentVersion
+		result.Entitlements = product.EntitlementSchema.Resolve(product.DefaultEntitlements, license.Entitlements)
+	} else {
+		result.Entitlements = license.Entitlements
+	}
+
+	if IsLicenseUsable(&license) {
+		reason, err := CheckBinding(&license, req.Trading)
+		if err != nil {
+			return nil, err
+		}
+		if reason == ReasonOK {
+			reason = CheckVersion(&license, product, req.Version)
+		}
+		result.Reason = reason
+	} else {
+		result.Reason = StatusReason(license.Status)
+	}
+	result.Valid = result.Reason == ReasonOK
+
+	// 未提供终端 ID 的客户端只能在线校验，不签发离线凭证
+	if result.Valid && req.Fingerprint.TerminalID != "" {
+		result.Token, err = RenewOfflineToken(&license, product, req.Fingerprint.TerminalID, req.Trading.Account, result.Entitlements)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return record(result)
+}
+
+// RecordUsage 补充客户端信息后保存一条使用记录。
+// 使用记录只用于统计和排查，保存失败不影响校验和激活的结果
+func RecordUsage(usage *model.LicenseUsage, client telemetry.Client, userAgent string) {
+	usage.IPAddress = client.IPAddress
+	usage.UserAgent = userAgent
+	usage.Country = client.Country
+	usage.Device = client.Device
+	usage.Platform = client.Platform
+	if usage.Timestamp.IsZero() {
+		usage.Timestamp = time.Now()
+	}
+	if err := database.DB.Create(usage).Error; err != nil {
+		log.Printf("保存使用记录失败: %v", err)
+	}
+}
+
+// CheckVersion 检查客户端版本是否在许可证允许的范围内。
+// 许可证的设置优先于产品；都未设置最大主版本号时，以许可证签发时的版本为准，
+// 即 v2 的许可证不能使用 v3 的客户端。客户端未提供版本时，许可证或产品设置了
+// 版本规则则要求升级，否则不做检查，兼容不上报版本的旧客户端。
+func CheckVersion(license *model.License, product *model.Product, clientVersion string) VerifyReason {
+	constraint, maxMajor := license.VersionConstraint, license.MaxMajorVersion
+	if product != nil {
+		if constraint == "" {
+			constraint = product.VersionConstraint
+		}
+		if maxMajor == 0 {
+			maxMajor = product.MaxMajorVersion
+		}
+	}
+	if clientVersion == "" {
+		if constraint != "" || maxMajor > 0 {
+			return ReasonUpgradeRequired
+		}
+		return ReasonOK
+	}
+	v, err := semver.NewVersion(clientVersion)
+	if err != nil {
+		return ReasonInvalidVersion
+	}
+
+	if maxMajor == 0 {
+		if issued, err := semver.NewVersion(license.Version); err == nil {
+			maxMajor = int(issued.Major())
+		}
+	}
+
+	if maxMajor > 0 && v.Major() > uint64(maxMajor) {
+		return ReasonUpgradeRequired
+	}
+	if constraint != "" {
+		c, err := semver.NewConstraint(constraint)
+		if err != nil || !c.Check(v) {
+			return ReasonVersionNotAllowed
+		}
+	}
+	return ReasonOK
+}
+
+// ValidateVersionRule 校验版本范围表达式和最大主版本号
+func ValidateVersionRule(constraint string, maxMajor int) error {
+	if maxMajor < 0 {
+		return ErrInvalidVersionRule
+	}
+	if constraint != "" {
+		if _, err := semver.NewConstraint(constraint); err != nil {
+			return ErrInvalidVersionRule
+		}
+	}
+	return nil
+}
+
+// StatusReason 不可用状态对应的原因代码
+func StatusReason(status model.LicenseStatus) VerifyReason {
+	switch status {
+	case model.LicenseSuspended:
+		return ReasonSuspended
+	case model.LicenseRevoked:
+		return ReasonRevoked
+	case model.LicenseExpired:
+		return ReasonExpired
+	default:
+		return ReasonInactive
+	}
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import "strings"
+
+// 设备类型
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// 操作系统
+const (
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+// Unknown 统计时无法识别的国家、设备或操作系统的名称，数据库中保存为空字符串
+const Unknown = "unknown"
+
+// Client 写入使用记录时附带的客户端信息，无法识别的字段为空字符串
+type Client struct {
+	IPAddress string
+	Country   string
+	Device    string
+	Platform  string
+}
+
+// Resolve 根据 IP、User-Agent 和客户端上报的平台解析客户端信息，
+// 客户端上报的平台可以识别时优先使用
+func Resolve(ip, userAgent, platform string) Client {
+	client := Client{IPAddress: ip, Country: Country(ip)}
+	client.Device, client.Platform = ParsePlatform(platform)
+	if client.Platform == "" {
+		client.Device, client.Platform = ParseUserAgent(userAgent)
+	}
+	return client
+}
+
+// platformAliases 客户端上报的平台名称，MQL 程序可以按 TerminalInfo 上报 windows 或 linux（Wine）
+var platformAliases = map[string]struct{ device, platform string }{
+	"windows": {DeviceDesktop, PlatformWindows},
+	"win":     {DeviceDesktop, PlatformWindows},
+	"win32":   {DeviceDesktop, PlatformWindows},
+	"win64":   {DeviceDesktop, PlatformWindows},
+	"macos":   {DeviceDesktop, PlatformMacOS},
+	"mac":     {DeviceDesktop, PlatformMacOS},
+	"osx":     {DeviceDesktop, PlatformMacOS},
+	"darwin":  {DeviceDesktop, PlatformMacOS},
+	"linux":   {DeviceDesktop, PlatformLinux},
+	"wine":    {DeviceDesktop, PlatformLinux},
+	"android": {DeviceMobile, PlatformAndroid},
+	"ios":     {DeviceMobile, PlatformIOS},
+	"iphone":  {DeviceMobile, PlatformIOS},
+	"ipad":    {DeviceTablet, PlatformIOS},
+	"ipados":  {DeviceTablet, PlatformIOS},
+}
+
+// ParsePlatform 解析客户端上报的平台名称，不区分大小写
+func ParsePlatform(platform string) (device, os string) {
+	alias, ok := platformAliases[strings.ToLower(strings.TrimSpace(platform))]
+	if !ok {
+		return "", ""
+	}
+	return alias.device, alias.platform
+}
+
+// userAgentRules 按顺序匹配 User-Agent 中的关键字，先匹配更具体的关键字，
+// 例如 iPad、iPhone 的 User-Agent 中也含有 Mac OS X，Android 的含有 Linux
+var userAgentRules = []struct {
+	keywords []string
+	device   string
+	platform string
+}{
+	{[]string{"ipad"}, DeviceTablet, PlatformIOS},
+	{[]string{"iphone", "ipod"}, DeviceMobile, PlatformIOS},
+	{[]string{"windows phone"}, DeviceMobile, PlatformWindows},
+	{[]string{"android"}, DeviceMobile, PlatformAndroid},
+	{[]string{"windows", "win64", "win32", "wow64"}, DeviceDesktop, PlatformWindows},
+	{[]string{"macintosh", "mac os x"}, DeviceDesktop, PlatformMacOS},
+	{[]string{"linux", "x11", "cros"}, DeviceDesktop, PlatformLinux},
+}
+
+// ParseUserAgent 从 User-Agent 解析设备类型和操作系统，
+// MetaTrader 终端的 WebRequest 与浏览器一样在括号中附带系统信息
+func ParseUserAgent(userAgent string) (device, os string) {
+	ua := strings.ToLower(userAgent)
+	for _, rule := range userAgentRules {
+		for _, keyword := range rule.keywords {
+			if !strings.Contains(ua, keyword) {
+				continue
+			}
+			// Android 平板的 User-Agent 不含 Mobile
+			if rule.platform == PlatformAndroid && !strings.Contains(ua, "mobile") {
+				return DeviceTablet, PlatformAndroid
+			}
+			return rule.device, rule.platform
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name         string
+		userAgent    string
+		wantDevice   string
+		wantPlatform string
+	}{
+		{name: "metatrader", userAgent: "MetaTrader 5 Terminal/5.4153 (Windows NT 10.0.19045; x64)", wantDevice: DeviceDesktop, wantPlatform: PlatformWindows},
+		{name: "chrome_windows", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", wantDevice: DeviceDesktop, wantPlatform: PlatformWindows},
+		{name: "safari_mac", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", wantDevice: DeviceDesktop, wantPlatform: PlatformMacOS},
+		{name: "firefox_linux", userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", wantDevice: DeviceDesktop, wantPlatform: PlatformLinux},
+		{name: "iphone", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", wantDevice: DeviceMobile, wantPlatform: PlatformIOS},
+		{name: "ipad", userAgent: "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", wantDevice: DeviceTablet, wantPlatform: PlatformIOS},
+		{name: "android_phone", userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", wantDevice: DeviceMobile, wantPlatform: PlatformAndroid},
+		{name: "android_tablet", userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", wantDevice: DeviceTablet, wantPlatform: PlatformAndroid},
+		{name: "unknown", userAgent: "curl/8.4.0"},
+		{name: "empty", userAgent: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, platform := ParseUserAgent(tt.userAgent)
+			assert.Equal(t, tt.wantDevice, device)
+			assert.Equal(t, tt.wantPlatform, platform)
+		})
+	}
+}
+
+func TestResolvePlatform(t *testing.T) {
+	ua := "MetaTrader 5 Terminal/5.4153 (Windows NT 10.0.19045; x64)"
+
+	// 客户端上报的平台优先于 User-Agent
+	client := Resolve("", ua, " Wine ")
+	assert.Equal(t, DeviceDesktop, client.Device)
+	assert.Equal(t, PlatformLinux, client.Platform)
+
+	// 无法识别的平台使用 User-Agent 解析
+	client = Resolve("", ua, "mt5")
+	assert.Equal(t, PlatformWindows, client.Platform)
+
+	client = Resolve("", "", "iPadOS")
+	assert.Equal(t, DeviceTablet, client.Device)
+	assert.Equal(t, PlatformIOS, client.Platform)
+}
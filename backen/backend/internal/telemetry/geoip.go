@@ -0,0 +1,60 @@
+// Package telemetry 解析客户端的国家、设备和操作系统，写入使用记录和登录日志时使用
+package telemetry
+
+import (
+	"net"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// geoDB 查询期间持有读锁，替换数据库时取得写锁，保证关闭旧数据库时没有正在进行的查询
+var (
+	geoMu sync.RWMutex
+	geoDB *geoip2.Reader
+)
+
+// InitGeoIP 加载离线 GeoIP 国家数据库（MaxMind mmdb 格式，如 GeoLite2-Country.mmdb），
+// path 为空时不解析国家。重复调用时替换已加载的数据库
+func InitGeoIP(path string) error {
+	var reader *geoip2.Reader
+	if path != "" {
+		var err error
+		if reader, err = geoip2.Open(path); err != nil {
+			return err
+		}
+	}
+
+	geoMu.Lock()
+	old := geoDB
+	geoDB = reader
+	geoMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Country 返回 IP 所在国家的 ISO 3166-1 两位代码，未加载数据库或无法识别时返回空字符串
+func Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	geoMu.RLock()
+	defer geoMu.RUnlock()
+	if geoDB == nil {
+		return ""
+	}
+	record, err := geoDB.Country(parsed)
+	if err != nil {
+		return ""
+	}
+	if record.Country.IsoCode != "" {
+		return record.Country.IsoCode
+	}
+	// 部分地址段只有注册国家，例如卫星或海事网络
+	return record.RegisteredCountry.IsoCode
+}
@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestGeoIP 生成只包含少量地址段的 GeoIP 国家数据库
+func writeTestGeoIP(t *testing.T) string {
+	writer, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "GeoLite2-Country", RecordSize: 24})
+	assert.NoError(t, err)
+
+	networks := []struct {
+		cidr   string
+		record mmdbtype.Map
+	}{
+		{"81.2.69.0/24", mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String("GB")}}},
+		{"2001:218::/32", mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String("JP")}}},
+		{"217.65.48.0/24", mmdbtype.Map{"registered_country": mmdbtype.Map{"iso_code": mmdbtype.String("GI")}}},
+	}
+	for _, n := range networks {
+		_, network, err := net.ParseCIDR(n.cidr)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Insert(network, n.record))
+	}
+
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = writer.WriteTo(f)
+	assert.NoError(t, err)
+	return path
+}
+
+func TestCountry(t *testing.T) {
+	assert.Equal(t, "", Country("81.2.69.142"), "未加载数据库")
+
+	assert.NoError(t, InitGeoIP(writeTestGeoIP(t)))
+	t.Cleanup(func() { InitGeoIP("") })
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"81.2.69.142", "GB"},
+		{"2001:218:85a3::1", "JP"},
+		{"217.65.48.7", "GI"},
+		{"8.8.8.8", ""},
+		{"127.0.0.1", ""},
+		{"not-an-ip", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Country(tt.ip), tt.ip)
+	}
+
+	assert.Equal(t, "GB", Resolve("81.2.69.142", "", "").Country)
+	assert.Error(t, InitGeoIP(filepath.Join(t.TempDir(), "missing.mmdb")))
+}
+
+func TestCountryReload(t *testing.T) {
+	path := writeTestGeoIP(t)
+	assert.NoError(t, InitGeoIP(path))
+	t.Cleanup(func() { InitGeoIP("") })
+
+	// 重新加载期间的查询不能使用已关闭的数据库
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				if got := Country("81.2.69.142"); got != "GB" {
+					t.Errorf("Country = %q, want GB", got)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, InitGeoIP(path))
+	}
+	wg.Wait()
+}
@@ -1,14 +1,26 @@
 package util
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("your-secret-key") // 在实际应用中应该从配置文件中读取
+var jwtSecret []byte
+
+var ErrJWTSecretNotSet = errors.New("未配置 JWT 密钥")
+
+// SetJWTSecret 设置 JWT 签名密钥，启动时从配置加载
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
 
 func GenerateToken(userID uint) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", ErrJWTSecretNotSet
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24小时过期
@@ -19,7 +31,14 @@ func GenerateToken(userID uint) (string, error) {
 }
 
 func ValidateToken(tokenString string) (uint, error) {
+	if len(jwtSecret) == 0 {
+		return 0, ErrJWTSecretNotSet
+	}
+
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
 		return jwtSecret, nil
 	})
 
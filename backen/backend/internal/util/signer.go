@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var signingKey ed25519.PrivateKey
+
+// InitSigningKey 从指定路径加载 Ed25519 签名私钥，文件不存在时自动生成
+func InitSigningKey(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return generateSigningKey(path)
+	}
+	return LoadSigningKey(path)
+}
+
+// LoadSigningKey 从指定路径加载 Ed25519 签名私钥，文件不存在时返回错误
+func LoadSigningKey(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("签名私钥文件格式错误")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return errors.New("签名私钥不是 Ed25519 类型")
+	}
+
+	signingKey = priv
+	return nil
+}
+
+func generateSigningKey(path string) error {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	signingKey = priv
+	return nil
+}
+
+// SigningKey 返回当前的签名私钥
+func SigningKey() ed25519.PrivateKey {
+	return signingKey
+}
+
+// PublicKey 返回签名私钥对应的公钥
+func PublicKey() ed25519.PublicKey {
+	if signingKey == nil {
+		return nil
+	}
+	return signingKey.Public().(ed25519.PublicKey)
+}
@@ -0,0 +1,52 @@
+package licensing
+
+import "strings"
+
+// NoneAllowed 品种或周期列表只包含该值时表示不允许任何取值。
+// 许可证原有的限制在产品范围调整后全部失效时使用，不能当作空列表（不限制）处理
+const NoneAllowed = "-"
+
+// Entitlements 许可证授予的功能与交易限制，数值限制为 0、列表为空表示不限制
+type Entitlements struct {
+	Features      []string `json:"features,omitempty"`        // 启用的功能开关
+	MaxLotSize    float64  `json:"max_lot_size,omitempty"`    // 单笔最大手数
+	MaxSymbols    int      `json:"max_symbols,omitempty"`     // 同时交易的最大品种数
+	MaxOpenTrades int      `json:"max_open_trades,omitempty"` // 最大持仓单数
+	Symbols       []string `json:"symbols,omitempty"`         // 允许交易的品种
+	Timeframes    []string `json:"timeframes,omitempty"`      // 允许运行的图表周期
+}
+
+// HasFeature 是否启用了指定功能
+func (e Entitlements) HasFeature(name string) bool {
+	return containsFold(e.Features, name)
+}
+
+// AllowsSymbol 是否允许交易指定品种
+func (e Entitlements) AllowsSymbol(symbol string) bool {
+	return len(e.Symbols) == 0 || containsFold(e.Symbols, symbol)
+}
+
+// AllowsTimeframe 是否允许在指定周期运行
+func (e Entitlements) AllowsTimeframe(timeframe string) bool {
+	return len(e.Timeframes) == 0 || containsFold(e.Timeframes, timeframe)
+}
+
+// AllowsLotSize 手数是否在限制范围内
+func (e Entitlements) AllowsLotSize(lots float64) bool {
+	return e.MaxLotSize == 0 || lots <= e.MaxLotSize
+}
+
+// IsZero 是否未设置任何权益
+func (e Entitlements) IsZero() bool {
+	return len(e.Features) == 0 && e.MaxLotSize == 0 && e.MaxSymbols == 0 &&
+		e.MaxOpenTrades == 0 && len(e.Symbols) == 0 && len(e.Timeframes) == 0
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Package licensing 提供离线许可证文件的签发与校验，以及客户端 API 请求的签名。
+//
+// 服务端使用 Ed25519 私钥对许可证文档签名，客户端只需持有公钥即可在
+// 无网络的情况下校验签名与有效期。
+package licensing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Algorithm 当前许可证文件使用的签名算法
+const Algorithm = "ed25519"
+
+var (
+	ErrMalformed        = errors.New("licensing: 许可证文件格式错误")
+	ErrUnknownAlgorithm = errors.New("licensing: 不支持的签名算法")
+	ErrInvalidSignature = errors.New("licensing: 签名校验失败")
+	ErrExpired          = errors.New("licensing: 许可证已过期")
+	ErrInvalidKey       = errors.New("licensing: 无效的公钥")
+)
+
+// Document 许可证文档内容
+type Document struct {
+	Key          string       `json:"key"`
+	UserId       string       `json:"userid"`
+	ProductId    string       `json:"productid"`
+	Entitlements Entitlements `json:"entitlements"`
+	ValidUntil   time.Time    `json:"valid_until"`
+	IssuedAt     time.Time    `json:"issued_at"`
+}
+
+// File 签名后的许可证文件，Payload 为文档 JSON 的 base64 编码
+type File struct {
+	Algorithm string `json:"alg"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Sign 使用私钥对文档签名
+func Sign(priv ed25519.PrivateKey, doc Document) (*File, error) {
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Algorithm: Algorithm,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+// Verify 使用公钥校验许可证文件的签名与有效期。
+// 签名有效但已过期时返回文档和 ErrExpired，便于调用方展示过期信息。
+func Verify(pub ed25519.PublicKey, data []byte, now time.Time) (*Document, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, ErrMalformed
+	}
+	payload, err := file.open(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Document
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !now.Before(doc.ValidUntil) {
+		return &doc, ErrExpired
+	}
+
+	return &doc, nil
+}
+
+// open 校验签名算法与签名，返回签名的内容
+func (f File) open(pub ed25519.PublicKey) ([]byte, error) {
+	if f.Algorithm != Algorithm {
+		return nil, ErrUnknownAlgorithm
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(f.Payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signature, err := base64.StdEncoding.DecodeString(f.Signature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !ed25519.Verify(pub, payload, signature) {
+		return nil, ErrInvalidSignature
+	}
+	return payload, nil
+}
+
+// ParsePublicKey 解析 base64 编码的 Ed25519 公钥
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// EncodePublicKey 将公钥编码为 base64，用于分发给客户端
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
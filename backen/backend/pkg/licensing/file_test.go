@@ -0,0 +1,71 @@
+package licensing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	doc := Document{
+		Key:          "ABCDE-FGHJK-LMNPQ-RSTUV",
+		UserId:       "10086",
+		ProductId:    "gold-scalper",
+		Entitlements: Entitlements{Features: []string{"full"}, MaxLotSize: 1.5},
+		ValidUntil:   now.Add(24 * time.Hour),
+		IssuedAt:     now,
+	}
+
+	file, err := Sign(priv, doc)
+	assert.NoError(t, err)
+	data, _ := json.Marshal(file)
+
+	tests := []struct {
+		name    string
+		pub     ed25519.PublicKey
+		data    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", pub: pub, data: data, now: now},
+		{name: "expired", pub: pub, data: data, now: now.Add(48 * time.Hour), wantErr: ErrExpired},
+		{name: "malformed", pub: pub, data: []byte("{"), now: now, wantErr: ErrMalformed},
+		{name: "invalid_key", pub: pub[:8], data: data, now: now, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.pub, tt.data, tt.now)
+			assert.Equal(t, tt.wantErr, err)
+			if err == nil {
+				assert.Equal(t, doc.Key, got.Key)
+				assert.Equal(t, doc.Entitlements, got.Entitlements)
+			}
+		})
+	}
+
+	t.Run("wrong_public_key", func(t *testing.T) {
+		otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+		_, err := Verify(otherPub, data, now)
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("tampered_payload", func(t *testing.T) {
+		tampered := *file
+		forged := doc
+		forged.ValidUntil = now.AddDate(10, 0, 0)
+		other, _ := Sign(priv, forged)
+		tampered.Payload = other.Payload
+		data, _ := json.Marshal(tampered)
+		_, err := Verify(pub, data, now)
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+}
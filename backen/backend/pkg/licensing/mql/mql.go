@@ -0,0 +1,9 @@
+// Package mql 提供 MQL4/MQL5 客户端使用的许可证校验源码。
+package mql
+
+import _ "embed"
+
+// LicenseVerify LicenseVerify.mqh 的源码，校验服务器响应的签名
+//
+//go:embed LicenseVerify.mqh
+var LicenseVerify string
@@ -0,0 +1,34 @@
+package licensing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// 客户端 API 请求签名使用的请求头
+const (
+	HeaderClientKey       = "X-Client-Key"       // API 密钥标识
+	HeaderClientTimestamp = "X-Client-Timestamp" // Unix 时间戳（秒）
+	HeaderClientNonce     = "X-Client-Nonce"     // 每个请求不同的随机字符串
+	HeaderClientSignature = "X-Client-Signature" // SignRequest 计算的签名
+)
+
+// SignRequest 计算客户端 API 请求的签名。
+// 签名内容为请求方法、路径（含查询参数）、时间戳、随机数和请求体 SHA-256 的十六进制，
+// 以换行分隔后使用 API 密钥的 secret 做 HMAC-SHA256，返回十六进制字符串
+func SignRequest(secret, method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		strings.ToUpper(method),
+		uri,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
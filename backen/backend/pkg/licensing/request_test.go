@@ -0,0 +1,32 @@
+package licensing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignRequest(t *testing.T) {
+	secret := "0123456789abcdef"
+	base := SignRequest(secret, "GET", "/api/v1/client/verify?key=K&productid=gold", "1700000000", "n-1", nil)
+
+	// 固定输入的签名，其他语言的客户端实现可据此核对
+	assert.Equal(t, "3efb8320028a8e4a5b5597272e40f51da531cf9be82837e8bb53f2bee11b0d23", base)
+	assert.Equal(t, base, SignRequest(secret, "get", "/api/v1/client/verify?key=K&productid=gold", "1700000000", "n-1", []byte{}))
+
+	tests := []struct {
+		name string
+		sig  string
+	}{
+		{name: "secret", sig: SignRequest("other-secret", "GET", "/api/v1/client/verify?key=K&productid=gold", "1700000000", "n-1", nil)},
+		{name: "query", sig: SignRequest(secret, "GET", "/api/v1/client/verify?key=K&productid=silver", "1700000000", "n-1", nil)},
+		{name: "timestamp", sig: SignRequest(secret, "GET", "/api/v1/client/verify?key=K&productid=gold", "1700000001", "n-1", nil)},
+		{name: "nonce", sig: SignRequest(secret, "GET", "/api/v1/client/verify?key=K&productid=gold", "1700000000", "n-2", nil)},
+		{name: "body", sig: SignRequest(secret, "GET", "/api/v1/client/verify?key=K&productid=gold", "1700000000", "n-1", []byte("{}"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotEqual(t, base, tt.sig)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package licensing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenVersion 当前离线凭证签名内容的格式版本
+const TokenVersion = "1"
+
+// Token 校验通过时签发的短期离线凭证。客户端保存后，重启时若无法连接服务器，
+// 可以在到期前凭它继续运行，无需网络请求。
+//
+// 签名内容与 Verdict 一样为每行一个 name=value 的文本，依次为 token、key、
+// productid、terminal_id、account、issued_at、expires_at 和 entitlements(JSON)。
+// 第一行为 token 而不是 v，校验结果和离线凭证不能互相冒用。
+type Token struct {
+	Key          string       `json:"key"`
+	ProductId    string       `json:"productid"`
+	TerminalId   string       `json:"terminal_id"` // 签发时的终端，为空表示不限制
+	Account      string       `json:"account"`     // 签发时的交易账号，为空表示不限制
+	Entitlements Entitlements `json:"entitlements"`
+	IssuedAt     time.Time    `json:"issued_at"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+}
+
+// Payload 按签名格式编码，字段不能包含换行
+func (t Token) Payload() ([]byte, error) {
+	entitlements, err := json.Marshal(t.Entitlements)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeFields([][2]string{
+		{"token", TokenVersion},
+		{"key", t.Key},
+		{"productid", t.ProductId},
+		{"terminal_id", t.TerminalId},
+		{"account", t.Account},
+		{"issued_at", strconv.FormatInt(t.IssuedAt.Unix(), 10)},
+		{"expires_at", strconv.FormatInt(t.ExpiresAt.Unix(), 10)},
+		{"entitlements", string(entitlements)},
+	})
+}
+
+// ParseToken 解析签名内容，忽略不认识的字段
+func ParseToken(data []byte) (*Token, error) {
+	var version string
+	t := new(Token)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, ErrMalformed
+		}
+		switch name {
+		case "token":
+			version = value
+		case "key":
+			t.Key = value
+		case "productid":
+			t.ProductId = value
+		case "terminal_id":
+			t.TerminalId = value
+		case "account":
+			t.Account = value
+		case "issued_at", "expires_at":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrMalformed
+			}
+			if name == "issued_at" {
+				t.IssuedAt = time.Unix(seconds, 0)
+			} else {
+				t.ExpiresAt = time.Unix(seconds, 0)
+			}
+		case "entitlements":
+			if err := json.Unmarshal([]byte(value), &t.Entitlements); err != nil {
+				return nil, ErrMalformed
+			}
+		}
+	}
+	if version != TokenVersion {
+		return nil, ErrMalformed
+	}
+	return t, nil
+}
+
+// SignToken 使用私钥对离线凭证签名，结果与许可证文件格式相同
+func SignToken(priv ed25519.PrivateKey, t Token) (*File, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKey
+	}
+	payload, err := t.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Algorithm: Algorithm,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+// VerifyToken 校验离线凭证的签名，并确认许可证密钥、终端和交易账号与当前环境一致。
+// 签名有效但已到期时返回凭证和 ErrExpired，客户端此时须重新连接服务器。
+func VerifyToken(pub ed25519.PublicKey, file File, key, terminalID, account string, now time.Time) (*Token, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	payload, err := file.open(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := ParseToken(payload)
+	if err != nil {
+		return nil, err
+	}
+	if t.Key != key ||
+		t.TerminalId != "" && t.TerminalId != terminalID ||
+		t.Account != "" && t.Account != account {
+		return t, ErrVerdictMismatch
+	}
+	if !now.Before(t.ExpiresAt) {
+		return t, ErrExpired
+	}
+	return t, nil
+}
@@ -0,0 +1,79 @@
+package licensing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenPayload(t *testing.T) {
+	// MQL 客户端按行解析签名内容，格式变更需要同时修改客户端
+	token := Token{
+		Key:          "ABCDE-FGHJK-LMNPQ-RSTUV",
+		ProductId:    "gold",
+		TerminalId:   "t-1",
+		Account:      "10086",
+		Entitlements: Entitlements{Features: []string{"full"}},
+		IssuedAt:     time.Unix(1700000000, 0),
+		ExpiresAt:    time.Unix(1700259200, 0),
+	}
+	payload, err := token.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, "token=1\nkey=ABCDE-FGHJK-LMNPQ-RSTUV\nproductid=gold\nterminal_id=t-1\naccount=10086\n"+
+		"issued_at=1700000000\nexpires_at=1700259200\nentitlements={\"features\":[\"full\"]}", string(payload))
+
+	parsed, err := ParseToken(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, token.Entitlements, parsed.Entitlements)
+	assert.True(t, parsed.ExpiresAt.Equal(token.ExpiresAt))
+
+	// 校验结果不能作为离线凭证使用
+	verdict, err := Verdict{Action: "verify", Key: token.Key, Valid: true}.Payload()
+	assert.NoError(t, err)
+	_, err = ParseToken(verdict)
+	assert.Equal(t, ErrMalformed, err)
+}
+
+func TestSignAndVerifyToken(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	token := Token{Key: "ABCDE-FGHJK-LMNPQ-RSTUV", ProductId: "gold", TerminalId: "t-1", Account: "10086", IssuedAt: now, ExpiresAt: now.Add(72 * time.Hour)}
+	file, err := SignToken(priv, token)
+	assert.NoError(t, err)
+	verdict, err := SignVerdict(priv, Verdict{Action: "verify", Key: token.Key, Valid: true})
+	assert.NoError(t, err)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name     string
+		pub      ed25519.PublicKey
+		file     File
+		terminal string
+		account  string
+		now      time.Time
+		wantErr  error
+	}{
+		{name: "valid", pub: pub, file: *file, terminal: "t-1", account: "10086", now: now},
+		{name: "expired", pub: pub, file: *file, terminal: "t-1", account: "10086", now: now.Add(72 * time.Hour), wantErr: ErrExpired},
+		{name: "other_terminal", pub: pub, file: *file, terminal: "t-2", account: "10086", now: now, wantErr: ErrVerdictMismatch},
+		{name: "other_account", pub: pub, file: *file, terminal: "t-1", account: "10010", now: now, wantErr: ErrVerdictMismatch},
+		{name: "wrong_public_key", pub: otherPub, file: *file, terminal: "t-1", account: "10086", now: now, wantErr: ErrInvalidSignature},
+		{name: "signed_verdict", pub: pub, file: *verdict, terminal: "t-1", account: "10086", now: now, wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyToken(tt.pub, tt.file, token.Key, tt.terminal, tt.account, tt.now)
+			assert.Equal(t, tt.wantErr, err)
+			if err == nil {
+				assert.Equal(t, "gold", got.ProductId)
+				assert.Equal(t, token.ExpiresAt.Unix(), got.ExpiresAt.Unix())
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package licensing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VerdictVersion 当前校验结果签名内容的格式版本
+const VerdictVersion = "1"
+
+// ErrVerdictMismatch 签名有效，但签名的随机数或许可证密钥与本次请求不一致，
+// 通常是重放了其他请求的响应
+var ErrVerdictMismatch = errors.New("licensing: 校验结果与请求不一致")
+
+// ErrNonceRequired 校验结论时没有提供随机数，不带随机数的结论可以被重放
+var ErrNonceRequired = errors.New("licensing: 随机数不能为空")
+
+// Verdict 服务器对一次校验或激活请求的结论。
+//
+// 签名内容为每行一个 name=value 的文本，依次为 v、action、nonce、key、valid、
+// status、reason、server_time 和 entitlements(JSON)，不含结尾换行，
+// 便于 MQL 等没有 JSON 库的客户端解析。
+type Verdict struct {
+	Action       string       `json:"action"` // verify 或 activate
+	Nonce        string       `json:"nonce"`  // 客户端请求时携带的随机数
+	Key          string       `json:"key"`
+	Valid        bool         `json:"valid"`
+	Status       string       `json:"status"`
+	Reason       string       `json:"reason"`
+	Entitlements Entitlements `json:"entitlements"`
+	ServerTime   time.Time    `json:"server_time"`
+}
+
+// Payload 按签名格式编码，字段不能包含换行
+func (v Verdict) Payload() ([]byte, error) {
+	entitlements, err := json.Marshal(v.Entitlements)
+	if err != nil {
+		return nil, err
+	}
+	valid := "0"
+	if v.Valid {
+		valid = "1"
+	}
+
+	return encodeFields([][2]string{
+		{"v", VerdictVersion},
+		{"action", v.Action},
+		{"nonce", v.Nonce},
+		{"key", v.Key},
+		{"valid", valid},
+		{"status", v.Status},
+		{"reason", v.Reason},
+		{"server_time", strconv.FormatInt(v.ServerTime.Unix(), 10)},
+		{"entitlements", string(entitlements)},
+	})
+}
+
+// encodeFields 按签名格式编码，每行一个 name=value，字段不能包含换行
+func encodeFields(fields [][2]string) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, f := range fields {
+		if strings.ContainsAny(f[1], "\r\n") {
+			return nil, ErrMalformed
+		}
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(f[0])
+		buf.WriteByte('=')
+		buf.WriteString(f[1])
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseVerdict 解析签名内容，忽略不认识的字段
+func ParseVerdict(data []byte) (*Verdict, error) {
+	var version string
+	v := new(Verdict)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, ErrMalformed
+		}
+		switch name {
+		case "v":
+			version = value
+		case "action":
+			v.Action = value
+		case "nonce":
+			v.Nonce = value
+		case "key":
+			v.Key = value
+		case "valid":
+			v.Valid = value == "1"
+		case "status":
+			v.Status = value
+		case "reason":
+			v.Reason = value
+		case "server_time":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrMalformed
+			}
+			v.ServerTime = time.Unix(seconds, 0)
+		case "entitlements":
+			if err := json.Unmarshal([]byte(value), &v.Entitlements); err != nil {
+				return nil, ErrMalformed
+			}
+		}
+	}
+	if version != VerdictVersion {
+		return nil, ErrMalformed
+	}
+	return v, nil
+}
+
+// SignVerdict 使用私钥对校验结果签名，结果与许可证文件格式相同
+func SignVerdict(priv ed25519.PrivateKey, v Verdict) (*File, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKey
+	}
+	payload, err := v.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Algorithm: Algorithm,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+// VerifyVerdict 校验服务器响应中 signed_verdict 的签名，并确认签名的随机数和
+// 许可证密钥与本次请求一致。客户端只能信任通过校验的结论，而不是响应中的其他字段。
+// nonce 不能为空。
+func VerifyVerdict(pub ed25519.PublicKey, file File, nonce, key string) (*Verdict, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	if nonce == "" {
+		return nil, ErrNonceRequired
+	}
+	payload, err := file.open(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := ParseVerdict(payload)
+	if err != nil {
+		return nil, err
+	}
+	if v.Nonce != nonce || v.Key != key {
+		return v, ErrVerdictMismatch
+	}
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package licensing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerdictPayload(t *testing.T) {
+	// MQL 客户端按行解析签名内容，格式变更需要同时修改客户端
+	v := Verdict{
+		Action:       "verify",
+		Nonce:        "n-1",
+		Key:          "ABCDE-FGHJK-LMNPQ-RSTUV",
+		Valid:        true,
+		Status:       "active",
+		Reason:       "ok",
+		Entitlements: Entitlements{Features: []string{"full"}, MaxLotSize: 1.5},
+		ServerTime:   time.Unix(1700000000, 0),
+	}
+	payload, err := v.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, "v=1\naction=verify\nnonce=n-1\nkey=ABCDE-FGHJK-LMNPQ-RSTUV\nvalid=1\nstatus=active\nreason=ok\n"+
+		"server_time=1700000000\nentitlements={\"features\":[\"full\"],\"max_lot_size\":1.5}", string(payload))
+
+	parsed, err := ParseVerdict(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, v.Entitlements, parsed.Entitlements)
+	assert.True(t, parsed.ServerTime.Equal(v.ServerTime))
+
+	v.Key = "ABCDE\nvalid=1"
+	_, err = v.Payload()
+	assert.Equal(t, ErrMalformed, err)
+}
+
+func TestSignAndVerifyVerdict(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	v := Verdict{Action: "activate", Nonce: "8f2c", Key: "ABCDE-FGHJK-LMNPQ-RSTUV", Valid: true, Status: "active", Reason: "ok", ServerTime: time.Now()}
+	file, err := SignVerdict(priv, v)
+	assert.NoError(t, err)
+
+	forged, _ := SignVerdict(priv, Verdict{Action: "activate", Nonce: "8f2c", Key: v.Key, Valid: false, Reason: "revoked"})
+	tampered := *file
+	tampered.Payload = forged.Payload
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name    string
+		pub     ed25519.PublicKey
+		file    File
+		nonce   string
+		key     string
+		wantErr error
+	}{
+		{name: "valid", pub: pub, file: *file, nonce: "8f2c", key: v.Key},
+		{name: "other_nonce", pub: pub, file: *file, nonce: "9a01", key: v.Key, wantErr: ErrVerdictMismatch},
+		{name: "empty_nonce", pub: pub, file: *file, nonce: "", key: v.Key, wantErr: ErrNonceRequired},
+		{name: "other_key", pub: pub, file: *file, nonce: "8f2c", key: "ZZZZZ-FGHJK-LMNPQ-RSTUV", wantErr: ErrVerdictMismatch},
+		{name: "wrong_public_key", pub: otherPub, file: *file, nonce: "8f2c", key: v.Key, wantErr: ErrInvalidSignature},
+		{name: "tampered_payload", pub: pub, file: tampered, nonce: "8f2c", key: v.Key, wantErr: ErrInvalidSignature},
+		{name: "unsigned", pub: pub, file: File{Algorithm: Algorithm, Payload: base64.StdEncoding.EncodeToString([]byte("v=1\nvalid=1"))}, nonce: "8f2c", key: v.Key, wantErr: ErrInvalidSignature},
+		{name: "unknown_algorithm", pub: pub, file: File{Algorithm: "none", Payload: file.Payload}, nonce: "8f2c", key: v.Key, wantErr: ErrUnknownAlgorithm},
+		{name: "invalid_key", pub: pub[:8], file: *file, nonce: "8f2c", key: v.Key, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyVerdict(tt.pub, tt.file, tt.nonce, tt.key)
+			assert.Equal(t, tt.wantErr, err)
+			if err == nil {
+				assert.True(t, got.Valid)
+				assert.Equal(t, "ok", got.Reason)
+				assert.Equal(t, v.ServerTime.Unix(), got.ServerTime.Unix())
+			}
+		})
+	}
+}
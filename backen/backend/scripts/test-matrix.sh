@@ -0,0 +1,66 @@
+#!/usr/bin/env bash
+# 在 SQLite、MySQL 和 PostgreSQL 上分别运行测试。
+# MySQL 和 PostgreSQL 使用本地 Docker 容器，测试结束后自动删除。
+# 用法: scripts/test-matrix.sh [sqlite|mysql|postgres ...]
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+dialects=("$@")
+if [ ${#dialects[@]} -eq 0 ]; then
+  dialects=(sqlite mysql postgres)
+fi
+
+containers=()
+cleanup() {
+  for c in "${containers[@]}"; do
+    docker rm -f "$c" >/dev/null 2>&1 || true
+  done
+}
+trap cleanup EXIT
+
+wait_for() {
+  local name=$1
+  shift
+  for _ in $(seq 1 60); do
+    if docker exec "$name" "$@" >/dev/null 2>&1; then
+      return 0
+    fi
+    sleep 1
+  done
+  echo "$name 启动超时" >&2
+  return 1
+}
+
+for dialect in "${dialects[@]}"; do
+  echo "==> $dialect"
+  case "$dialect" in
+    sqlite)
+      go test ./...
+      ;;
+    mysql)
+      name=license-test-mysql
+      containers+=("$name")
+      docker run -d --rm --name "$name" -p 13306:3306 \
+        -e MYSQL_ROOT_PASSWORD=test -e MYSQL_DATABASE=license_test mysql:8.0 >/dev/null
+      wait_for "$name" mysql -uroot -ptest -e "SELECT 1" license_test
+      LICENSE_TEST_DB_DRIVER=mysql \
+      LICENSE_TEST_DB_DSN="root:test@tcp(127.0.0.1:13306)/license_test?charset=utf8mb4&parseTime=True&loc=Local" \
+        go test -p 1 ./...
+      ;;
+    postgres)
+      name=license-test-postgres
+      containers+=("$name")
+      docker run -d --rm --name "$name" -p 15432:5432 \
+        -e POSTGRES_PASSWORD=test -e POSTGRES_DB=license_test postgres:16 >/dev/null
+      wait_for "$name" pg_isready -U postgres -d license_test
+      LICENSE_TEST_DB_DRIVER=postgres \
+      LICENSE_TEST_DB_DSN="host=127.0.0.1 port=15432 user=postgres password=test dbname=license_test sslmode=disable" \
+        go test -p 1 ./...
+      ;;
+    *)
+      echo "未知的数据库: $dialect" >&2
+      exit 1
+      ;;
+  esac
+done